@@ -17,6 +17,8 @@ import (
 	"github.com/maxzhirnov/formease/config"
 	"github.com/maxzhirnov/formease/internal/handlers"
 	"github.com/maxzhirnov/formease/internal/middleware"
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/notifier"
 	"github.com/maxzhirnov/formease/internal/repository"
 	"github.com/maxzhirnov/formease/internal/service"
 	"github.com/maxzhirnov/formease/internal/storage"
@@ -31,7 +33,20 @@ func setupRoutes(router *gin.Engine,
 	gptHandler *handlers.GPTHandler,
 	imageHandler *handlers.ImageHandler,
 	submissionHandler *handlers.SubmissionHandler,
+	progressHandler *handlers.ProgressHandler,
+	webhookHandler *handlers.WebhookHandler,
+	notificationHandler *handlers.NotificationHandler,
+	templateHandler *handlers.TemplateHandler,
+	bundleHandler *handlers.BundleHandler,
+	folderHandler *handlers.FolderHandler,
+	workspaceHandler *handlers.WorkspaceHandler,
+	workspaceService *service.WorkspaceService,
+	apiKeyHandler *handlers.APIKeyHandler,
+	apiKeyService *service.APIKeyService,
+	userService *service.UserService,
 	jwtUtil *utils.JWTUtil) {
+	authMiddleware := middleware.AuthMiddleware(jwtUtil, apiKeyService, userService)
+
 	// Public health check routes
 	router.GET("/ping", healthHandler.Ping)
 	router.GET("/health", healthHandler.HealthCheck)
@@ -44,12 +59,19 @@ func setupRoutes(router *gin.Engine,
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/logout-all", authMiddleware, middleware.RequireSession(), authHandler.LogoutAll)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/resend-verification", authMiddleware, middleware.RequireSession(), authHandler.ResendVerification)
+			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
 		}
 
 		// Public form routes (read-only access)
 		forms := api.Group("/forms")
 		{
 			forms.GET("/:id", formHandler.GetForm) // Get a specific form
+			forms.POST("/:id/events", progressHandler.RecordEvent)
 		}
 
 		submission := api.Group("/submissions")
@@ -59,24 +81,81 @@ func setupRoutes(router *gin.Engine,
 
 		// Protected routes
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware(jwtUtil))
+		protected.Use(authMiddleware)
 		{
 			// User profile routes
 			protected.GET("/profile", authHandler.GetProfile)
-			// Image upload routes
-			protected.POST("/image-upload", imageHandler.UploadImage)
-			protected.GET("/images", imageHandler.GetUserImages)
-			protected.DELETE("/images/:id", imageHandler.DeleteImage)
+			// API keys are managed from a signed in session only
+			protected.GET("/api-keys", middleware.RequireSession(), apiKeyHandler.ListKeys)
+			protected.POST("/api-keys", middleware.RequireSession(), apiKeyHandler.CreateKey)
+			protected.DELETE("/api-keys/:id", middleware.RequireSession(), apiKeyHandler.RevokeKey)
+			// Devices the user is signed in on
+			protected.GET("/sessions", middleware.RequireSession(), authHandler.ListSessions)
+			protected.DELETE("/sessions/:id", middleware.RequireSession(), authHandler.RevokeSession)
+			// Workspaces and their members
+			protected.GET("/workspaces", workspaceHandler.ListWorkspaces)
+			protected.POST("/workspaces", workspaceHandler.CreateWorkspace)
+			protected.GET("/workspaces/:id/members", workspaceHandler.ListMembers)
+			protected.PUT("/workspaces/:id/members/:userId", workspaceHandler.UpdateMember)
+			protected.DELETE("/workspaces/:id/members/:userId", workspaceHandler.RemoveMember)
+			protected.POST("/workspaces/:id/invitations", workspaceHandler.CreateInvitation)
+			protected.GET("/workspaces/:id/invitations", workspaceHandler.ListInvitations)
+			protected.DELETE("/workspaces/:id/invitations/:invitationId", workspaceHandler.RevokeInvitation)
+			protected.POST("/invitations/accept", workspaceHandler.AcceptInvitation)
+
+			// Routes below work in the workspace selected by the X-Workspace-ID header
+			workspace := protected.Group("/")
+			workspace.Use(middleware.WorkspaceMiddleware(workspaceService))
+			viewResponses := middleware.RequirePermission(models.PermissionViewResponses)
+			editForms := middleware.RequirePermission(models.PermissionEditForms)
 
-			// User's personal forms routes
-			userForms := protected.Group("/my-forms")
+			// Image upload routes
+			workspace.POST("/image-upload", imageHandler.UploadImage)
+			workspace.GET("/images", imageHandler.GetUserImages)
+			workspace.DELETE("/images/:id", imageHandler.DeleteImage)
+			// Form templates
+			workspace.GET("/templates", templateHandler.ListTemplates)
+			// Folders
+			workspace.GET("/folders", folderHandler.ListFolders)
+			workspace.POST("/folders", folderHandler.CreateFolder)
+			workspace.PUT("/folders/:id", folderHandler.UpdateFolder)
+			workspace.DELETE("/folders/:id", folderHandler.DeleteFolder)
+
+			// Forms of the workspace
+			userForms := workspace.Group("/my-forms")
 			{
-				userForms.GET("/:id/toggle-draft", formHandler.ToggleDraftStatus)
-				userForms.GET("", formHandler.ListForms)         // List user's forms
-				userForms.GET("/:id", formHandler.GetForm)       // Get user's specific form
+				userForms.POST("/:id/toggle-draft", editForms, formHandler.ToggleDraftStatus)
+				// Deprecated GET alias kept for older clients, it changes data so needs the write scope
+				userForms.GET("/:id/toggle-draft", middleware.RequireWriteScope(), editForms, formHandler.ToggleDraftStatus)
+				userForms.GET("", formHandler.ListForms)         // List workspace forms
+				userForms.GET("/:id", formHandler.GetOwnedForm)  // Get a workspace form
 				userForms.POST("", formHandler.CreateForm)       // Create new form
-				userForms.PUT("/:id", formHandler.UpdateForm)    // Update user's form
-				userForms.DELETE("/:id", formHandler.DeleteForm) // Delete user's form
+				userForms.PUT("/:id", formHandler.UpdateForm)    // Update a workspace form
+				userForms.DELETE("/:id", formHandler.DeleteForm) // Move a workspace form to the trash
+				userForms.GET("/trash", formHandler.ListTrash)
+				userForms.POST("/trash/:id/restore", formHandler.RestoreForm)
+				userForms.GET("/tags", folderHandler.ListTags)
+				userForms.PUT("/:id/folder", folderHandler.MoveForm)
+				userForms.POST("/:id/preview-link", formHandler.CreatePreviewLink)
+				userForms.POST("/:id/duplicate", templateHandler.DuplicateForm)
+				userForms.POST("/from-template", templateHandler.CreateFromTemplate)
+				userForms.GET("/:id/export", editForms, bundleHandler.ExportForm)
+				userForms.POST("/import", bundleHandler.ImportForm)
+				userForms.GET("/:id/revisions", formHandler.ListRevisions)
+				userForms.GET("/:id/revisions/diff", formHandler.DiffRevisions)
+				userForms.GET("/:id/revisions/:number", formHandler.GetRevision)
+				userForms.POST("/:id/revisions/:number/rollback", formHandler.RollbackRevision)
+				userForms.GET("/:id/submissions", viewResponses, submissionHandler.ListSubmissions)
+				userForms.GET("/:id/submissions/export", viewResponses, submissionHandler.ExportSubmissions)
+				userForms.GET("/:id/analytics", viewResponses, submissionHandler.GetAnalytics)
+				userForms.GET("/:id/funnel", viewResponses, progressHandler.GetFunnel)
+				userForms.POST("/:id/webhooks", webhookHandler.CreateWebhook)
+				userForms.GET("/:id/webhooks", editForms, webhookHandler.ListWebhooks)
+				userForms.DELETE("/:id/webhooks/:webhookId", webhookHandler.DeleteWebhook)
+				userForms.GET("/:id/webhooks/:webhookId/deliveries", viewResponses, webhookHandler.ListDeliveries)
+				userForms.POST("/:id/webhooks/:webhookId/deliveries/:deliveryId/replay", webhookHandler.ReplayDelivery)
+				userForms.GET("/:id/notifications", editForms, notificationHandler.GetSettings)
+				userForms.PUT("/:id/notifications", notificationHandler.UpdateSettings)
 				userForms.POST("/generate-form", gptHandler.GenerateForm)
 			}
 		}
@@ -140,13 +219,65 @@ func main() {
 
 	// Initialize repositories
 	formRepo := repository.NewFormRepository(db)
+	if err := formRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure form indexes: %v", err)
+	}
+	if err := formRepo.BackfillListFields(context.Background()); err != nil {
+		log.Fatalf("Failed to backfill forms: %v", err)
+	}
+	revisionRepo := repository.NewRevisionRepository(db)
+	if err := revisionRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure form revision indexes: %v", err)
+	}
 	userRepo := repository.NewUserRepository(db)
 	err = userRepo.EnsureIndexes(context.Background())
 	if err != nil {
 		log.Fatalf("Failed to ensure indexes: %v", err)
 	}
+	sessionRepo := repository.NewSessionRepository(db)
+	if err := sessionRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure session indexes: %v", err)
+	}
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db)
+	if err := revokedTokenRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure revoked token indexes: %v", err)
+	}
+	userTokenRepo := repository.NewUserTokenRepository(db)
+	if err := userTokenRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure user token indexes: %v", err)
+	}
 	imageRepo := repository.NewMongoImageRepository(db)
 	submissionRepo := repository.NewSubmissionRepository(db)
+	if err := submissionRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure submission indexes: %v", err)
+	}
+	progressRepo := repository.NewProgressRepository(db)
+	if err := progressRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure progress event indexes: %v", err)
+	}
+	folderRepo := repository.NewFolderRepository(db)
+	if err := folderRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure folder indexes: %v", err)
+	}
+	webhookRepo := repository.NewWebhookRepository(db)
+	if err := webhookRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure webhook indexes: %v", err)
+	}
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	if err := workspaceRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure workspace indexes: %v", err)
+	}
+	if err := repository.BackfillWorkspaceIDs(context.Background(), db); err != nil {
+		log.Fatalf("Failed to backfill workspace IDs: %v", err)
+	}
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	if err := apiKeyRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure API key indexes: %v", err)
+	}
+	notificationRepo := repository.NewNotificationRepository(db)
+	if err := notificationRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Failed to ensure notification settings indexes: %v", err)
+	}
 
 	fileStorageConfig := storage.FileStorageConfig{
 		UploadDir:       "uploads", // Не используется для S3
@@ -167,12 +298,59 @@ func main() {
 	// Initialize JWT utility
 	jwtUtil := utils.NewJWTUtil(cfg.AuthSecret)
 
+	var mailSender notifier.Sender
+	switch cfg.MailDriver {
+	case "smtp":
+		smtpSender, err := notifier.NewSMTPSender(notifier.SMTPConfig(cfg.SMTP))
+		if err != nil {
+			log.Fatalf("Failed to configure SMTP: %v", err)
+		}
+		mailSender = smtpSender
+	case "log":
+		// Emails contain sign-in links, never use this in production
+		log.Println("MAIL_DRIVER is log, emails are written to the log")
+		mailSender = notifier.NewLogSender()
+	case "":
+		log.Println("MAIL_DRIVER is not set, emails are disabled")
+	default:
+		log.Fatalf("Unknown MAIL_DRIVER %q", cfg.MailDriver)
+	}
+
 	// Initialize services
-	formService := service.NewFormService(formRepo)
-	userService := service.NewUserService(cfg, userRepo, jwtUtil)
+	formService := service.NewFormService(formRepo, revisionRepo, jwtUtil)
+	userService := service.NewUserService(cfg, userRepo, sessionRepo, revokedTokenRepo, userTokenRepo, mailSender, jwtUtil)
 	gptService := service.NewYandexGPTService("AQVN3j7OW3-zdGmDl4p5nr8D7MHizPCs9tHd0IqG", "b1gakioh5lutqcssd8ph")
 	imageService := service.NewImageService(imageRepo, fileStorage)
-	submissionService := service.NewSubmissionService(submissionRepo)
+	submissionService := service.NewSubmissionService(submissionRepo, formRepo, progressRepo)
+	progressService := service.NewProgressService(progressRepo, formRepo)
+	webhookService := service.NewWebhookService(webhookRepo, formRepo, cfg.WebhookAllowPrivate)
+	submissionService.AddListener(webhookService)
+
+	notificationService := service.NewNotificationService(notificationRepo, formRepo, submissionRepo, userRepo, workspaceRepo, mailSender, cfg.AppURL)
+	submissionService.AddListener(notificationService)
+
+	// Background workers run until shutdown
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go webhookService.Run(workersCtx)
+	go notificationService.Run(workersCtx)
+
+	trashPurger := service.NewTrashPurger(formRepo, revisionRepo, submissionRepo, progressRepo, webhookRepo, notificationRepo, imageService, service.TrashOptions{
+		Retention:        time.Duration(cfg.Trash.RetentionDays) * 24 * time.Hour,
+		PurgeSubmissions: cfg.Trash.PurgeSubmissions,
+		PurgeImages:      cfg.Trash.PurgeImages,
+	})
+	go trashPurger.Run(workersCtx)
+
+	builtinTemplates, err := service.LoadTemplates(cfg.TemplatesDir)
+	if err != nil {
+		log.Printf("Failed to load built-in templates: %v", err)
+	}
+	templateService := service.NewTemplateService(formService, formRepo, builtinTemplates)
+	bundleService := service.NewBundleService(formService, imageService)
+	folderService := service.NewFolderService(folderRepo, formRepo)
+	workspaceService := service.NewWorkspaceService(workspaceRepo, userRepo, mailSender, cfg.AppURL)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo)
 
 	// Initialize handlers
 	formHandler := handlers.NewFormHandler(formService)
@@ -181,6 +359,14 @@ func main() {
 	gptHandler := handlers.NewGPTHandler(formService, gptService)
 	imageHandler := handlers.NewImageHandler(imageService)
 	submissionHandler := handlers.NewSubmissionHandler(submissionService)
+	progressHandler := handlers.NewProgressHandler(progressService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	templateHandler := handlers.NewTemplateHandler(templateService)
+	bundleHandler := handlers.NewBundleHandler(bundleService)
+	folderHandler := handlers.NewFolderHandler(folderService)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
 
 	// Set up Gin router
 	router := gin.Default()
@@ -194,7 +380,7 @@ func main() {
 	setupStaticFileServing(router, fileStorage)
 
 	// Routes
-	setupRoutes(router, formHandler, authHandler, healthHandler, gptHandler, imageHandler, submissionHandler, jwtUtil)
+	setupRoutes(router, formHandler, authHandler, healthHandler, gptHandler, imageHandler, submissionHandler, progressHandler, webhookHandler, notificationHandler, templateHandler, bundleHandler, folderHandler, workspaceHandler, workspaceService, apiKeyHandler, apiKeyService, userService, jwtUtil)
 
 	// Create server
 	srv := &http.Server{
@@ -214,6 +400,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopWorkers()
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
@@ -222,6 +409,8 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
+	// Let password reset emails requested before the shutdown go out
+	userService.WaitForEmails()
 
 	log.Println("Server exiting")
 }
@@ -12,6 +12,35 @@ type Config struct {
 	MongoDB              string
 	AuthSecret           string
 	TokenExpirationHours int `env:"TOKEN_EXPIRATION_HOURS" envDefault:"24"`
+	// Allows webhooks to target localhost and private networks, for development
+	WebhookAllowPrivate bool
+	// Frontend address used for links in emails
+	AppURL string
+	// "smtp" sends email with SMTP, "log" writes it to the log for local
+	// development. Email is disabled when empty.
+	MailDriver string
+	SMTP       SMTPConfig
+	// Directory with the JSON files of built-in form templates
+	TemplatesDir string
+	Trash        TrashConfig
+}
+
+// TrashConfig controls purging of deleted forms.
+type TrashConfig struct {
+	RetentionDays int
+	// Remove submissions together with purged forms
+	PurgeSubmissions bool
+	// Remove library images only purged forms used
+	PurgeImages bool
+}
+
+// SMTPConfig configures outgoing email for the "smtp" mail driver.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
 }
 
 func Load() (*Config, error) {
@@ -39,9 +68,37 @@ func Load() (*Config, error) {
 		MongoDB:              getEnvOrDefault("MONGO_DB", "formease"),
 		AuthSecret:           getEnvOrDefault("AUTH_SECRET", ""),
 		TokenExpirationHours: getIntEnvOrDefault("JWT_LIFETIME", 24),
+		WebhookAllowPrivate:  getEnvOrDefault("WEBHOOK_ALLOW_PRIVATE", "") == "true",
+		AppURL:               getEnvOrDefault("APP_URL", ""),
+		MailDriver:           mailDriver(),
+		TemplatesDir:         getEnvOrDefault("TEMPLATES_DIR", "../sample-data"),
+		Trash: TrashConfig{
+			RetentionDays:    getIntEnvOrDefault("TRASH_RETENTION_DAYS", 30),
+			PurgeSubmissions: getEnvOrDefault("TRASH_PURGE_SUBMISSIONS", "true") == "true",
+			PurgeImages:      getEnvOrDefault("TRASH_PURGE_IMAGES", "") == "true",
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnvOrDefault("SMTP_HOST", ""),
+			Port:     getIntEnvOrDefault("SMTP_PORT", 587),
+			Username: getEnvOrDefault("SMTP_USERNAME", ""),
+			Password: getEnvOrDefault("SMTP_PASSWORD", ""),
+			From:     getEnvOrDefault("SMTP_FROM", "Formease <noreply@formease.local>"),
+		},
 	}, nil
 }
 
+// mailDriver reads MAIL_DRIVER. Setting SMTP_HOST alone keeps selecting
+// SMTP as before the setting existed.
+func mailDriver() string {
+	if driver := getEnvOrDefault("MAIL_DRIVER", ""); driver != "" {
+		return driver
+	}
+	if getEnvOrDefault("SMTP_HOST", "") != "" {
+		return "smtp"
+	}
+	return ""
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/service"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+}
+
+// createAPIKeyResponse is the only place the key itself is returned.
+type createAPIKeyResponse struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	keys, err := h.apiKeyService.ListKeys(c.Request.Context(), userID.(string))
+	if err != nil {
+		logger.Error("Failed to list API keys", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid API key data", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key data"})
+		return
+	}
+
+	key, secret, err := h.apiKeyService.CreateKey(c.Request.Context(), userID.(string), req.Name, req.Scopes)
+	if err != nil {
+		logger.Error("Failed to create API key", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("API key created successfully", zap.String("keyId", key.ID.Hex()))
+	c.JSON(http.StatusCreated, createAPIKeyResponse{APIKey: key, Key: secret})
+}
+
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	id := c.Param("id")
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.apiKeyService.RevokeKey(c.Request.Context(), id, userID.(string)); err != nil {
+		logger.Error("Failed to revoke API key", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("API key revoked", zap.String("keyId", id))
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
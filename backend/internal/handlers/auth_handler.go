@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 	"strings"
 
@@ -45,12 +44,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, user, err := h.userService.Login(c.Request.Context(), loginRequest.Email, loginRequest.Password)
+	accessToken, refreshToken, user, err := h.userService.Login(c.Request.Context(), loginRequest.Email, loginRequest.Password, sessionClient(c))
 	if err != nil {
 		c.JSON(401, gin.H{"error": "Invalid credentials"})
 		return
 	}
-	logger.Info(fmt.Sprintf("accessToken: %s, refreshToken: %s", accessToken, refreshToken))
+	logger.Info("User logged in", zap.String("userId", user.ID.Hex()))
 
 	// Set access token cookie
 	c.SetSameSite(http.SameSiteStrictMode)
@@ -86,10 +85,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 }
 
 func (h *AuthHandler) Refresh(c *gin.Context) {
-	logger.Info("Refresh request received",
-		zap.String("path", c.Request.URL.Path),
-		zap.Any("headers", c.Request.Header),
-		zap.Any("cookies", c.Request.Cookies()))
+	logger.Info("Refresh request received", zap.String("path", c.Request.URL.Path))
 
 	// Try to get token from cookie first
 	refreshToken, err := c.Cookie("refresh_token")
@@ -114,10 +110,8 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	logger.Info("Found refresh token", zap.String("token", refreshToken))
-
-	// Rest of your refresh logic
-	accessToken, newRefreshToken, err := h.userService.RefreshToken(c.Request.Context(), refreshToken)
+	// Refresh tokens are single use, the new one replaces the cookie
+	accessToken, newRefreshToken, err := h.userService.RefreshToken(c.Request.Context(), refreshToken, sessionClient(c))
 	if err != nil {
 		logger.Error("Error refreshing token", zap.Error(err))
 		c.SetCookie("refresh_token", "", -1, "/", "", true, true)
@@ -126,9 +120,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	logger.Info("Setting new cookies",
-		zap.String("accessToken", accessToken),
-		zap.String("refreshToken", newRefreshToken))
+	logger.Info("Setting new cookies")
 
 	// Set access token cookie
 	c.SetSameSite(http.SameSiteStrictMode)
@@ -166,3 +158,174 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	email := c.GetString("email")
 	c.JSON(200, gin.H{"userId": userId, "email": email})
 }
+
+// Logout ends the session of the request. It does not need a valid access
+// token, so users can sign out after it expired.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	accessToken, _ := c.Cookie("access_token")
+	refreshToken, _ := c.Cookie("refresh_token")
+
+	err := h.userService.Logout(c.Request.Context(), accessToken, refreshToken)
+	clearAuthCookies(c)
+	if err != nil {
+		logger.Error("Failed to revoke session on logout", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll ends all sessions of the user, on every device.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	err := h.userService.LogoutAll(c.Request.Context(), userID.(string))
+	clearAuthCookies(c)
+	if err != nil {
+		logger.Error("Failed to revoke sessions", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("User logged out of all sessions", zap.String("userId", userID.(string)))
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
+
+type tokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req tokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	if err := h.userService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		logger.Error("Failed to verify email", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.userService.SendVerificationEmail(c.Request.Context(), userID.(string)); err != nil {
+		logger.Error("Failed to send verification email", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// ForgotPassword answers the same way whether the account exists or not.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+		return
+	}
+
+	if err := h.userService.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		logger.Error("Failed to send password reset email", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists, a password reset email was sent"})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token and password are required"})
+		return
+	}
+
+	if err := h.userService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		logger.Error("Failed to reset password", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// ListSessions returns the devices the user is signed in on.
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	refreshToken, _ := c.Cookie("refresh_token")
+	sessions, err := h.userService.ListSessions(c.Request.Context(), userID.(string), refreshToken)
+	if err != nil {
+		logger.Error("Failed to list sessions", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.userService.RevokeSession(c.Request.Context(), userID.(string), sessionID); err != nil {
+		logger.Error("Failed to revoke session", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Session revoked", zap.String("sessionId", sessionID))
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+func sessionClient(c *gin.Context) service.SessionClient {
+	return service.SessionClient{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+func clearAuthCookies(c *gin.Context) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie("access_token", "", -1, "/", "", true, true)
+	c.SetCookie("refresh_token", "", -1, "/", "", true, true)
+}
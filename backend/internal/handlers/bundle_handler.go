@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/service"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// JSON bundles embed images as base64, so the limit leaves room for that
+const maxBundleUploadSize = 200 << 20 // 200 MB
+
+type BundleHandler struct {
+	bundleService *service.BundleService
+}
+
+func NewBundleHandler(bundleService *service.BundleService) *BundleHandler {
+	return &BundleHandler{
+		bundleService: bundleService,
+	}
+}
+
+func (h *BundleHandler) ExportForm(c *gin.Context) {
+	formID := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	export, err := h.bundleService.PrepareExport(formID, workspaceID.(string), c.DefaultQuery("format", service.BundleFormatZip))
+	if err != nil {
+		logger.Error("Failed to prepare form bundle", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+	c.Status(http.StatusOK)
+
+	if err := export.WriteTo(c.Request.Context(), c.Writer); err != nil {
+		// Headers are already sent, the client gets a truncated file
+		logger.Error("Failed to stream form bundle",
+			zap.String("formId", formID),
+			zap.Error(err))
+		return
+	}
+
+	logger.Info("Form exported successfully", zap.String("formId", formID))
+}
+
+func (h *BundleHandler) ImportForm(c *gin.Context) {
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBundleUploadSize)
+	file, header, err := c.Request.FormFile("bundle")
+	if err != nil {
+		logger.Error("Failed to get bundle from request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No bundle provided"})
+		return
+	}
+	defer file.Close()
+
+	result, err := h.bundleService.ImportBundle(workspaceID.(string), file, header.Size)
+	if err != nil {
+		logger.Error("Failed to import form bundle", zap.Error(err))
+		respondFormError(c, err)
+		return
+	}
+
+	logger.Info("Form imported successfully",
+		zap.String("formId", result.Form.ID.Hex()),
+		zap.Int("conflicts", len(result.Conflicts)))
+	c.Header("ETag", formETag(result.Form))
+	c.JSON(http.StatusCreated, result)
+}
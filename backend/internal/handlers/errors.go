@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/service"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+)
+
+// errorStatus maps an error returned by a service to an HTTP status code.
+// Errors that are not *AppError are treated as internal server errors.
+func errorStatus(err error) int {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		return appErr.StatusCode
+	}
+	return http.StatusInternalServerError
+}
+
+// respondFormClosed answers with 403 and the closed payload if err says the
+// form does not accept responses. It reports whether it wrote a response.
+func respondFormClosed(c *gin.Context, err error) bool {
+	var closedErr *service.FormClosedError
+	if !errors.As(err, &closedErr) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":  "Form is closed",
+		"closed": closedErr.Closed,
+	})
+	return true
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/service"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type FolderHandler struct {
+	folderService *service.FolderService
+}
+
+func NewFolderHandler(folderService *service.FolderService) *FolderHandler {
+	return &FolderHandler{
+		folderService: folderService,
+	}
+}
+
+// folderRequest creates or updates a folder. An empty parent ID puts the
+// folder at the top level.
+type folderRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID string `json:"parentId"`
+}
+
+type moveFormRequest struct {
+	FolderID string `json:"folderId"`
+}
+
+func (h *FolderHandler) ListFolders(c *gin.Context) {
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	folders, err := h.folderService.ListFolders(c.Request.Context(), workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to list folders", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, folders)
+}
+
+func (h *FolderHandler) CreateFolder(c *gin.Context) {
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req folderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid folder data", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder data"})
+		return
+	}
+
+	folder, err := h.folderService.CreateFolder(c.Request.Context(), workspaceID.(string), req.Name, req.ParentID)
+	if err != nil {
+		logger.Error("Failed to create folder", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Folder created successfully", zap.String("folderId", folder.ID.Hex()))
+	c.JSON(http.StatusCreated, folder)
+}
+
+func (h *FolderHandler) UpdateFolder(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req folderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid folder data", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder data"})
+		return
+	}
+
+	folder, err := h.folderService.UpdateFolder(c.Request.Context(), id, workspaceID.(string), req.Name, req.ParentID)
+	if err != nil {
+		logger.Error("Failed to update folder", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Folder updated successfully", zap.String("folderId", id))
+	c.JSON(http.StatusOK, folder)
+}
+
+func (h *FolderHandler) DeleteFolder(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.folderService.DeleteFolder(c.Request.Context(), id, workspaceID.(string)); err != nil {
+		logger.Error("Failed to delete folder", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Folder deleted successfully", zap.String("folderId", id))
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted successfully"})
+}
+
+func (h *FolderHandler) MoveForm(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req moveFormRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid move request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid move request"})
+		return
+	}
+
+	form, err := h.folderService.MoveForm(c.Request.Context(), id, workspaceID.(string), req.FolderID)
+	if err != nil {
+		logger.Error("Failed to move form", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Form moved successfully",
+		zap.String("formId", id),
+		zap.String("folderId", req.FolderID))
+	c.JSON(http.StatusOK, gin.H{"id": form.ID, "folderId": form.FolderID})
+}
+
+func (h *FolderHandler) ListTags(c *gin.Context) {
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tags, err := h.folderService.ListTags(c.Request.Context(), workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to list tags", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
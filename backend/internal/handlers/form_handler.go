@@ -1,16 +1,28 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/maxzhirnov/formease/internal/models"
 	"github.com/maxzhirnov/formease/internal/service"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
 	"github.com/maxzhirnov/formease/pkg/logger"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
 
+// formResponse is a form together with the non-blocking issues found
+// while validating it.
+type formResponse struct {
+	*models.Form
+	Warnings []service.FormIssue `json:"warnings,omitempty"`
+}
+
 type FormHandler struct {
 	formService *service.FormService
 }
@@ -22,9 +34,6 @@ func NewFormHandler(formService *service.FormService) *FormHandler {
 }
 
 func (h *FormHandler) CreateForm(c *gin.Context) {
-	// Add debug logging
-	logger.Info("Request headers", zap.String("authorization", c.GetHeader("Authorization")))
-
 	var form models.Form
 	if err := c.ShouldBindJSON(&form); err != nil {
 		logger.Error("Invalid form data", zap.Error(err))
@@ -32,80 +41,163 @@ func (h *FormHandler) CreateForm(c *gin.Context) {
 		return
 	}
 
-	// Debug logging for context
-	logger.Info("Context values", zap.Any("keys", c.Keys))
-
-	userID, exists := c.Get("userID")
+	workspaceID, exists := c.Get("workspaceID")
 	if !exists {
-		logger.Error("UserID not found in context")
+		logger.Error("Workspace ID not found in context")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	logger.Info("UserID from context", zap.Any("userID", userID))
-
-	// Convert the userID to primitive.ObjectID
-	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	// Convert the workspace ID to primitive.ObjectID
+	objectID, err := primitive.ObjectIDFromHex(workspaceID.(string))
 	if err != nil {
-		logger.Error("Invalid user ID format", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		logger.Error("Invalid workspace ID format", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid workspace ID"})
 		return
 	}
 
-	// Set the UserID field of the form
-	form.UserID = objectID
+	// Forms are always created in the workspace of the request and outside
+	// of any folder, they are moved into a folder with PUT /:id/folder
+	form.WorkspaceID = objectID
+	form.FolderID = nil
 
-	if err := h.formService.CreateForm(&form); err != nil {
+	warnings, err := h.formService.CreateForm(&form)
+	if err != nil {
 		logger.Error("Failed to create form", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFormError(c, err)
 		return
 	}
 
 	logger.Info("Form created successfully", zap.String("formId", form.ID.Hex()))
-	c.JSON(http.StatusCreated, form)
+	c.JSON(http.StatusCreated, formResponse{Form: &form, Warnings: warnings})
 }
 
 func (h *FormHandler) GetForm(c *gin.Context) {
 	id := c.Param("id")
 	logger.Info("Get form", zap.String("formId", id))
 
-	form, err := h.formService.GetForm(id)
+	form, err := h.formService.GetPublicForm(id, c.Query("previewToken"))
+	if err != nil {
+		logger.Error("Failed to get form", zap.Error(err))
+		if respondFormClosed(c, err) {
+			return
+		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Form retrieved successfully", zap.String("formId", id))
+	c.JSON(http.StatusOK, form)
+}
+
+func (h *FormHandler) CreatePreviewLink(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	link, err := h.formService.CreatePreviewLink(id, workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to create preview link", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Preview link created", zap.String("formId", id))
+	c.JSON(http.StatusCreated, link)
+}
+
+func (h *FormHandler) GetOwnedForm(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	form, err := h.formService.GetOwnedForm(id, workspaceID.(string))
 	if err != nil {
 		logger.Error("Failed to get form", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	logger.Info("Form retrieved successfully", zap.String("formId", id))
+	c.Header("ETag", formETag(form))
 	c.JSON(http.StatusOK, form)
 }
 
 func (h *FormHandler) ListForms(c *gin.Context) {
 	logger.Info("Listing forms")
-	userID, ok := c.Get("userID")
+	workspaceID, ok := c.Get("workspaceID")
 	if !ok {
-		logger.Error("User ID not found in context")
+		logger.Error("Workspace ID not found in context")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	logger.Info("User ID", zap.String("userID", userID.(string)))
 
-	UserIDString, ok := userID.(string)
+	workspaceIDString, ok := workspaceID.(string)
 	if !ok {
-		logger.Error("Invalid user ID type")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+		logger.Error("Invalid workspace ID type")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid workspace ID type"})
+		return
+	}
+
+	params, err := parseFormListParams(c)
+	if err != nil {
+		logger.Error("Invalid form list parameters", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	forms, err := h.formService.ListForms(UserIDString)
+	page, err := h.formService.SearchForms(c.Request.Context(), workspaceIDString, params)
 	if err != nil {
 		logger.Error("Failed to list forms", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	logger.Info("Forms listed successfully", zap.Int("count", len(forms)))
-	c.JSON(http.StatusOK, forms)
+	logger.Info("Forms listed successfully", zap.Int("count", len(page.Forms)))
+	c.JSON(http.StatusOK, page)
+}
+
+// parseFormListParams reads the query string of the forms list: q, status
+// (draft|published), theme, folder (ID or "root"), tag, sort
+// (created|updated|responses), order (asc|desc), cursor and limit.
+func parseFormListParams(c *gin.Context) (service.FormListParams, error) {
+	params := service.FormListParams{
+		Search: c.Query("q"),
+		Status: c.Query("status"),
+		Theme:  c.Query("theme"),
+		Folder: c.Query("folder"),
+		Tag:    c.Query("tag"),
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+	}
+
+	switch order := c.DefaultQuery("order", "desc"); order {
+	case "asc":
+		params.Ascending = true
+	case "desc":
+	default:
+		return params, fmt.Errorf("invalid order: %s", order)
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return params, fmt.Errorf("invalid limit: %s", limit)
+		}
+		params.Limit = n
+	}
+
+	return params, nil
 }
 
 func (h *FormHandler) UpdateForm(c *gin.Context) {
@@ -118,13 +210,19 @@ func (h *FormHandler) UpdateForm(c *gin.Context) {
 		return
 	}
 
-	userID, ok := c.Get("userID")
+	workspaceID, ok := c.Get("workspaceID")
 	if !ok {
-		logger.Error("User ID not found in context")
+		logger.Error("Workspace ID not found in context")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
+	version, err := parseFormETag(c.GetHeader("If-Match"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
 	var form models.Form
 	if err := c.ShouldBindJSON(&form); err != nil {
 		logger.Error("Invalid form data", zap.Error(err))
@@ -134,55 +232,241 @@ func (h *FormHandler) UpdateForm(c *gin.Context) {
 
 	logger.Info("Form data received", zap.Any("formData", form))
 
-	form.UserID, err = primitive.ObjectIDFromHex(userID.(string))
-	if err != nil {
-		logger.Error("Invalid user ID", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	form.ID = objectID
 
-	if err := h.formService.UpdateForm(&form); err != nil {
+	warnings, err := h.formService.UpdateForm(&form, workspaceID.(string), version)
+	if err != nil {
 		logger.Error("Failed to update form", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondFormError(c, err)
 		return
 	}
 
 	logger.Info("Form updated successfully", zap.String("formId", id))
-	c.JSON(http.StatusOK, form)
+	c.Header("ETag", formETag(&form))
+	c.JSON(http.StatusOK, formResponse{Form: &form, Warnings: warnings})
 }
 
 func (h *FormHandler) DeleteForm(c *gin.Context) {
 	id := c.Param("id")
 
-	if err := h.formService.DeleteForm(id); err != nil {
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := h.formService.DeleteForm(id, workspaceID.(string)); err != nil {
 		logger.Error("Failed to delete form", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Form moved to trash", zap.String("formId", id))
+	c.JSON(http.StatusOK, gin.H{"message": "Form moved to trash"})
+}
+
+func (h *FormHandler) ListTrash(c *gin.Context) {
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	forms, err := h.formService.ListTrash(workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to list trash", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, forms)
+}
+
+func (h *FormHandler) RestoreForm(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	form, err := h.formService.RestoreForm(id, workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to restore form", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	logger.Info("Form deleted successfully", zap.String("formId", id))
-	c.JSON(http.StatusOK, gin.H{"message": "Form deleted successfully"})
+	logger.Info("Form restored from trash", zap.String("formId", id))
+	c.Header("ETag", formETag(form))
+	c.JSON(http.StatusOK, form)
 }
 
 func (h *FormHandler) ToggleDraftStatus(c *gin.Context) {
 	id := c.Param("id")
 	logger.Info("Toggle draft status", zap.String("formId", id))
 
-	userID, ok := c.Get("userID")
+	workspaceID, ok := c.Get("workspaceID")
 	if !ok {
-		logger.Error("User ID not found in context")
+		logger.Error("Workspace ID not found in context")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	if err := h.formService.ToggleDraftStatus(id, userID.(string)); err != nil {
+	form, err := h.formService.ToggleDraftStatus(id, workspaceID.(string))
+	if err != nil {
 		logger.Error("Failed to toggle draft status", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	logger.Info("Draft status toggled successfully", zap.String("formId", id))
-	c.JSON(http.StatusOK, gin.H{"message": "Draft status updated successfully"})
+	c.Header("ETag", formETag(form))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Draft status updated successfully",
+		"isDraft": form.IsDraft,
+		"version": form.Version,
+	})
+}
+
+func (h *FormHandler) ListRevisions(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	revisions, err := h.formService.ListRevisions(c.Request.Context(), id, workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to list revisions", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func (h *FormHandler) GetRevision(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	revision, err := h.formService.GetRevision(c.Request.Context(), id, workspaceID.(string), number)
+	if err != nil {
+		logger.Error("Failed to get revision", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+func (h *FormHandler) DiffRevisions(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameters from and to must be revision numbers"})
+		return
+	}
+
+	diff, err := h.formService.DiffRevisions(c.Request.Context(), id, workspaceID.(string), from, to)
+	if err != nil {
+		logger.Error("Failed to diff revisions", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+func (h *FormHandler) RollbackRevision(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	form, err := h.formService.RollbackRevision(c.Request.Context(), id, workspaceID.(string), number)
+	if err != nil {
+		logger.Error("Failed to roll back form", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Form rolled back",
+		zap.String("formId", id),
+		zap.Int("revision", number))
+	c.Header("ETag", formETag(form))
+	c.JSON(http.StatusOK, form)
+}
+
+func formETag(form *models.Form) string {
+	return `"` + strconv.Itoa(form.Version) + `"`
+}
+
+// parseFormETag returns the version from an If-Match header. A weak
+// validator is accepted as well, "*" matches any version. A missing header
+// is answered with 428 Precondition Required.
+func parseFormETag(header string) (int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, apperrors.NewPreconditionRequiredError("If-Match header with the form ETag is required")
+	}
+	if header == "*" {
+		return service.AnyFormVersion, nil
+	}
+	header = strings.TrimPrefix(header, "W/")
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil {
+		return 0, apperrors.NewBadRequestError("Invalid If-Match header")
+	}
+	return version, nil
+}
+
+func respondFormError(c *gin.Context, err error) {
+	var validationErr *service.FormValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Form validation failed",
+			"issues": validationErr.Issues,
+		})
+		return
+	}
+	c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 }
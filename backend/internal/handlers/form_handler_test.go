@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseFormETag(t *testing.T) {
+	tests := []struct {
+		header      string
+		wantVersion int
+		wantStatus  int
+	}{
+		{header: `"3"`, wantVersion: 3},
+		{header: `W/"4"`, wantVersion: 4},
+		{header: ` "5" `, wantVersion: 5},
+		{header: "*", wantVersion: service.AnyFormVersion},
+		{header: "", wantStatus: http.StatusPreconditionRequired},
+		{header: `"abc"`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			version, err := parseFormETag(tt.header)
+			if tt.wantStatus != 0 {
+				if err == nil || errorStatus(err) != tt.wantStatus {
+					t.Fatalf("parseFormETag(%q) error = %v, want status %d", tt.header, err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFormETag(%q) error = %v", tt.header, err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("parseFormETag(%q) = %d, want %d", tt.header, version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestCreateFormIgnoresFolder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	forms := &fakeFormStore{}
+	handler := NewFormHandler(service.NewFormService(forms, fakeRevisionStore{}, nil))
+	workspaceID := primitive.NewObjectID()
+
+	router := gin.New()
+	router.POST("/my-forms", func(c *gin.Context) { c.Set("workspaceID", workspaceID.Hex()) }, handler.CreateForm)
+
+	body := `{
+		"name": "Survey",
+		"workspaceId": "` + primitive.NewObjectID().Hex() + `",
+		"folderId": "` + primitive.NewObjectID().Hex() + `",
+		"questions": [{"id": 1, "type": "input", "question": "Name?"}]
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/my-forms", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /my-forms = %d, want 201 (%s)", rec.Code, rec.Body.String())
+	}
+	if forms.created == nil {
+		t.Fatal("form was not stored")
+	}
+	if forms.created.FolderID != nil {
+		t.Errorf("FolderID = %s, want the form outside of any folder", forms.created.FolderID.Hex())
+	}
+	if forms.created.WorkspaceID != workspaceID {
+		t.Errorf("WorkspaceID = %s, want the workspace of the request %s", forms.created.WorkspaceID.Hex(), workspaceID.Hex())
+	}
+}
+
+// fakeFormStore only stores created forms.
+type fakeFormStore struct {
+	service.FormStore
+	created *models.Form
+}
+
+func (f *fakeFormStore) CreateForm(form *models.Form) error {
+	form.ID = primitive.NewObjectID()
+	copied := *form
+	f.created = &copied
+	return nil
+}
+
+func (f *fakeFormStore) SetRevision(formID primitive.ObjectID, number, version int) error {
+	return nil
+}
+
+type fakeRevisionStore struct {
+	service.RevisionStore
+}
+
+func (fakeRevisionStore) CreateRevision(ctx context.Context, revision *models.FormRevision) error {
+	revision.Number = 1
+	return nil
+}
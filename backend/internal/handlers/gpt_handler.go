@@ -44,10 +44,10 @@ func (h *GPTHandler) GenerateForm(c *gin.Context) {
 		return
 	}
 
-	// Get user ID from context
-	userID, exists := c.Get("userID")
+	// Get workspace ID from context
+	workspaceID, exists := c.Get("workspaceID")
 	if !exists {
-		logger.Error("UserID not found in context")
+		logger.Error("Workspace ID not found in context")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
@@ -212,22 +212,26 @@ func (h *GPTHandler) GenerateForm(c *gin.Context) {
 		return
 	}
 
-	// Set user ID and draft status
-	objectID, err := primitive.ObjectIDFromHex(userID.(string))
+	// Set workspace ID and draft status
+	objectID, err := primitive.ObjectIDFromHex(workspaceID.(string))
 	if err != nil {
-		logger.Error("Invalid user ID format", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		logger.Error("Invalid workspace ID format", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid workspace ID"})
 		return
 	}
-	generatedForm.UserID = objectID
+	generatedForm.WorkspaceID = objectID
 	generatedForm.IsDraft = true
 
 	// Save the form
-	if err := h.formService.CreateForm(&generatedForm); err != nil {
+	warnings, err := h.formService.CreateForm(&generatedForm)
+	if err != nil {
 		logger.Error("Failed to save generated form", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save form"})
+		respondFormError(c, err)
 		return
 	}
+	if len(warnings) > 0 {
+		logger.Info("Generated form has warnings", zap.Any("warnings", warnings))
+	}
 
 	logger.Info("Form generated and saved successfully", zap.String("formId", generatedForm.ID.Hex()))
 	c.JSON(http.StatusCreated, generatedForm)
@@ -286,6 +290,9 @@ func (h *GPTHandler) validateAndFixForm(form *models.Form) error {
 		h.validateAndFixNextQuestion(&form.Questions[i], len(form.Questions))
 	}
 
+	// Submissions reference options by ID, so make sure they are unique
+	service.AssignOptionIDs(form)
+
 	// Fix thank you message
 	h.validateAndFixThankYouMessage(&form.ThankYouMessage)
 
@@ -327,8 +334,8 @@ func (h *GPTHandler) validateAndFixQuestionType(q *models.Question) error {
 		// Set default type if invalid
 		q.Type = "single-choice"
 		q.Options = []models.Option{
-			{Text: "Option 1", Icon: "✨"},
-			{Text: "Option 2", Icon: "🌟"},
+			{ID: 1, Text: "Option 1", Icon: "✨"},
+			{ID: 2, Text: "Option 2", Icon: "🌟"},
 		}
 	}
 
@@ -27,9 +27,9 @@ func (h *ImageHandler) UploadImage(c *gin.Context) {
 	logger.Info("Starting image upload")
 
 	// Получаем ID пользователя из контекста
-	userID, exists := c.Get("userID")
+	workspaceID, exists := c.Get("workspaceID")
 	if !exists {
-		logger.Error("UserID not found in context")
+		logger.Error("Workspace ID not found in context")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
@@ -45,11 +45,11 @@ func (h *ImageHandler) UploadImage(c *gin.Context) {
 
 	// Создаем модель изображения
 	image := &models.Image{
-		UserID:    userID.(string),
-		Filename:  header.Filename,
-		Size:      header.Size,
-		Type:      header.Header.Get("Content-Type"),
-		CreatedAt: time.Now(),
+		WorkspaceID: workspaceID.(string),
+		Filename:    header.Filename,
+		Size:        header.Size,
+		Type:        header.Header.Get("Content-Type"),
+		CreatedAt:   time.Now(),
 	}
 
 	// Загружаем изображение через сервис
@@ -62,15 +62,15 @@ func (h *ImageHandler) UploadImage(c *gin.Context) {
 
 	logger.Info("Image uploaded successfully",
 		zap.String("imageId", uploadedImage.ID.Hex()),
-		zap.String("userId", userID.(string)))
+		zap.String("workspaceId", workspaceID.(string)))
 
 	c.JSON(http.StatusCreated, uploadedImage)
 }
 
 func (s *ImageHandler) GetUserImages(c *gin.Context) {
-	userID, ok := c.Get("userID")
+	workspaceID, ok := c.Get("workspaceID")
 	if !ok {
-		logger.Error("UserID not found in context")
+		logger.Error("Workspace ID not found in context")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
@@ -97,24 +97,24 @@ func (s *ImageHandler) GetUserImages(c *gin.Context) {
 		return
 	}
 
-	userIDString, ok := userID.(string)
+	workspaceIDString, ok := workspaceID.(string)
 	if !ok {
-		logger.Error("Invalid user ID type")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+		logger.Error("Invalid workspace ID type")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid workspace ID type"})
 		return
 	}
 
-	logger.Info("Finding images by user ID", zap.String("userID", userIDString))
-	images, err := s.imageService.FindByUserID(userIDString, pageInt, limitInt)
+	logger.Info("Finding images by workspace ID", zap.String("workspaceID", workspaceIDString))
+	images, err := s.imageService.FindByWorkspaceID(workspaceIDString, pageInt, limitInt)
 	if err != nil {
 		logger.Error("Failed to find images", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	totalCount, err := s.imageService.CountByUserID(userIDString)
+	totalCount, err := s.imageService.CountByWorkspaceID(workspaceIDString)
 	if err != nil {
-		logger.Error("Failed to count user images", zap.Error(err))
+		logger.Error("Failed to count workspace images", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -135,17 +135,17 @@ func (s *ImageHandler) GetUserImages(c *gin.Context) {
 func (s *ImageHandler) DeleteImage(c *gin.Context) {
 	imageID := c.Param("id")
 
-	userID, ok := c.Get("userID")
+	workspaceID, ok := c.Get("workspaceID")
 	if !ok {
-		logger.Error("UserID not found in context")
+		logger.Error("Workspace ID not found in context")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	userIDString, ok := userID.(string)
+	workspaceIDString, ok := workspaceID.(string)
 	if !ok {
-		logger.Error("Invalid user ID type")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+		logger.Error("Invalid workspace ID type")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid workspace ID type"})
 		return
 	}
 
@@ -157,10 +157,10 @@ func (s *ImageHandler) DeleteImage(c *gin.Context) {
 		return
 	}
 
-	if image.UserID != userIDString {
+	if image.WorkspaceID != workspaceIDString {
 		logger.Error("Unauthorized image deletion attempt",
-			zap.String("requestUserID", userIDString),
-			zap.String("imageUserID", image.UserID))
+			zap.String("requestWorkspaceID", workspaceIDString),
+			zap.String("imageWorkspaceID", image.WorkspaceID))
 		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized to delete this image"})
 		return
 	}
@@ -173,7 +173,7 @@ func (s *ImageHandler) DeleteImage(c *gin.Context) {
 
 	logger.Info("Image deleted successfully",
 		zap.String("imageID", imageID),
-		zap.String("userID", userIDString))
+		zap.String("workspaceID", workspaceIDString))
 
 	c.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
 }
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/service"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+type notificationSettingsRequest struct {
+	Mode string `json:"mode" binding:"required"`
+}
+
+func (h *NotificationHandler) GetSettings(c *gin.Context) {
+	formID := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	settings, err := h.notificationService.GetSettings(c.Request.Context(), formID, workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to get notification settings", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *NotificationHandler) UpdateSettings(c *gin.Context) {
+	formID := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req notificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid notification settings", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification settings"})
+		return
+	}
+
+	settings, err := h.notificationService.UpdateSettings(c.Request.Context(), formID, workspaceID.(string), req.Mode)
+	if err != nil {
+		logger.Error("Failed to update notification settings", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Notification settings updated",
+		zap.String("formId", formID),
+		zap.String("mode", settings.Mode))
+	c.JSON(http.StatusOK, settings)
+}
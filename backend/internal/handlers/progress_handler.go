@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/service"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type ProgressHandler struct {
+	progressService *service.ProgressService
+}
+
+func NewProgressHandler(progressService *service.ProgressService) *ProgressHandler {
+	return &ProgressHandler{
+		progressService: progressService,
+	}
+}
+
+type progressEventRequest struct {
+	SessionID  string `json:"sessionId" binding:"required"`
+	QuestionID int    `json:"questionId" binding:"required"`
+	Type       string `json:"type" binding:"required"`
+}
+
+func (h *ProgressHandler) RecordEvent(c *gin.Context) {
+	formID := c.Param("id")
+
+	var req progressEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid progress event data", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid progress event data"})
+		return
+	}
+
+	event := &models.ProgressEvent{
+		SessionID:  req.SessionID,
+		QuestionID: req.QuestionID,
+		Type:       req.Type,
+	}
+	if err := h.progressService.RecordEvent(c.Request.Context(), formID, event); err != nil {
+		logger.Error("Failed to record progress event", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ProgressHandler) GetFunnel(c *gin.Context) {
+	formID := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	report, err := h.progressService.GetFunnel(c.Request.Context(), formID, workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to get funnel report", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Funnel report retrieved successfully", zap.String("formId", formID))
+	c.JSON(http.StatusOK, report)
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/maxzhirnov/formease/internal/models"
@@ -24,17 +28,184 @@ func (h *SubmissionHandler) CreateSubmission(c *gin.Context) {
 
 	var sub models.Submission
 	if err := c.ShouldBindJSON(&sub); err != nil {
-		logger.Error("Invalid form data", zap.Error(err))
+		logger.Error("Invalid submission data", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid submission data"})
 		return
 	}
 
 	if err := h.subService.CreateSubmission(&sub); err != nil {
-		logger.Error("Failed to create form", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Error("Failed to create submission", zap.Error(err))
+		var validationErr *service.SubmissionValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "Submission validation failed",
+				"details": validationErr.Errors,
+			})
+			return
+		}
+		if respondFormClosed(c, err) {
+			return
+		}
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	logger.Info("Form created successfully", zap.String("formId", sub.ID.Hex()))
+	logger.Info("Submission created successfully",
+		zap.String("submissionId", sub.ID.Hex()),
+		zap.String("formId", sub.FormID.Hex()))
 	c.JSON(http.StatusCreated, sub)
 }
+
+func (h *SubmissionHandler) ListSubmissions(c *gin.Context) {
+	formID := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	params, err := parseSubmissionListParams(c)
+	if err != nil {
+		logger.Error("Invalid submission list parameters", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.subService.ListSubmissions(c.Request.Context(), formID, workspaceID.(string), params)
+	if err != nil {
+		logger.Error("Failed to list submissions", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Submissions listed successfully",
+		zap.String("formId", formID),
+		zap.Int("count", len(page.Submissions)))
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *SubmissionHandler) GetAnalytics(c *gin.Context) {
+	formID := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	analytics, err := h.subService.GetAnalytics(c.Request.Context(), formID, workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to get form analytics", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Form analytics retrieved successfully",
+		zap.String("formId", formID),
+		zap.Int64("totalResponses", analytics.TotalResponses))
+	c.JSON(http.StatusOK, analytics)
+}
+
+func (h *SubmissionHandler) ExportSubmissions(c *gin.Context) {
+	formID := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	export, err := h.subService.PrepareExport(formID, workspaceID.(string), c.DefaultQuery("format", service.ExportFormatCSV))
+	if err != nil {
+		logger.Error("Failed to prepare submissions export", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.Filename))
+	c.Status(http.StatusOK)
+
+	if err := export.WriteTo(c.Request.Context(), c.Writer); err != nil {
+		// Headers are already sent, the client gets a truncated file
+		logger.Error("Failed to stream submissions export",
+			zap.String("formId", formID),
+			zap.Error(err))
+		return
+	}
+
+	logger.Info("Submissions exported successfully", zap.String("formId", formID))
+}
+
+// parseSubmissionListParams reads the query string of the submissions list:
+// from, to (RFC3339 or YYYY-MM-DD, "to" is exclusive), order (asc|desc),
+// cursor, limit and answer[<questionId>]=<value> filters.
+func parseSubmissionListParams(c *gin.Context) (service.SubmissionListParams, error) {
+	var params service.SubmissionListParams
+
+	if from := c.Query("from"); from != "" {
+		t, err := parseDateParam(from, false)
+		if err != nil {
+			return params, fmt.Errorf("invalid from date: %s", from)
+		}
+		params.From = &t
+	}
+	if to := c.Query("to"); to != "" {
+		t, err := parseDateParam(to, true)
+		if err != nil {
+			return params, fmt.Errorf("invalid to date: %s", to)
+		}
+		params.To = &t
+	}
+
+	switch order := c.DefaultQuery("order", "desc"); order {
+	case "asc":
+		params.Ascending = true
+	case "desc":
+	default:
+		return params, fmt.Errorf("invalid order: %s", order)
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return params, fmt.Errorf("invalid limit: %s", limit)
+		}
+		params.Limit = n
+	}
+
+	params.Cursor = c.Query("cursor")
+
+	for key, value := range c.QueryMap("answer") {
+		questionID, err := strconv.Atoi(key)
+		if err != nil {
+			return params, fmt.Errorf("invalid answer filter question ID: %s", key)
+		}
+		if params.AnswerFilters == nil {
+			params.AnswerFilters = make(map[int]string)
+		}
+		params.AnswerFilters[questionID] = value
+	}
+
+	return params, nil
+}
+
+// parseDateParam accepts RFC3339 timestamps and plain dates. A plain date
+// used as an upper bound covers the whole day.
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/service"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type TemplateHandler struct {
+	templateService *service.TemplateService
+}
+
+func NewTemplateHandler(templateService *service.TemplateService) *TemplateHandler {
+	return &TemplateHandler{
+		templateService: templateService,
+	}
+}
+
+type createFromTemplateRequest struct {
+	TemplateID string `json:"templateId" binding:"required"`
+	Name       string `json:"name"`
+}
+
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	templates, err := h.templateService.ListTemplates(c.Request.Context(), workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to list templates", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *TemplateHandler) CreateFromTemplate(c *gin.Context) {
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req createFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid template request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template request"})
+		return
+	}
+
+	form, warnings, err := h.templateService.CreateFromTemplate(req.TemplateID, workspaceID.(string), req.Name)
+	if err != nil {
+		logger.Error("Failed to create form from template", zap.Error(err))
+		respondFormError(c, err)
+		return
+	}
+
+	logger.Info("Form created from template",
+		zap.String("formId", form.ID.Hex()),
+		zap.String("templateId", req.TemplateID))
+	c.Header("ETag", formETag(form))
+	c.JSON(http.StatusCreated, formResponse{Form: form, Warnings: warnings})
+}
+
+func (h *TemplateHandler) DuplicateForm(c *gin.Context) {
+	id := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	form, warnings, err := h.templateService.DuplicateForm(id, workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to duplicate form", zap.Error(err))
+		respondFormError(c, err)
+		return
+	}
+
+	logger.Info("Form duplicated",
+		zap.String("sourceId", id),
+		zap.String("formId", form.ID.Hex()))
+	c.Header("ETag", formETag(form))
+	c.JSON(http.StatusCreated, formResponse{Form: form, Warnings: warnings})
+}
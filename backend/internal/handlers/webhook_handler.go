@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/service"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+type createWebhookRequest struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// createWebhookResponse is the only place the signing secret is returned.
+type createWebhookResponse struct {
+	*models.Webhook
+	Secret string `json:"secret"`
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	formID := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid webhook data", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook data"})
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), formID, workspaceID.(string), req.URL)
+	if err != nil {
+		logger.Error("Failed to create webhook", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Webhook created successfully",
+		zap.String("webhookId", webhook.ID.Hex()),
+		zap.String("formId", formID))
+	c.JSON(http.StatusCreated, createWebhookResponse{Webhook: webhook, Secret: webhook.Secret})
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	formID := c.Param("id")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(c.Request.Context(), formID, workspaceID.(string))
+	if err != nil {
+		logger.Error("Failed to list webhooks", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	formID := c.Param("id")
+	webhookID := c.Param("webhookId")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), formID, workspaceID.(string), webhookID); err != nil {
+		logger.Error("Failed to delete webhook", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Webhook deleted successfully", zap.String("webhookId", webhookID))
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	formID := c.Param("id")
+	webhookID := c.Param("webhookId")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), formID, workspaceID.(string), webhookID)
+	if err != nil {
+		logger.Error("Failed to list webhook deliveries", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	formID := c.Param("id")
+	webhookID := c.Param("webhookId")
+	deliveryID := c.Param("deliveryId")
+
+	workspaceID, ok := c.Get("workspaceID")
+	if !ok {
+		logger.Error("Workspace ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	delivery, err := h.webhookService.ReplayDelivery(c.Request.Context(), formID, workspaceID.(string), webhookID, deliveryID)
+	if err != nil {
+		logger.Error("Failed to replay webhook delivery", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Webhook delivery queued for replay", zap.String("deliveryId", deliveryID))
+	c.JSON(http.StatusAccepted, delivery)
+}
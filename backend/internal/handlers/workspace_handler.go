@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/service"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type WorkspaceHandler struct {
+	workspaceService *service.WorkspaceService
+}
+
+func NewWorkspaceHandler(workspaceService *service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+	}
+}
+
+type createWorkspaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type updateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type createInvitationRequest struct {
+	Email string `json:"email" binding:"required"`
+	Role  string `json:"role" binding:"required"`
+}
+
+// createInvitationResponse is the only place the invitation token is
+// returned, so it can be shared when email is not configured.
+type createInvitationResponse struct {
+	*models.WorkspaceInvitation
+	Token string `json:"token"`
+}
+
+type acceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	workspaces, err := h.workspaceService.ListWorkspaces(c.Request.Context(), userID.(string))
+	if err != nil {
+		logger.Error("Failed to list workspaces", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req createWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid workspace data", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace data"})
+		return
+	}
+
+	workspace, err := h.workspaceService.CreateWorkspace(c.Request.Context(), userID.(string), req.Name)
+	if err != nil {
+		logger.Error("Failed to create workspace", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Workspace created successfully", zap.String("workspaceId", workspace.ID.Hex()))
+	c.JSON(http.StatusCreated, workspace)
+}
+
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	workspaceID := c.Param("id")
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	members, err := h.workspaceService.ListMembers(c.Request.Context(), workspaceID, userID.(string))
+	if err != nil {
+		logger.Error("Failed to list workspace members", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	workspaceID := c.Param("id")
+	memberID := c.Param("userId")
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req updateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid member data", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid member data"})
+		return
+	}
+
+	if err := h.workspaceService.UpdateMemberRole(c.Request.Context(), workspaceID, userID.(string), memberID, req.Role); err != nil {
+		logger.Error("Failed to update workspace member", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member updated successfully"})
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	workspaceID := c.Param("id")
+	memberID := c.Param("userId")
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.workspaceService.RemoveMember(c.Request.Context(), workspaceID, userID.(string), memberID); err != nil {
+		logger.Error("Failed to remove workspace member", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+func (h *WorkspaceHandler) CreateInvitation(c *gin.Context) {
+	workspaceID := c.Param("id")
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req createInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid invitation data", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation data"})
+		return
+	}
+
+	invitation, token, err := h.workspaceService.CreateInvitation(c.Request.Context(), workspaceID, userID.(string), req.Email, req.Role)
+	if err != nil {
+		logger.Error("Failed to create invitation", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Invitation created successfully",
+		zap.String("invitationId", invitation.ID.Hex()),
+		zap.String("workspaceId", workspaceID))
+	c.JSON(http.StatusCreated, createInvitationResponse{WorkspaceInvitation: invitation, Token: token})
+}
+
+func (h *WorkspaceHandler) ListInvitations(c *gin.Context) {
+	workspaceID := c.Param("id")
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	invitations, err := h.workspaceService.ListInvitations(c.Request.Context(), workspaceID, userID.(string))
+	if err != nil {
+		logger.Error("Failed to list invitations", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (h *WorkspaceHandler) RevokeInvitation(c *gin.Context) {
+	workspaceID := c.Param("id")
+	invitationID := c.Param("invitationId")
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.workspaceService.RevokeInvitation(c.Request.Context(), workspaceID, userID.(string), invitationID); err != nil {
+		logger.Error("Failed to revoke invitation", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+func (h *WorkspaceHandler) AcceptInvitation(c *gin.Context) {
+	userID, ok := c.Get("userID")
+	if !ok {
+		logger.Error("User ID not found in context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req acceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error("Invalid invitation data", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation data"})
+		return
+	}
+
+	workspace, err := h.workspaceService.AcceptInvitation(c.Request.Context(), userID.(string), req.Token)
+	if err != nil {
+		logger.Error("Failed to accept invitation", zap.Error(err))
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Invitation accepted", zap.String("workspaceId", workspace.ID.Hex()))
+	c.JSON(http.StatusOK, workspace)
+}
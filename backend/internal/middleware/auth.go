@@ -2,16 +2,47 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/models"
 	"github.com/maxzhirnov/formease/internal/utils"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
 	"github.com/maxzhirnov/formease/pkg/logger"
 	"go.uber.org/zap"
 )
 
-func AuthMiddleware(jwtUtil *utils.JWTUtil) gin.HandlerFunc {
+const (
+	// AuthMethodSession is set for requests authenticated by the access token cookie
+	AuthMethodSession = "session"
+	// AuthMethodAPIKey is set for requests authenticated by an API key
+	AuthMethodAPIKey = "api_key"
+)
+
+// APIKeyAuthenticator resolves an API key to the key and its user.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, secret string) (*models.APIKey, *models.User, error)
+}
+
+// TokenDenylist reports access tokens revoked before they expired.
+type TokenDenylist interface {
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// AuthMiddleware accepts an API key in the Authorization header or the
+// access token cookie. Both set "userID", "email" and "authMethod"; cookie
+// sessions also set "tokenID" and "sessionID", API keys "apiKeyID" and
+// "apiKeyWrite".
+func AuthMiddleware(jwtUtil *utils.JWTUtil, apiKeys APIKeyAuthenticator, denylist TokenDenylist) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if header := c.GetHeader("Authorization"); header != "" {
+			authenticateAPIKey(c, apiKeys, header)
+			return
+		}
+
 		// Get token from cookie instead of header
 		accessToken, err := c.Cookie("access_token")
 		if err != nil {
@@ -22,7 +53,7 @@ func AuthMiddleware(jwtUtil *utils.JWTUtil) gin.HandlerFunc {
 		}
 
 		// Validate token
-		userId, email, err := jwtUtil.ValidateToken(accessToken)
+		claims, err := jwtUtil.ValidateAccessToken(accessToken)
 		if err != nil {
 			logger.Error("Failed to validate access token", zap.Error(err))
 			// Clear invalid cookie
@@ -32,10 +63,92 @@ func AuthMiddleware(jwtUtil *utils.JWTUtil) gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := denylist.IsTokenRevoked(c.Request.Context(), claims.ID)
+		if err != nil {
+			logger.Error("Failed to check token denylist", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.SetCookie("access_token", "", -1, "/", "", true, true)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
 		// Set user information in context
-		c.Set("userID", userId)
-		c.Set("email", email)
+		c.Set("userID", claims.UserID)
+		c.Set("email", claims.Email)
+		c.Set("authMethod", AuthMethodSession)
+		c.Set("tokenID", claims.ID)
+		c.Set("sessionID", claims.SessionID)
+
+		c.Next()
+	}
+}
 
+// authenticateAPIKey handles requests with a "Bearer <key>" header. Keys
+// with only the read scope may not change data.
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, header string) {
+	scheme, secret, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(secret) == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header must be Bearer <API key>"})
+		c.Abort()
+		return
+	}
+
+	key, user, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), strings.TrimSpace(secret))
+	if err != nil {
+		logger.Error("Failed to authenticate API key", zap.Error(err))
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) {
+			c.JSON(appErr.StatusCode, gin.H{"error": appErr.Error()})
+		} else {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		}
+		c.Abort()
+		return
+	}
+
+	write := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead
+	if !key.Allows(write) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key scope does not allow this request"})
+		c.Abort()
+		return
+	}
+
+	c.Set("userID", user.ID.Hex())
+	c.Set("email", user.Email)
+	c.Set("authMethod", AuthMethodAPIKey)
+	c.Set("apiKeyID", key.ID.Hex())
+	c.Set("apiKeyWrite", key.Allows(true))
+
+	c.Next()
+}
+
+// RequireWriteScope rejects read-only API keys on routes that change data
+// even though they use GET, such as the deprecated toggle-draft alias.
+func RequireWriteScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("authMethod") == AuthMethodAPIKey && !c.GetBool("apiKeyWrite") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key scope does not allow this request"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests authenticated by an API key, so keys can
+// not be used to create more keys.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("authMethod") != AuthMethodSession {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This action requires signing in"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeAPIKeys map[string]*models.APIKey
+
+func (f fakeAPIKeys) AuthenticateAPIKey(ctx context.Context, secret string) (*models.APIKey, *models.User, error) {
+	key, ok := f[secret]
+	if !ok {
+		return nil, nil, errors.New("invalid API key")
+	}
+	return key, &models.User{ID: key.UserID, Email: "ann@example.com"}, nil
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := fakeAPIKeys{
+		"fe_read":  {ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Scopes: []string{models.APIKeyScopeRead}},
+		"fe_write": {ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Scopes: []string{models.APIKeyScopeWrite}},
+	}
+
+	router := gin.New()
+	api := router.Group("/", AuthMiddleware(utils.NewJWTUtil("test-secret"), keys, nil))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.GET("/forms", ok)
+	api.POST("/forms/:id/toggle-draft", ok)
+	api.GET("/forms/:id/toggle-draft", RequireWriteScope(), ok)
+	api.POST("/api-keys", RequireSession(), ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"read key reads", http.MethodGet, "/forms", "fe_read", http.StatusOK},
+		{"read key can not write", http.MethodPost, "/forms/1/toggle-draft", "fe_read", http.StatusForbidden},
+		{"read key can not use the GET alias", http.MethodGet, "/forms/1/toggle-draft", "fe_read", http.StatusForbidden},
+		{"write key writes", http.MethodPost, "/forms/1/toggle-draft", "fe_write", http.StatusOK},
+		{"write key uses the GET alias", http.MethodGet, "/forms/1/toggle-draft", "fe_write", http.StatusOK},
+		{"keys can not create keys", http.MethodPost, "/api-keys", "fe_write", http.StatusForbidden},
+		{"unknown key", http.MethodGet, "/forms", "fe_unknown", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d (%s)", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+type fakeDenylist map[string]bool
+
+func (f fakeDenylist) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return f[tokenID], nil
+}
+
+func TestAuthMiddlewareDenylist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtUtil := utils.NewJWTUtil("test-secret")
+	active, _, err := jwtUtil.GenerateToken("user1", "ann@example.com", "session1", 1)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	revoked, claims, err := jwtUtil.GenerateToken("user1", "ann@example.com", "session2", 1)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	denylist := fakeDenylist{claims.ID: true}
+
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(jwtUtil, fakeAPIKeys{}, denylist), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("sessionID"))
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"active token", active, http.StatusOK},
+		{"revoked token", revoked, http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.token})
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("GET /me = %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.want == http.StatusOK && rec.Body.String() != "session1" {
+				t.Errorf("sessionID = %q, want session1", rec.Body.String())
+			}
+		})
+	}
+}
@@ -48,8 +48,8 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Requested-With, Cookie")
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Set-Cookie")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Requested-With, Cookie, If-Match, X-Workspace-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Set-Cookie, ETag")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
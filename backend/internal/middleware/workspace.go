@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/models"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// WorkspaceHeader selects the workspace a request works in. Requests
+// without it use the personal workspace of the user.
+const WorkspaceHeader = "X-Workspace-ID"
+
+// WorkspaceResolver returns the role of a user in a workspace.
+type WorkspaceResolver interface {
+	ResolveRole(ctx context.Context, userID, workspaceID string) (string, error)
+}
+
+// WorkspaceMiddleware must run after AuthMiddleware. It sets "workspaceID"
+// and "workspaceRole" in the context and rejects requests the role does not
+// allow: reading requires viewing forms, everything else requires editing.
+func WorkspaceMiddleware(resolver WorkspaceResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		if userID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		workspaceID := c.GetHeader(WorkspaceHeader)
+		if workspaceID == "" {
+			workspaceID = userID
+		}
+
+		role, err := resolver.ResolveRole(c.Request.Context(), userID, workspaceID)
+		if err != nil {
+			logger.Error("Failed to resolve workspace role",
+				zap.String("workspaceId", workspaceID),
+				zap.Error(err))
+			status := http.StatusInternalServerError
+			var appErr *apperrors.AppError
+			if errors.As(err, &appErr) {
+				status = appErr.StatusCode
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Set("workspaceID", workspaceID)
+		c.Set("workspaceRole", role)
+
+		permission := models.PermissionEditForms
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			permission = models.PermissionViewForms
+		}
+		if !checkPermission(c, permission) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission rejects requests whose workspace role lacks permission.
+// It is used on routes that need more than WorkspaceMiddleware checks.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkPermission(c, permission) {
+			return
+		}
+		c.Next()
+	}
+}
+
+func checkPermission(c *gin.Context, permission string) bool {
+	role := c.GetString("workspaceRole")
+	if models.RoleAllows(role, permission) {
+		return true
+	}
+
+	logger.Error("Workspace role lacks permission",
+		zap.String("role", role),
+		zap.String("permission", permission),
+		zap.String("path", c.FullPath()))
+	c.JSON(http.StatusForbidden, gin.H{"error": "Your role does not allow this action"})
+	c.Abort()
+	return false
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/maxzhirnov/formease/internal/models"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+)
+
+// fakeResolver maps user and workspace IDs to roles. Users are the owners
+// of the personal workspace with their own ID.
+type fakeResolver map[[2]string]string
+
+func (f fakeResolver) ResolveRole(ctx context.Context, userID, workspaceID string) (string, error) {
+	if userID == workspaceID {
+		return models.RoleOwner, nil
+	}
+	if workspaceID == "broken" {
+		return "", errors.New("connection refused")
+	}
+	role, ok := f[[2]string{userID, workspaceID}]
+	if !ok {
+		return "", apperrors.NewForbiddenError("you are not a member of this workspace")
+	}
+	return role, nil
+}
+
+func TestWorkspaceMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resolver := fakeResolver{
+		{"editor", "team"}:  models.RoleEditor,
+		{"analyst", "team"}: models.RoleAnalyst,
+		{"viewer", "team"}:  models.RoleViewer,
+	}
+
+	router := gin.New()
+	authenticated := router.Group("/", func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set("userID", userID)
+		}
+	})
+	workspace := authenticated.Group("/", WorkspaceMiddleware(resolver))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, c.GetString("workspaceID")) }
+	workspace.GET("/forms", ok)
+	workspace.POST("/forms", ok)
+	workspace.GET("/forms/:id/submissions", RequirePermission(models.PermissionViewResponses), ok)
+	workspace.GET("/forms/:id/export", RequirePermission(models.PermissionEditForms), ok)
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/forms"},
+		{http.MethodPost, "/forms"},
+		{http.MethodGet, "/forms/1/submissions"},
+		{http.MethodGet, "/forms/1/export"},
+	}
+
+	tests := []struct {
+		name      string
+		user      string
+		workspace string
+		// want holds the status of each of requests
+		want [4]int
+	}{
+		{"owner of the personal workspace", "ann", "", [4]int{200, 200, 200, 200}},
+		{"personal workspace selected explicitly", "ann", "ann", [4]int{200, 200, 200, 200}},
+		{"editor", "editor", "team", [4]int{200, 200, 200, 200}},
+		{"analyst", "analyst", "team", [4]int{200, 403, 200, 403}},
+		{"viewer", "viewer", "team", [4]int{200, 403, 403, 403}},
+		{"personal workspace of another user", "ann", "bob", [4]int{403, 403, 403, 403}},
+		{"not a member", "ann", "team", [4]int{403, 403, 403, 403}},
+		{"resolver failure", "ann", "broken", [4]int{500, 500, 500, 500}},
+		{"not authenticated", "", "team", [4]int{401, 401, 401, 401}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, r := range requests {
+				req := httptest.NewRequest(r.method, r.path, nil)
+				if tt.user != "" {
+					req.Header.Set("X-Test-User", tt.user)
+				}
+				if tt.workspace != "" {
+					req.Header.Set(WorkspaceHeader, tt.workspace)
+				}
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+
+				if rec.Code != tt.want[i] {
+					t.Errorf("%s %s = %d, want %d (%s)", r.method, r.path, rec.Code, tt.want[i], rec.Body.String())
+					continue
+				}
+				if rec.Code != http.StatusOK {
+					continue
+				}
+				wantWorkspace := tt.workspace
+				if wantWorkspace == "" {
+					wantWorkspace = tt.user
+				}
+				if rec.Body.String() != wantWorkspace {
+					t.Errorf("%s %s workspaceID = %q, want %q", r.method, r.path, rec.Body.String(), wantWorkspace)
+				}
+			}
+		})
+	}
+}
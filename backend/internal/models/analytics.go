@@ -0,0 +1,38 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type FormAnalytics struct {
+	FormID         primitive.ObjectID  `json:"formId"`
+	TotalResponses int64               `json:"totalResponses"`
+	Questions      []QuestionAnalytics `json:"questions"`
+}
+
+type QuestionAnalytics struct {
+	QuestionID int    `json:"questionId"`
+	Type       string `json:"type"`
+	Question   string `json:"question"`
+	Responses  int64  `json:"responses"`
+	// Choice question statistics
+	Options []OptionStats `json:"options,omitempty"`
+	// Rating question statistics
+	Rating *RatingStats `json:"rating,omitempty"`
+}
+
+type OptionStats struct {
+	OptionID   int     `json:"optionId"`
+	Text       string  `json:"text"`
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+type RatingStats struct {
+	Mean         float64        `json:"mean"`
+	Median       float64        `json:"median"`
+	Distribution []RatingBucket `json:"distribution"`
+}
+
+type RatingBucket struct {
+	Value float64 `json:"value"`
+	Count int64   `json:"count"`
+}
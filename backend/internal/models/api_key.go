@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// APIKeyScopeRead allows requests that do not change data
+	APIKeyScopeRead = "read"
+	// APIKeyScopeWrite allows all requests
+	APIKeyScopeWrite = "write"
+)
+
+// APIKey authenticates scripts as the user that created it. Only a hash of
+// the key is stored, Prefix helps users tell their keys apart.
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"userId" json:"userId"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	KeyHash    string             `bson:"keyHash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Allows reports whether the key may make requests that read, or with
+// write set, change data. The write scope includes reading.
+func (k *APIKey) Allows(write bool) bool {
+	for _, scope := range k.Scopes {
+		if scope == APIKeyScopeWrite || (!write && scope == APIKeyScopeRead) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestAPIKeyAllows(t *testing.T) {
+	tests := []struct {
+		name      string
+		scopes    []string
+		wantRead  bool
+		wantWrite bool
+	}{
+		{"read", []string{APIKeyScopeRead}, true, false},
+		{"write", []string{APIKeyScopeWrite}, true, true},
+		{"both", []string{APIKeyScopeRead, APIKeyScopeWrite}, true, true},
+		{"none", nil, false, false},
+		{"unknown", []string{"admin"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &APIKey{Scopes: tt.scopes}
+			if got := key.Allows(false); got != tt.wantRead {
+				t.Errorf("Allows(false) = %v, want %v", got, tt.wantRead)
+			}
+			if got := key.Allows(true); got != tt.wantWrite {
+				t.Errorf("Allows(true) = %v, want %v", got, tt.wantWrite)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	BundleFormat = "formease-form"
+	// BundleVersion is increased whenever the bundle layout changes
+	BundleVersion = 1
+)
+
+// FormBundle is a form exported together with the library images it uses.
+// In zip bundles it is stored as form.json and image data is kept in
+// separate files, in JSON bundles image data is embedded.
+type FormBundle struct {
+	Format     string        `json:"format"`
+	Version    int           `json:"version"`
+	ExportedAt time.Time     `json:"exportedAt"`
+	Form       FormSnapshot  `json:"form"`
+	Images     []BundleImage `json:"images"`
+}
+
+// BundleImage is a library image referenced by the form under URL.
+type BundleImage struct {
+	URL         string `json:"url"`
+	Filename    string `json:"filename"`
+	Type        string `json:"type"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Path of the image file inside a zip bundle
+	Path string `json:"path,omitempty"`
+	// Image content of JSON bundles
+	Data []byte `json:"data,omitempty"`
+}
+
+const (
+	ImportConflictName       = "name"
+	ImportConflictQuestionID = "question_id"
+	ImportConflictReference  = "reference"
+	ImportConflictImage      = "image"
+)
+
+// ImportConflict describes something that could not be imported as is and
+// what was done about it.
+type ImportConflict struct {
+	Type       string `json:"type"`
+	QuestionID int    `json:"questionId,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Message    string `json:"message"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Folder groups forms of a workspace. Folders without a parent are at the top level.
+type Folder struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID  `bson:"workspaceId" json:"workspaceId"`
+	ParentID    *primitive.ObjectID `bson:"parentId,omitempty" json:"parentId,omitempty"`
+	Name        string              `bson:"name" json:"name"`
+	CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
@@ -1,18 +1,92 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Form is owned by the workspace in WorkspaceID. For personal workspaces that
+// is the ID of the user.
 type Form struct {
 	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+	WorkspaceID     primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
 	IsDraft         bool               `bson:"isDraft" json:"isDraft"`
 	Name            string             `bson:"name" json:"name"`
 	Theme           string             `bson:"theme" json:"theme"`
 	FloatingShapes  string             `bson:"floatingShapesTheme" json:"floatingShapesTheme"`
 	Questions       []Question         `bson:"questions" json:"questions"`
 	ThankYouMessage ThankYouMessage    `bson:"thankYouMessage" json:"thankYouMessage"`
+	// Number of the revision respondents currently see, 0 if never published
+	Revision int `bson:"revision,omitempty" json:"revision,omitempty"`
+	// Incremented on every write, used for optimistic concurrency control
+	Version int `bson:"version" json:"version"`
+	// Scheduling, all optional
+	OpensAt       *time.Time `bson:"opensAt,omitempty" json:"opensAt,omitempty"`
+	ClosesAt      *time.Time `bson:"closesAt,omitempty" json:"closesAt,omitempty"`
+	MaxResponses  int64      `bson:"maxResponses,omitempty" json:"maxResponses,omitempty"`
+	ClosedMessage string     `bson:"closedMessage,omitempty" json:"closedMessage,omitempty"`
+	// Maintained by the server on every submission
+	ResponseCount int64 `bson:"responseCount" json:"responseCount"`
+	// Shown in the template catalog of the owner
+	IsTemplate bool `bson:"isTemplate,omitempty" json:"isTemplate,omitempty"`
+	// Organization, the folder is changed by moving the form
+	FolderID *primitive.ObjectID `bson:"folderId,omitempty" json:"folderId,omitempty"`
+	Tags     []string            `bson:"tags,omitempty" json:"tags,omitempty"`
+	// Set while the form is in the trash
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	// Set once the trash purger started removing the form, it can no
+	// longer be restored
+	Purging   bool      `bson:"purging,omitempty" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+type FormPage struct {
+	Forms      []Form `json:"forms"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+const (
+	FormClosedReasonNotOpen       = "not_open_yet"
+	FormClosedReasonClosed        = "closed"
+	FormClosedReasonResponseLimit = "response_limit_reached"
+)
+
+// FormClosed tells respondents why a form does not accept responses.
+type FormClosed struct {
+	Reason   string     `json:"reason"`
+	Message  string     `json:"message,omitempty"`
+	OpensAt  *time.Time `json:"opensAt,omitempty"`
+	ClosesAt *time.Time `json:"closesAt,omitempty"`
+}
+
+// PublicForm is the part of a form shown to respondents. It leaves out
+// the owner and the draft flag.
+type PublicForm struct {
+	ID              primitive.ObjectID `json:"id"`
+	Name            string             `json:"name"`
+	Theme           string             `json:"theme"`
+	FloatingShapes  string             `json:"floatingShapesTheme"`
+	Questions       []Question         `json:"questions"`
+	ThankYouMessage ThankYouMessage    `json:"thankYouMessage"`
+}
+
+func NewPublicForm(form *Form) *PublicForm {
+	return &PublicForm{
+		ID:              form.ID,
+		Name:            form.Name,
+		Theme:           form.Theme,
+		FloatingShapes:  form.FloatingShapes,
+		Questions:       form.Questions,
+		ThankYouMessage: form.ThankYouMessage,
+	}
 }
 
+// Question is a step of a form. Every question on the path a respondent
+// takes is required: submissions that skip one are rejected, unless
+// Optional is set, which the editor exposes as a checkbox and the form
+// player as a Skip button.
 type Question struct {
 	ID       int    `bson:"id" json:"id"`
 	Type     string `bson:"type" json:"type"`
@@ -35,6 +109,7 @@ type Question struct {
 	MaxLabel   string  `bson:"maxLabel,omitempty" json:"maxLabel,omitempty"`
 	Icon       string  `bson:"icon,omitempty" json:"icon,omitempty"`
 	// Common fields
+	Optional     bool         `bson:"optional,omitempty" json:"optional,omitempty"`
 	NextQuestion NextQuestion `bson:"nextQuestion" json:"nextQuestion"`
 }
 
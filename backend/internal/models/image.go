@@ -8,7 +8,7 @@ import (
 
 type Image struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID      string             `bson:"userId" json:"userId"`
+	WorkspaceID string             `bson:"workspaceId" json:"workspaceId"`
 	URL         string             `bson:"url" json:"url"`
 	Filename    string             `bson:"filename" json:"filename"`
 	Size        int64              `bson:"size" json:"size"`
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	NotificationModeOff       = "off"
+	NotificationModeImmediate = "immediate"
+	NotificationModeDigest    = "digest"
+)
+
+// NotificationSettings controls the emails the owner of a form receives
+// about new submissions.
+type NotificationSettings struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	FormID      primitive.ObjectID `bson:"formId" json:"formId"`
+	WorkspaceID primitive.ObjectID `bson:"workspaceId" json:"-"`
+	Mode        string             `bson:"mode" json:"mode"`
+	// End of the period covered by the last digest
+	DigestSentAt *time.Time `bson:"digestSentAt,omitempty" json:"digestSentAt,omitempty"`
+	UpdatedAt    time.Time  `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ProgressEventView     = "view"
+	ProgressEventAnswer   = "answer"
+	ProgressEventComplete = "complete"
+)
+
+// ProgressEvent records a step a respondent made while filling in a form.
+// Events of one respondent are grouped by SessionID.
+type ProgressEvent struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FormID     primitive.ObjectID `bson:"formId" json:"formId"`
+	SessionID  string             `bson:"sessionId" json:"sessionId"`
+	QuestionID int                `bson:"questionId,omitempty" json:"questionId,omitempty"`
+	Type       string             `bson:"type" json:"type"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type FunnelReport struct {
+	FormID         primitive.ObjectID `json:"formId"`
+	Views          int64              `json:"views"`
+	Starts         int64              `json:"starts"`
+	Completions    int64              `json:"completions"`
+	CompletionRate float64            `json:"completionRate"`
+	Questions      []FunnelStep       `json:"questions"`
+}
+
+type FunnelStep struct {
+	QuestionID  int     `json:"questionId"`
+	Question    string  `json:"question"`
+	Views       int64   `json:"views"`
+	Answers     int64   `json:"answers"`
+	DropOffs    int64   `json:"dropOffs"`
+	DropOffRate float64 `json:"dropOffRate"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FormRevision is an immutable snapshot of a form taken when it was published.
+type FormRevision struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FormID      primitive.ObjectID `bson:"formId" json:"formId"`
+	WorkspaceID primitive.ObjectID `bson:"workspaceId" json:"-"`
+	Number      int                `bson:"number" json:"number"`
+	Snapshot    FormSnapshot       `bson:"snapshot" json:"snapshot"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// FormSnapshot is the content of a form that respondents see.
+type FormSnapshot struct {
+	Name            string          `bson:"name" json:"name"`
+	Theme           string          `bson:"theme" json:"theme"`
+	FloatingShapes  string          `bson:"floatingShapesTheme" json:"floatingShapesTheme"`
+	Questions       []Question      `bson:"questions" json:"questions"`
+	ThankYouMessage ThankYouMessage `bson:"thankYouMessage" json:"thankYouMessage"`
+}
+
+func NewFormSnapshot(form *Form) FormSnapshot {
+	return FormSnapshot{
+		Name:            form.Name,
+		Theme:           form.Theme,
+		FloatingShapes:  form.FloatingShapes,
+		Questions:       form.Questions,
+		ThankYouMessage: form.ThankYouMessage,
+	}
+}
+
+// Apply replaces the content of form with the snapshot.
+func (s FormSnapshot) Apply(form *Form) {
+	form.Name = s.Name
+	form.Theme = s.Theme
+	form.FloatingShapes = s.FloatingShapes
+	form.Questions = s.Questions
+	form.ThankYouMessage = s.ThankYouMessage
+}
+
+// RevisionDiff lists what changed between two revisions of a form.
+type RevisionDiff struct {
+	From             int              `json:"from"`
+	To               int              `json:"to"`
+	Fields           []string         `json:"fields"`
+	AddedQuestions   []Question       `json:"addedQuestions"`
+	RemovedQuestions []Question       `json:"removedQuestions"`
+	ChangedQuestions []QuestionChange `json:"changedQuestions"`
+}
+
+type QuestionChange struct {
+	QuestionID int      `json:"questionId"`
+	Fields     []string `json:"fields"`
+	Before     Question `json:"before"`
+	After      Question `json:"after"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a sign-in on one device. Its refresh token is replaced on
+// every refresh; the tokens it replaced are remembered, so a stolen token
+// that is used again is recognized and the whole session is revoked.
+// AccessTokenID is the latest access token, it is denied on revocation.
+type Session struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          primitive.ObjectID `bson:"userId" json:"userId"`
+	TokenHash       string             `bson:"tokenHash" json:"-"`
+	UsedTokenHashes []string           `bson:"usedTokenHashes,omitempty" json:"-"`
+	AccessTokenID   string             `bson:"accessTokenId" json:"-"`
+	AccessExpiresAt time.Time          `bson:"accessExpiresAt" json:"-"`
+	UserAgent       string             `bson:"userAgent" json:"userAgent"`
+	IP              string             `bson:"ip" json:"ip"`
+	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUsedAt      time.Time          `bson:"lastUsedAt" json:"lastUsedAt"`
+	ExpiresAt       time.Time          `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt       *time.Time         `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	// Current marks the session of the request that listed the sessions
+	Current bool `bson:"-" json:"current"`
+}
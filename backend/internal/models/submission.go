@@ -7,8 +7,28 @@ import (
 )
 
 type Submission struct {
-	ID        primitive.ObjectID `json:"id"`
-	Answers   string             `json:"answers"`
-	CreatedAt time.Time          `json:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at"`
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FormID    primitive.ObjectID `bson:"formId" json:"formId"`
+	SessionID string             `bson:"sessionId,omitempty" json:"sessionId,omitempty"`
+	Revision  int                `bson:"revision,omitempty" json:"revision,omitempty"`
+	Answers   []Answer           `bson:"answers" json:"answers"`
+	CreatedAt time.Time          `bson:"createdAt" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updated_at"`
+}
+
+// Answer holds the response to a single question, keyed by Question.ID.
+// Only the field matching the question type is expected to be set.
+type Answer struct {
+	QuestionID int `bson:"questionId" json:"questionId"`
+	// Input question answer
+	Text string `bson:"text,omitempty" json:"text,omitempty"`
+	// Choice question answer
+	OptionIDs []int `bson:"optionIds,omitempty" json:"optionIds,omitempty"`
+	// Rating question answer
+	Rating *float64 `bson:"rating,omitempty" json:"rating,omitempty"`
+}
+
+type SubmissionPage struct {
+	Submissions []Submission `json:"submissions"`
+	NextCursor  string       `json:"nextCursor,omitempty"`
 }
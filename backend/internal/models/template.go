@@ -0,0 +1,9 @@
+package models
+
+// FormTemplate is a starting point for new forms. Built-in templates are
+// loaded from JSON files, the others are forms their owner marked as templates.
+type FormTemplate struct {
+	ID      string       `json:"id"`
+	Builtin bool         `json:"builtin"`
+	Form    FormSnapshot `json:"form"`
+}
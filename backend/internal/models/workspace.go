@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Workspace owns forms, folders and images. Every user also has a personal
+// workspace that is not stored: its ID is the ID of the user, so content
+// created before workspaces existed stays with its author.
+type Workspace struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	OwnerID   primitive.ObjectID `bson:"ownerId" json:"ownerId"`
+	Personal  bool               `bson:"-" json:"personal"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// WorkspaceMembership is a workspace together with the role of the
+// current user in it.
+type WorkspaceMembership struct {
+	Workspace
+	Role string `json:"role"`
+}
+
+const (
+	RoleOwner   = "owner"
+	RoleEditor  = "editor"
+	RoleViewer  = "viewer"
+	RoleAnalyst = "analyst"
+)
+
+const (
+	PermissionViewForms       = "forms:view"
+	PermissionEditForms       = "forms:edit"
+	PermissionViewResponses   = "responses:view"
+	PermissionManageWorkspace = "workspace:manage"
+)
+
+var rolePermissions = map[string]map[string]bool{
+	RoleOwner: {
+		PermissionViewForms:       true,
+		PermissionEditForms:       true,
+		PermissionViewResponses:   true,
+		PermissionManageWorkspace: true,
+	},
+	RoleEditor: {
+		PermissionViewForms:     true,
+		PermissionEditForms:     true,
+		PermissionViewResponses: true,
+	},
+	RoleAnalyst: {
+		PermissionViewForms:     true,
+		PermissionViewResponses: true,
+	},
+	RoleViewer: {
+		PermissionViewForms: true,
+	},
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleAllows reports whether members with role have permission.
+func RoleAllows(role, permission string) bool {
+	return rolePermissions[role][permission]
+}
+
+type WorkspaceMember struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	Email       string             `bson:"-" json:"email,omitempty"`
+	Role        string             `bson:"role" json:"role"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// WorkspaceInvitation lets the owner of Email join a workspace. Only a hash
+// of the token is stored, the token itself is sent by email.
+type WorkspaceInvitation struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
+	Email       string             `bson:"email" json:"email"`
+	Role        string             `bson:"role" json:"role"`
+	TokenHash   string             `bson:"tokenHash" json:"-"`
+	InvitedBy   primitive.ObjectID `bson:"invitedBy" json:"invitedBy"`
+	ExpiresAt   time.Time          `bson:"expiresAt" json:"expiresAt"`
+	AcceptedAt  *time.Time         `bson:"acceptedAt,omitempty" json:"acceptedAt,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
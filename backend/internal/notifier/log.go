@@ -0,0 +1,25 @@
+package notifier
+
+import (
+	"context"
+	"strings"
+
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// LogSender writes messages to the log instead of sending them. It is meant
+// for local development, where links in emails can be copied from the log.
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	logger.Info("Email",
+		zap.String("to", strings.Join(msg.To, ", ")),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body))
+	return nil
+}
@@ -0,0 +1,212 @@
+package notifier
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSMTPSenderSend(t *testing.T) {
+	server := startFakeSMTPServer(t, "")
+	sender := newTestSMTPSender(t, server)
+
+	body := "Your form \"Опрос\" received a new response.\n" + strings.Repeat("long line ", 12) + "\n"
+	err := sender.Send(context.Background(), Message{
+		To:      []string{"ann@example.com", "Bob <bob@example.com>"},
+		Subject: "New response to «Опрос»",
+		Body:    body,
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	session := server.session(t)
+	if session.from != "noreply@formease.test" {
+		t.Errorf("MAIL FROM = %q, want noreply@formease.test", session.from)
+	}
+	if want := []string{"ann@example.com", "bob@example.com"}; !reflect.DeepEqual(session.rcpt, want) {
+		t.Errorf("RCPT TO = %q, want %q", session.rcpt, want)
+	}
+	if !session.quit {
+		t.Error("session was not closed with QUIT")
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(session.data))
+	if err != nil {
+		t.Fatalf("message is not a valid email: %v", err)
+	}
+	headers := map[string]string{
+		"From":                      `"FormEase" <noreply@formease.test>`,
+		"To":                        `<ann@example.com>, "Bob" <bob@example.com>`,
+		"Mime-Version":              "1.0",
+		"Content-Type":              "text/plain; charset=utf-8",
+		"Content-Transfer-Encoding": "quoted-printable",
+	}
+	for key, want := range headers {
+		if got := msg.Header.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "New response to «Опрос»" {
+		t.Errorf("Subject = %q (%v), want the original subject", subject, err)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date header is invalid: %v", err)
+	}
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("body is not quoted-printable: %v", err)
+	}
+	if got := strings.ReplaceAll(string(decoded), "\r\n", "\n"); got != body {
+		t.Errorf("body = %q, want %q", got, body)
+	}
+}
+
+func TestSMTPSenderSendErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		to     []string
+		reject string
+	}{
+		{name: "no recipients"},
+		{name: "invalid recipient", to: []string{"not an address"}},
+		{name: "rejected recipient", to: []string{"ann@example.com", "gone@example.com"}, reject: "gone@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := startFakeSMTPServer(t, tt.reject)
+			sender := newTestSMTPSender(t, server)
+
+			if err := sender.Send(context.Background(), Message{To: tt.to, Subject: "Hi", Body: "Hi"}); err == nil {
+				t.Fatal("Send() error = nil, want an error")
+			}
+			if tt.reject == "" {
+				return
+			}
+			if session := server.session(t); session.data != "" {
+				t.Error("message was sent despite the rejected recipient")
+			}
+		})
+	}
+}
+
+func newTestSMTPSender(t *testing.T, server *fakeSMTPServer) *SMTPSender {
+	t.Helper()
+	host, port, err := net.SplitHostPort(server.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, _ := strconv.Atoi(port)
+	sender, err := NewSMTPSender(SMTPConfig{Host: host, Port: portNumber, From: "FormEase <noreply@formease.test>"})
+	if err != nil {
+		t.Fatalf("NewSMTPSender() error = %v", err)
+	}
+	return sender
+}
+
+// fakeSMTPServer accepts a single plain SMTP session and records it.
+// Recipients equal to reject are refused.
+type fakeSMTPServer struct {
+	listener net.Listener
+	reject   string
+	sessions chan fakeSMTPSession
+}
+
+type fakeSMTPSession struct {
+	from string
+	rcpt []string
+	data string
+	quit bool
+}
+
+func startFakeSMTPServer(t *testing.T, reject string) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTPServer{listener: listener, reject: reject, sessions: make(chan fakeSMTPSession, 1)}
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTPServer) serve() {
+	var session fakeSMTPSession
+	defer func() { s.sessions <- session }()
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	text := textproto.NewConn(conn)
+	reply := func(line string) { text.PrintfLine("%s", line) }
+
+	reply("220 localhost fake smtp")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			session.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			reply("250 OK")
+		case "RCPT":
+			rcpt := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if rcpt == s.reject {
+				reply("550 No such user")
+				continue
+			}
+			session.rcpt = append(session.rcpt, rcpt)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			session.data = string(data)
+			reply("250 OK")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			session.quit = true
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// session waits for the client to end the session.
+func (s *fakeSMTPServer) session(t *testing.T) fakeSMTPSession {
+	t.Helper()
+	select {
+	case session := <-s.sessions:
+		return session
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp session did not end")
+		return fakeSMTPSession{}
+	}
+}
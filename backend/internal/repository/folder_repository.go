@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrFolderNotFound = errors.New("folder not found")
+
+type FolderRepository struct {
+	collection *mongo.Collection
+}
+
+func NewFolderRepository(db *mongo.Database) *FolderRepository {
+	return &FolderRepository{
+		collection: db.Collection("folders"),
+	}
+}
+
+func (r *FolderRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "workspaceId", Value: 1}, {Key: "parentId", Value: 1}},
+	})
+	return err
+}
+
+func (r *FolderRepository) CreateFolder(ctx context.Context, folder *models.Folder) error {
+	result, err := r.collection.InsertOne(ctx, folder)
+	if err != nil {
+		return err
+	}
+	folder.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ListFolders returns all folders of a workspace sorted by name. The client
+// builds the tree from the parent IDs.
+func (r *FolderRepository) ListFolders(ctx context.Context, workspaceID primitive.ObjectID) ([]models.Folder, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"workspaceId": workspaceID}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find folders: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	folders := []models.Folder{}
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, fmt.Errorf("failed to decode folders: %w", err)
+	}
+	return folders, nil
+}
+
+func (r *FolderRepository) FindFolder(ctx context.Context, id, workspaceID primitive.ObjectID) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "workspaceId": workspaceID}).Decode(&folder)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrFolderNotFound
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// UpdateFolder saves the name and parent of a folder.
+func (r *FolderRepository) UpdateFolder(ctx context.Context, folder *models.Folder) error {
+	set := bson.M{"name": folder.Name, "updatedAt": folder.UpdatedAt}
+	update := bson.M{"$set": set}
+	if folder.ParentID != nil {
+		set["parentId"] = folder.ParentID
+	} else {
+		update["$unset"] = bson.M{"parentId": ""}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": folder.ID, "workspaceId": folder.WorkspaceID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrFolderNotFound
+	}
+	return nil
+}
+
+// MoveChildren gives the subfolders of a folder a new parent, nil moves
+// them to the top level.
+func (r *FolderRepository) MoveChildren(ctx context.Context, workspaceID, folderID primitive.ObjectID, parentID *primitive.ObjectID) error {
+	set := bson.M{"updatedAt": time.Now()}
+	update := bson.M{"$set": set}
+	if parentID != nil {
+		set["parentId"] = parentID
+	} else {
+		update["$unset"] = bson.M{"parentId": ""}
+	}
+	_, err := r.collection.UpdateMany(ctx, bson.M{"workspaceId": workspaceID, "parentId": folderID}, update)
+	return err
+}
+
+func (r *FolderRepository) DeleteFolder(ctx context.Context, id, workspaceID primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "workspaceId": workspaceID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrFolderNotFound
+	}
+	return nil
+}
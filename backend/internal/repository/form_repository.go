@@ -4,14 +4,49 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"time"
 
 	"github.com/maxzhirnov/formease/internal/models"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// optionalFormFields are omitted from the stored document when empty.
+var optionalFormFields = []string{"opensAt", "closesAt", "maxResponses", "closedMessage", "isTemplate", "tags"}
+
+var (
+	ErrFormNotFound        = errors.New("form not found")
+	ErrFormVersionConflict = errors.New("form was modified by someone else")
+)
+
+// FormCursor points at the last form of a page. Value is the sort field
+// of that form.
+type FormCursor struct {
+	Value interface{}
+	ID    primitive.ObjectID
+}
+
+// FormQuery describes a page of the forms of a workspace.
+type FormQuery struct {
+	WorkspaceID primitive.ObjectID
+	Search      string
+	IsDraft     *bool
+	Theme       string
+	// Forms directly in this folder, or outside of any folder with InRoot
+	FolderID *primitive.ObjectID
+	InRoot   bool
+	Tag      string
+	// Stored field to sort by: createdAt, updatedAt or responseCount
+	SortField string
+	Ascending bool
+	After     *FormCursor
+	Limit     int
+}
+
 type FormRepository struct {
 	collection *mongo.Collection
 }
@@ -22,6 +57,58 @@ func NewFormRepository(db *mongo.Database) *FormRepository {
 	}
 }
 
+func (r *FormRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.M{"deletedAt": 1},
+			Options: options.Index().SetSparse(true),
+		},
+		{Keys: bson.D{{Key: "workspaceId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "workspaceId", Value: 1}, {Key: "updatedAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "workspaceId", Value: 1}, {Key: "responseCount", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "workspaceId", Value: 1}, {Key: "folderId", Value: 1}}},
+		{Keys: bson.D{{Key: "workspaceId", Value: 1}, {Key: "tags", Value: 1}}},
+	})
+	return err
+}
+
+// BackfillListFields fills in the fields the form list sorts by for forms
+// stored before they existed. Timestamps are taken from the ID, response
+// counts from the submissions.
+func (r *FormRepository) BackfillListFields(ctx context.Context) error {
+	createdAt := bson.M{"$toDate": "$_id"}
+	if _, err := r.collection.UpdateMany(ctx,
+		bson.M{"createdAt": bson.M{"$exists": false}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"createdAt": createdAt,
+			"updatedAt": bson.M{"$ifNull": bson.A{"$updatedAt", createdAt}},
+		}}}},
+	); err != nil {
+		return fmt.Errorf("failed to backfill form timestamps: %w", err)
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"responseCount": bson.M{"$exists": false}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "submissions",
+			"let":  bson.M{"formId": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$formId", "$$formId"}}}},
+				bson.M{"$count": "count"},
+			},
+			"as": "submissions",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"responseCount": bson.M{"$ifNull": bson.A{bson.M{"$first": "$submissions.count"}, 0}},
+		}}},
+		{{Key: "$merge", Value: bson.M{"into": "forms", "on": "_id", "whenMatched": "merge", "whenNotMatched": "discard"}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to backfill response counts: %w", err)
+	}
+	return cursor.Close(ctx)
+}
+
 func (r *FormRepository) CreateForm(form *models.Form) error {
 	ctx := context.Background()
 	result, err := r.collection.InsertOne(ctx, form)
@@ -40,27 +127,28 @@ func (r *FormRepository) GetForm(id string) (*models.Form, error) {
 	}
 
 	var form models.Form
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&form)
+	// Forms in the trash are only reachable through the trash methods
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID, "deletedAt": nil}).Decode(&form)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("form not found")
+			return nil, ErrFormNotFound
 		}
 		return nil, err
 	}
 	return &form, nil
 }
 
-func (r *FormRepository) ListForms(userID string) ([]models.Form, error) {
+func (r *FormRepository) ListForms(workspaceID string) ([]models.Form, error) {
 	ctx := context.Background()
 
 	// Convert string ID to ObjectID
-	objectID, err := primitive.ObjectIDFromHex(userID)
+	objectID, err := primitive.ObjectIDFromHex(workspaceID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid user ID format: %v", err)
+		return nil, fmt.Errorf("invalid workspace ID format: %v", err)
 	}
 
-	// Add filter for userID
-	filter := bson.M{"userId": objectID}
+	// Add filter for workspaceID
+	filter := bson.M{"workspaceId": objectID, "deletedAt": nil}
 
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
@@ -75,49 +163,362 @@ func (r *FormRepository) ListForms(userID string) ([]models.Form, error) {
 	return forms, nil
 }
 
+// FindForms returns a page of the forms of a workspace that are not in the trash.
+func (r *FormRepository) FindForms(ctx context.Context, q FormQuery) ([]models.Form, error) {
+	direction := -1
+	if q.Ascending {
+		direction = 1
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: q.SortField, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(q.Limit))
+
+	cursor, err := r.collection.Find(ctx, formFilter(q), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find forms: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	forms := []models.Form{}
+	if err := cursor.All(ctx, &forms); err != nil {
+		return nil, fmt.Errorf("failed to decode forms: %w", err)
+	}
+	return forms, nil
+}
+
+func formFilter(q FormQuery) bson.M {
+	filter := bson.M{"workspaceId": q.WorkspaceID, "deletedAt": nil}
+	and := bson.A{}
+
+	if q.IsDraft != nil {
+		filter["isDraft"] = *q.IsDraft
+	}
+	if q.Theme != "" {
+		filter["theme"] = q.Theme
+	}
+	if q.FolderID != nil {
+		filter["folderId"] = *q.FolderID
+	} else if q.InRoot {
+		filter["folderId"] = nil
+	}
+	if q.Tag != "" {
+		filter["tags"] = q.Tag
+	}
+
+	if q.Search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(q.Search), Options: "i"}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"name": pattern},
+			bson.M{"questions.question": pattern},
+		}})
+	}
+
+	// Keyset pagination on (sort field, _id)
+	if q.After != nil {
+		op := "$lt"
+		if q.Ascending {
+			op = "$gt"
+		}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{q.SortField: bson.M{op: q.After.Value}},
+			bson.M{q.SortField: q.After.Value, "_id": bson.M{op: q.After.ID}},
+		}})
+	}
+
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+	return filter
+}
+
+// ListTags returns the distinct tags used on the forms of a workspace.
+func (r *FormRepository) ListTags(ctx context.Context, workspaceID primitive.ObjectID) ([]string, error) {
+	values, err := r.collection.Distinct(ctx, "tags", bson.M{"workspaceId": workspaceID, "deletedAt": nil})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, 0, len(values))
+	for _, v := range values {
+		if tag, ok := v.(string); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// SetFolder moves a form of workspaceID into a folder, nil moves it out of any folder.
+func (r *FormRepository) SetFolder(ctx context.Context, formID, workspaceID primitive.ObjectID, folderID *primitive.ObjectID) error {
+	// Not an edit of the form, so neither the version nor updatedAt change
+	update := bson.M{"$set": bson.M{"folderId": folderID}}
+	if folderID == nil {
+		update = bson.M{"$unset": bson.M{"folderId": ""}}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": formID, "workspaceId": workspaceID, "deletedAt": nil}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrFormNotFound
+	}
+	return nil
+}
+
+// MoveFolderForms moves all forms of a folder, including the ones in the
+// trash, into another folder or out of any folder when to is nil.
+func (r *FormRepository) MoveFolderForms(ctx context.Context, workspaceID, from primitive.ObjectID, to *primitive.ObjectID) error {
+	update := bson.M{"$set": bson.M{"folderId": to}}
+	if to == nil {
+		update = bson.M{"$unset": bson.M{"folderId": ""}}
+	}
+	_, err := r.collection.UpdateMany(ctx, bson.M{"workspaceId": workspaceID, "folderId": from}, update)
+	return err
+}
+
+// ListTemplates returns the forms a user marked as templates.
+func (r *FormRepository) ListTemplates(ctx context.Context, workspaceID primitive.ObjectID) ([]models.Form, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"workspaceId": workspaceID, "isTemplate": true, "deletedAt": nil})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	forms := []models.Form{}
+	if err := cursor.All(ctx, &forms); err != nil {
+		return nil, err
+	}
+	return forms, nil
+}
+
+// UpdateForm saves a form. form.Version must hold the version the
+// changes are based on, it is incremented on success. The filter includes
+// the owner, so a form of another user is reported as not found instead
+// of being overwritten.
 func (r *FormRepository) UpdateForm(form *models.Form) error {
 	ctx := context.Background()
-	_, err := r.collection.ReplaceOne(
+	expected := form.Version
+	form.Version++
+	form.UpdatedAt = time.Now()
+
+	fields, err := editableFields(form)
+	if err != nil {
+		form.Version = expected
+		return err
+	}
+
+	update := bson.M{"$set": fields}
+	// Optional settings left out of the form are cleared
+	unset := bson.M{}
+	for _, key := range optionalFormFields {
+		if _, ok := fields[key]; !ok {
+			unset[key] = ""
+		}
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	result, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": form.ID},
-		form,
+		bson.M{"_id": form.ID, "workspaceId": form.WorkspaceID, "deletedAt": nil, "version": versionFilter(expected)},
+		update,
 	)
-	return err
+	if err != nil {
+		form.Version = expected
+		return err
+	}
+	if result.MatchedCount == 0 {
+		form.Version = expected
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": form.ID, "workspaceId": form.WorkspaceID, "deletedAt": nil})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrFormVersionConflict
+		}
+		return ErrFormNotFound
+	}
+	return nil
+}
+
+// editableFields returns the stored fields of a form except the ones that
+// are maintained by the server independently of edits.
+func editableFields(form *models.Form) (bson.M, error) {
+	data, err := bson.Marshal(form)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "_id")
+	delete(fields, "responseCount")
+	delete(fields, "deletedAt")
+	delete(fields, "createdAt")
+	delete(fields, "folderId")
+	return fields, nil
+}
+
+// versionFilter matches forms stored before versioning as version 0.
+func versionFilter(version int) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
 }
 
-func (r *FormRepository) DeleteForm(id string) error {
+// DeleteForm moves a form to the trash. It stays there until it is
+// restored or purged.
+func (r *FormRepository) DeleteForm(formID primitive.ObjectID, workspaceID primitive.ObjectID) error {
 	ctx := context.Background()
-	objectID, err := primitive.ObjectIDFromHex(id)
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": formID, "workspaceId": workspaceID, "deletedAt": nil},
+		bson.M{
+			"$set": bson.M{"deletedAt": time.Now()},
+			"$inc": bson.M{"version": 1},
+		},
+	)
 	if err != nil {
 		return err
 	}
+	if result.MatchedCount == 0 {
+		return ErrFormNotFound
+	}
+	return nil
+}
+
+// ListDeletedForms returns the trash of a workspace, most recently deleted
+// first. Forms that are being purged are left out.
+func (r *FormRepository) ListDeletedForms(ctx context.Context, workspaceID primitive.ObjectID) ([]models.Form, error) {
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"workspaceId": workspaceID, "deletedAt": bson.M{"$ne": nil}, "purging": bson.M{"$ne": true}},
+		options.Find().SetSort(bson.M{"deletedAt": -1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	forms := []models.Form{}
+	if err := cursor.All(ctx, &forms); err != nil {
+		return nil, err
+	}
+	return forms, nil
+}
+
+// RestoreForm takes a form of workspaceID out of the trash. Forms that are being
+// purged can not be restored.
+func (r *FormRepository) RestoreForm(ctx context.Context, formID, workspaceID primitive.ObjectID) (*models.Form, error) {
+	var form models.Form
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": formID, "workspaceId": workspaceID, "deletedAt": bson.M{"$ne": nil}, "purging": bson.M{"$ne": true}},
+		bson.M{
+			"$unset": bson.M{"deletedAt": ""},
+			"$inc":   bson.M{"version": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&form)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrFormNotFound
+		}
+		return nil, err
+	}
+	return &form, nil
+}
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+// ListDeletedBefore returns up to limit forms that were moved to the trash
+// before the given time.
+func (r *FormRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int64) ([]models.Form, error) {
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"deletedAt": bson.M{"$lt": before}},
+		options.Find().SetSort(bson.M{"deletedAt": 1}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	forms := []models.Form{}
+	if err := cursor.All(ctx, &forms); err != nil {
+		return nil, err
+	}
+	return forms, nil
+}
+
+// MarkPurging flags a form that has been in the trash since before the
+// given time as being purged, so it can no longer be restored. It fails
+// with ErrFormNotFound if the form was restored in the meantime.
+func (r *FormRepository) MarkPurging(ctx context.Context, formID primitive.ObjectID, before time.Time) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": formID, "deletedAt": bson.M{"$lt": before}},
+		bson.M{"$set": bson.M{"purging": true}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrFormNotFound
+	}
+	return nil
+}
+
+// PurgeForm permanently removes a form marked by MarkPurging.
+func (r *FormRepository) PurgeForm(ctx context.Context, formID primitive.ObjectID, before time.Time) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": formID, "deletedAt": bson.M{"$lt": before}, "purging": true})
 	if err != nil {
 		return err
 	}
 	if result.DeletedCount == 0 {
-		return errors.New("form not found")
+		return ErrFormNotFound
 	}
 	return nil
 }
 
-func (r *FormRepository) ToggleDraftStatus(formID primitive.ObjectID, userID primitive.ObjectID) error {
+// ImageInUse reports whether a form of workspaceID other than formID, in the
+// trash or not, references the image URL.
+func (r *FormRepository) ImageInUse(ctx context.Context, workspaceID, formID primitive.ObjectID, url string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"workspaceId": workspaceID,
+		"_id":         bson.M{"$ne": formID},
+		"$or": bson.A{
+			bson.M{"questions.image": url},
+			bson.M{"questions.options.image": url},
+		},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *FormRepository) ToggleDraftStatus(formID primitive.ObjectID, workspaceID primitive.ObjectID) error {
 	filter := bson.M{
-		"_id":    formID,
-		"userId": userID,
+		"_id":         formID,
+		"workspaceId": workspaceID,
+		"deletedAt":   nil,
 	}
 
 	var currentForm models.Form
 	err := r.collection.FindOne(context.Background(), filter).Decode(&currentForm)
 	if err != nil {
-		return fmt.Errorf("form not found: %w", err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrFormNotFound
+		}
+		return fmt.Errorf("failed to find form: %w", err)
 	}
 
 	update := bson.M{
 		"$set": bson.M{
-			"isDraft": !currentForm.IsDraft,
+			"isDraft":   !currentForm.IsDraft,
+			"updatedAt": time.Now(),
 		},
+		"$inc": bson.M{"version": 1},
 	}
 
 	result, err := r.collection.UpdateOne(context.Background(), filter, update)
@@ -125,9 +526,59 @@ func (r *FormRepository) ToggleDraftStatus(formID primitive.ObjectID, userID pri
 		return fmt.Errorf("failed to update draft status: %w", err)
 	}
 
-	if result.ModifiedCount == 0 {
-		return fmt.Errorf("form not found or not modified")
+	if result.MatchedCount == 0 {
+		return ErrFormNotFound
 	}
 
 	return nil
 }
+
+// SetRevision records which revision of the form is live and increments
+// its version. version is the version the revision was taken from, if the
+// form was written since ErrFormVersionConflict is returned and nothing
+// changes.
+func (r *FormRepository) SetRevision(formID primitive.ObjectID, number, version int) error {
+	result, err := r.collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": formID, "deletedAt": nil, "version": versionFilter(version)},
+		bson.M{
+			"$set": bson.M{"revision": number},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrFormVersionConflict
+	}
+	return nil
+}
+
+// ReserveResponse counts a new response towards the limit of a form. It
+// returns false without changes if the form already reached maxResponses.
+func (r *FormRepository) ReserveResponse(ctx context.Context, formID primitive.ObjectID) (bool, error) {
+	filter := bson.M{
+		"_id":       formID,
+		"deletedAt": nil,
+		"$or": bson.A{
+			bson.M{"maxResponses": bson.M{"$exists": false}},
+			bson.M{"maxResponses": bson.M{"$lte": 0}},
+			bson.M{"$expr": bson.M{"$lt": bson.A{
+				bson.M{"$ifNull": bson.A{"$responseCount", 0}},
+				"$maxResponses",
+			}}},
+		},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"responseCount": 1}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// ReleaseResponse undoes ReserveResponse when the submission could not be stored.
+func (r *FormRepository) ReleaseResponse(ctx context.Context, formID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": formID}, bson.M{"$inc": bson.M{"responseCount": -1}})
+	return err
+}
@@ -15,10 +15,11 @@ import (
 
 type ImageRepository interface {
 	Create(image *models.Image) error
-	FindByUserID(userID string, page, limit int) ([]*models.Image, error)
+	FindByWorkspaceID(workspaceID string, page, limit int) ([]*models.Image, error)
 	FindByID(id string) (*models.Image, error)
+	FindByURLs(workspaceID string, urls []string) ([]*models.Image, error)
 	Delete(id string) error
-	CountByUserID(userID string) (int64, error)
+	CountByWorkspaceID(workspaceID string) (int64, error)
 }
 
 type MongoImageRepository struct {
@@ -44,13 +45,13 @@ func (r *MongoImageRepository) Create(image *models.Image) error {
 	return nil
 }
 
-func (r *MongoImageRepository) CountByUserID(userID string) (int64, error) {
+func (r *MongoImageRepository) CountByWorkspaceID(workspaceID string) (int64, error) {
 
 	collection := r.db.Collection("images")
 
 	count, err := collection.CountDocuments(
 		context.Background(),
-		bson.M{"userId": userID},
+		bson.M{"workspaceId": workspaceID},
 	)
 
 	if err != nil {
@@ -61,7 +62,7 @@ func (r *MongoImageRepository) CountByUserID(userID string) (int64, error) {
 	return count, nil
 }
 
-func (r *MongoImageRepository) FindByUserID(userID string, page, limit int) ([]*models.Image, error) {
+func (r *MongoImageRepository) FindByWorkspaceID(workspaceID string, page, limit int) ([]*models.Image, error) {
 	collection := r.db.Collection("images")
 
 	// Вычисляем skip для пагинации
@@ -75,7 +76,7 @@ func (r *MongoImageRepository) FindByUserID(userID string, page, limit int) ([]*
 
 	// Выполняем поиск
 	cursor, err := collection.Find(context.Background(),
-		bson.M{"userId": userID},
+		bson.M{"workspaceId": workspaceID},
 		options,
 	)
 	if err != nil {
@@ -123,6 +124,28 @@ func (r *MongoImageRepository) FindByID(id string) (*models.Image, error) {
 	return &image, nil
 }
 
+// FindByURLs returns the images of workspaceID with one of the given URLs.
+func (r *MongoImageRepository) FindByURLs(workspaceID string, urls []string) ([]*models.Image, error) {
+	collection := r.db.Collection("images")
+
+	cursor, err := collection.Find(context.Background(),
+		bson.M{"workspaceId": workspaceID, "url": bson.M{"$in": urls}},
+	)
+	if err != nil {
+		logger.Error("Failed to find images by URL", zap.Error(err))
+		return nil, fmt.Errorf("failed to find images: %w", err)
+	}
+	defer cursor.Close(context.Background())
+
+	var images []*models.Image
+	if err = cursor.All(context.Background(), &images); err != nil {
+		logger.Error("Failed to decode images", zap.Error(err))
+		return nil, fmt.Errorf("failed to decode images: %w", err)
+	}
+
+	return images, nil
+}
+
 func (r *MongoImageRepository) Delete(id string) error {
 	// Конвертируем id в ObjectID
 	objectID, err := primitive.ObjectIDFromHex(id)
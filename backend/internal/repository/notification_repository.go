@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NotificationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewNotificationRepository(db *mongo.Database) *NotificationRepository {
+	return &NotificationRepository{
+		collection: db.Collection("notification_settings"),
+	}
+}
+
+func (r *NotificationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.M{"formId": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "mode", Value: 1}, {Key: "digestSentAt", Value: 1}}},
+	})
+	return err
+}
+
+// GetSettings returns the settings of a form, or nil if none were saved.
+func (r *NotificationRepository) GetSettings(ctx context.Context, formID primitive.ObjectID) (*models.NotificationSettings, error) {
+	var settings models.NotificationSettings
+	err := r.collection.FindOne(ctx, bson.M{"formId": formID}).Decode(&settings)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *NotificationRepository) SaveSettings(ctx context.Context, settings *models.NotificationSettings) error {
+	set := bson.M{
+		"workspaceId": settings.WorkspaceID,
+		"mode":        settings.Mode,
+		"updatedAt":   settings.UpdatedAt,
+	}
+	update := bson.M{"$set": set}
+	if settings.DigestSentAt != nil {
+		set["digestSentAt"] = settings.DigestSentAt
+	} else {
+		update["$unset"] = bson.M{"digestSentAt": ""}
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"formId": settings.FormID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// ClaimDueDigest picks a form whose last digest is older than period and
+// moves its digestSentAt to now. The returned settings hold the previous
+// value, i.e. the start of the period the new digest has to cover.
+func (r *NotificationRepository) ClaimDueDigest(ctx context.Context, now time.Time, period time.Duration) (*models.NotificationSettings, error) {
+	filter := bson.M{
+		"mode":         models.NotificationModeDigest,
+		"digestSentAt": bson.M{"$lte": now.Add(-period)},
+	}
+	update := bson.M{"$set": bson.M{"digestSentAt": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var settings models.NotificationSettings
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&settings)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// DeleteSettings removes the settings of a form, if any.
+func (r *NotificationRepository) DeleteSettings(ctx context.Context, formID primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"formId": formID})
+	return err
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SessionCounts holds the number of distinct sessions per event type.
+type SessionCounts struct {
+	Sessions int64
+	ByType   map[string]int64
+	// Question ID -> event type -> sessions
+	ByQuestion map[int]map[string]int64
+}
+
+type ProgressRepository struct {
+	collection *mongo.Collection
+}
+
+func NewProgressRepository(db *mongo.Database) *ProgressRepository {
+	return &ProgressRepository{
+		collection: db.Collection("progress_events"),
+	}
+}
+
+func (r *ProgressRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "formId", Value: 1}, {Key: "sessionId", Value: 1}}},
+		{Keys: bson.D{{Key: "formId", Value: 1}, {Key: "type", Value: 1}, {Key: "questionId", Value: 1}}},
+	})
+	return err
+}
+
+func (r *ProgressRepository) CreateEvent(ctx context.Context, event *models.ProgressEvent) error {
+	result, err := r.collection.InsertOne(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	event.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// CountSessions counts distinct sessions of a form overall, per event type
+// and per question and event type in a single aggregation.
+func (r *ProgressRepository) CountSessions(ctx context.Context, formID primitive.ObjectID) (*SessionCounts, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"formId": formID}}},
+		{{Key: "$facet", Value: bson.M{
+			"sessions": bson.A{
+				bson.M{"$group": bson.M{"_id": "$sessionId"}},
+				bson.M{"$count": "count"},
+			},
+			"byType": bson.A{
+				bson.M{"$group": bson.M{"_id": bson.M{"type": "$type", "sessionId": "$sessionId"}}},
+				bson.M{"$group": bson.M{"_id": "$_id.type", "count": bson.M{"$sum": 1}}},
+			},
+			"byQuestion": bson.A{
+				bson.M{"$match": bson.M{"type": bson.M{"$in": bson.A{models.ProgressEventView, models.ProgressEventAnswer}}}},
+				bson.M{"$group": bson.M{"_id": bson.M{"questionId": "$questionId", "type": "$type", "sessionId": "$sessionId"}}},
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"questionId": "$_id.questionId", "type": "$_id.type"},
+					"count": bson.M{"$sum": 1},
+				}},
+			},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate progress events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Sessions []struct {
+			Count int64 `bson:"count"`
+		} `bson:"sessions"`
+		ByType []struct {
+			Type  string `bson:"_id"`
+			Count int64  `bson:"count"`
+		} `bson:"byType"`
+		ByQuestion []struct {
+			ID struct {
+				QuestionID int    `bson:"questionId"`
+				Type       string `bson:"type"`
+			} `bson:"_id"`
+			Count int64 `bson:"count"`
+		} `bson:"byQuestion"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode progress events: %w", err)
+	}
+
+	counts := &SessionCounts{
+		ByType:     make(map[string]int64),
+		ByQuestion: make(map[int]map[string]int64),
+	}
+	if len(rows) == 0 {
+		return counts, nil
+	}
+
+	facets := rows[0]
+	if len(facets.Sessions) > 0 {
+		counts.Sessions = facets.Sessions[0].Count
+	}
+	for _, row := range facets.ByType {
+		counts.ByType[row.Type] = row.Count
+	}
+	for _, row := range facets.ByQuestion {
+		if counts.ByQuestion[row.ID.QuestionID] == nil {
+			counts.ByQuestion[row.ID.QuestionID] = make(map[string]int64)
+		}
+		counts.ByQuestion[row.ID.QuestionID][row.ID.Type] = row.Count
+	}
+	return counts, nil
+}
+
+// DeleteEvents removes all progress events of a form.
+func (r *ProgressRepository) DeleteEvents(ctx context.Context, formID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"formId": formID})
+	return err
+}
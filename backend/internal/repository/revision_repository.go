@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// maxRevisionNumberAttempts bounds the retries of CreateRevision when
+// another revision of the form took the number first.
+const maxRevisionNumberAttempts = 5
+
+// RevisionRepository stores form revisions. Revisions are only ever
+// inserted, there are no update methods. They are deleted together with
+// their form when it is purged from the trash.
+type RevisionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRevisionRepository(db *mongo.Database) *RevisionRepository {
+	return &RevisionRepository{
+		collection: db.Collection("form_revisions"),
+	}
+}
+
+func (r *RevisionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "formId", Value: 1}, {Key: "number", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateRevision stores revision with the next free number of its form.
+// Concurrent publishes of the same form collide on the unique index, the
+// loser takes the next number instead of failing.
+func (r *RevisionRepository) CreateRevision(ctx context.Context, revision *models.FormRevision) error {
+	for attempt := 1; ; attempt++ {
+		latest, err := r.LatestNumber(ctx, revision.FormID)
+		if err != nil {
+			return err
+		}
+		revision.Number = latest + 1
+
+		result, err := r.collection.InsertOne(ctx, revision)
+		if err == nil {
+			revision.ID = result.InsertedID.(primitive.ObjectID)
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) || attempt == maxRevisionNumberAttempts {
+			return fmt.Errorf("failed to insert revision: %w", err)
+		}
+	}
+}
+
+// LatestNumber returns the highest revision number of a form, 0 if it has none.
+func (r *RevisionRepository) LatestNumber(ctx context.Context, formID primitive.ObjectID) (int, error) {
+	opts := options.FindOne().
+		SetSort(bson.M{"number": -1}).
+		SetProjection(bson.M{"number": 1})
+
+	var revision models.FormRevision
+	err := r.collection.FindOne(ctx, bson.M{"formId": formID}, opts).Decode(&revision)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return revision.Number, nil
+}
+
+func (r *RevisionRepository) GetRevision(ctx context.Context, formID primitive.ObjectID, number int) (*models.FormRevision, error) {
+	var revision models.FormRevision
+	err := r.collection.FindOne(ctx, bson.M{"formId": formID, "number": number}).Decode(&revision)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// ListRevisions returns the revisions of a form, newest first.
+func (r *RevisionRepository) ListRevisions(ctx context.Context, formID primitive.ObjectID) ([]models.FormRevision, error) {
+	opts := options.Find().SetSort(bson.M{"number": -1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"formId": formID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find revisions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []models.FormRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to decode revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// DeleteRevisions removes the revisions of a form that is purged.
+func (r *RevisionRepository) DeleteRevisions(ctx context.Context, formID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"formId": formID})
+	return err
+}
+
+// ImageInUse reports whether a revision of a form of workspaceID other than
+// formID references the image URL, so rolling back would need it.
+func (r *RevisionRepository) ImageInUse(ctx context.Context, workspaceID, formID primitive.ObjectID, url string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"workspaceId": workspaceID,
+		"formId":      bson.M{"$ne": formID},
+		"$or": bson.A{
+			bson.M{"snapshot.questions.image": url},
+			bson.M{"snapshot.questions.options.image": url},
+		},
+	}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
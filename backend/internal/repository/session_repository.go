@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxUsedTokenHashes bounds how many replaced refresh tokens a session
+// remembers for reuse detection.
+const maxUsedTokenHashes = 100
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSessionRepository(db *mongo.Database) *SessionRepository {
+	return &SessionRepository{
+		collection: db.Collection("sessions"),
+	}
+}
+
+// EnsureIndexes also lets MongoDB remove sessions once they expire.
+func (r *SessionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"userId": 1}},
+		{
+			Keys:    bson.M{"expiresAt": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	result, err := r.collection.InsertOne(ctx, session)
+	if err != nil {
+		return err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *SessionRepository) FindSession(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	var session models.Session
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&session)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveSessions returns the sessions of a user that were neither
+// revoked nor expired, most recently used first.
+func (r *SessionRepository) ListActiveSessions(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]models.Session, error) {
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"userId": userID, "revokedAt": nil, "expiresAt": bson.M{"$gt": now}},
+		options.Find().SetSort(bson.M{"lastUsedAt": -1}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RotateToken replaces the refresh token of an active session. It fails
+// with ErrSessionNotFound if the token was rotated or the session revoked
+// in the meantime, so a token can only be exchanged once.
+func (r *SessionRepository) RotateToken(ctx context.Context, session *models.Session, newHash string) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": session.ID, "tokenHash": session.TokenHash, "revokedAt": nil},
+		bson.M{
+			"$set": bson.M{
+				"tokenHash":       newHash,
+				"accessTokenId":   session.AccessTokenID,
+				"accessExpiresAt": session.AccessExpiresAt,
+				"userAgent":       session.UserAgent,
+				"ip":              session.IP,
+				"lastUsedAt":      session.LastUsedAt,
+				"expiresAt":       session.ExpiresAt,
+			},
+			"$push": bson.M{"usedTokenHashes": bson.M{
+				"$each":  []string{session.TokenHash},
+				"$slice": -maxUsedTokenHashes,
+			}},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeSession(ctx context.Context, id, userID primitive.ObjectID, now time.Time) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "userId": userID, "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
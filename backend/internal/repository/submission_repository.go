@@ -2,12 +2,41 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// SubmissionCursor points at the last submission of a page.
+type SubmissionCursor struct {
+	CreatedAt time.Time
+	ID        primitive.ObjectID
+}
+
+// AnswerFilter matches submissions whose answer to QuestionID equals Value.
+// Value is compared with the answer text, selected option IDs and rating.
+type AnswerFilter struct {
+	QuestionID int
+	Value      string
+}
+
+// SubmissionQuery describes a page of submissions of a single form.
+type SubmissionQuery struct {
+	FormID        primitive.ObjectID
+	From          *time.Time
+	To            *time.Time
+	Ascending     bool
+	After         *SubmissionCursor
+	AnswerFilters []AnswerFilter
+	Limit         int
+}
+
 type SubmissionRepository struct {
 	collection *mongo.Collection
 }
@@ -18,6 +47,14 @@ func NewSubmissionRepository(db *mongo.Database) *SubmissionRepository {
 	}
 }
 
+func (r *SubmissionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "formId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "formId", Value: 1}, {Key: "answers.questionId", Value: 1}}},
+	})
+	return err
+}
+
 func (r *SubmissionRepository) CreateSubmission(s *models.Submission) error {
 	ctx := context.Background()
 	result, err := r.collection.InsertOne(ctx, s)
@@ -28,3 +65,234 @@ func (r *SubmissionRepository) CreateSubmission(s *models.Submission) error {
 	s.ID = result.InsertedID.(primitive.ObjectID)
 	return nil
 }
+
+func (r *SubmissionRepository) ListSubmissions(ctx context.Context, q SubmissionQuery) ([]models.Submission, error) {
+	direction := -1
+	if q.Ascending {
+		direction = 1
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(q.Limit))
+
+	cursor, err := r.collection.Find(ctx, submissionFilter(q), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find submissions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	submissions := []models.Submission{}
+	if err := cursor.All(ctx, &submissions); err != nil {
+		return nil, fmt.Errorf("failed to decode submissions: %w", err)
+	}
+	return submissions, nil
+}
+
+// StreamSubmissions calls fn for every submission of a form, oldest first.
+// Documents are decoded one at a time straight from the cursor.
+func (r *SubmissionRepository) StreamSubmissions(ctx context.Context, formID primitive.ObjectID, fn func(*models.Submission) error) error {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetBatchSize(500)
+
+	cursor, err := r.collection.Find(ctx, bson.M{"formId": formID}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find submissions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var sub models.Submission
+		if err := cursor.Decode(&sub); err != nil {
+			return fmt.Errorf("failed to decode submission: %w", err)
+		}
+		if err := fn(&sub); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func submissionFilter(q SubmissionQuery) bson.M {
+	filter := bson.M{"formId": q.FormID}
+	and := bson.A{}
+
+	createdAt := bson.M{}
+	if q.From != nil {
+		createdAt["$gte"] = *q.From
+	}
+	if q.To != nil {
+		createdAt["$lt"] = *q.To
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	// Keyset pagination on (createdAt, _id)
+	if q.After != nil {
+		op := "$lt"
+		if q.Ascending {
+			op = "$gt"
+		}
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"createdAt": bson.M{op: q.After.CreatedAt}},
+			bson.M{"createdAt": q.After.CreatedAt, "_id": bson.M{op: q.After.ID}},
+		}})
+	}
+
+	for _, f := range q.AnswerFilters {
+		and = append(and, bson.M{"answers": bson.M{"$elemMatch": answerMatch(f)}})
+	}
+
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+	return filter
+}
+
+func answerMatch(f AnswerFilter) bson.M {
+	values := bson.A{bson.M{"text": f.Value}}
+	if n, err := strconv.Atoi(f.Value); err == nil {
+		values = append(values, bson.M{"optionIds": n})
+	}
+	if n, err := strconv.ParseFloat(f.Value, 64); err == nil {
+		values = append(values, bson.M{"rating": n})
+	}
+	return bson.M{"questionId": f.QuestionID, "$or": values}
+}
+
+// OptionCount is the number of times an option of a question was picked.
+type OptionCount struct {
+	QuestionID int
+	OptionID   int
+	Count      int64
+}
+
+// RatingCount is the number of times a rating value was given to a question.
+type RatingCount struct {
+	QuestionID int
+	Value      float64
+	Count      int64
+}
+
+func (r *SubmissionRepository) CountSubmissions(ctx context.Context, formID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"formId": formID})
+}
+
+// CountSubmissionsBetween counts submissions created in [from, to).
+func (r *SubmissionRepository) CountSubmissionsBetween(ctx context.Context, formID primitive.ObjectID, from, to time.Time) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"formId":    formID,
+		"createdAt": bson.M{"$gte": from, "$lt": to},
+	})
+}
+
+// CountAnswers returns the number of answers given to each question of a form.
+func (r *SubmissionRepository) CountAnswers(ctx context.Context, formID primitive.ObjectID) (map[int]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"formId": formID}}},
+		{{Key: "$unwind", Value: "$answers"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$answers.questionId",
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	var rows []struct {
+		QuestionID int   `bson:"_id"`
+		Count      int64 `bson:"count"`
+	}
+	if err := r.aggregate(ctx, pipeline, &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.QuestionID] = row.Count
+	}
+	return counts, nil
+}
+
+// CountOptions returns how many times each option of each question was picked.
+func (r *SubmissionRepository) CountOptions(ctx context.Context, formID primitive.ObjectID) ([]OptionCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"formId": formID}}},
+		{{Key: "$unwind", Value: "$answers"}},
+		{{Key: "$unwind", Value: "$answers.optionIds"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"questionId": "$answers.questionId", "optionId": "$answers.optionIds"},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	var rows []struct {
+		ID struct {
+			QuestionID int `bson:"questionId"`
+			OptionID   int `bson:"optionId"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := r.aggregate(ctx, pipeline, &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make([]OptionCount, 0, len(rows))
+	for _, row := range rows {
+		counts = append(counts, OptionCount{QuestionID: row.ID.QuestionID, OptionID: row.ID.OptionID, Count: row.Count})
+	}
+	return counts, nil
+}
+
+// CountRatings returns the distribution of rating values for each question.
+func (r *SubmissionRepository) CountRatings(ctx context.Context, formID primitive.ObjectID) ([]RatingCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"formId": formID}}},
+		{{Key: "$unwind", Value: "$answers"}},
+		{{Key: "$match", Value: bson.M{"answers.rating": bson.M{"$exists": true}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"questionId": "$answers.questionId", "value": "$answers.rating"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.questionId", Value: 1}, {Key: "_id.value", Value: 1}}}},
+	}
+
+	var rows []struct {
+		ID struct {
+			QuestionID int     `bson:"questionId"`
+			Value      float64 `bson:"value"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := r.aggregate(ctx, pipeline, &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make([]RatingCount, 0, len(rows))
+	for _, row := range rows {
+		counts = append(counts, RatingCount{QuestionID: row.ID.QuestionID, Value: row.ID.Value, Count: row.Count})
+	}
+	return counts, nil
+}
+
+func (r *SubmissionRepository) aggregate(ctx context.Context, pipeline mongo.Pipeline, result interface{}) error {
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate submissions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, result); err != nil {
+		return fmt.Errorf("failed to decode aggregation result: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubmissions removes all submissions of a form.
+func (r *SubmissionRepository) DeleteSubmissions(ctx context.Context, formID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"formId": formID})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -19,8 +19,8 @@ type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	FindByID(ctx context.Context, id string) (*models.User, error)
 	EnsureIndexes(ctx context.Context) error
-	UpdateRefreshToken(ctx context.Context, userID primitive.ObjectID, refreshToken string) error
-	FindByRefreshToken(ctx context.Context, refreshToken string) (*models.User, error)
+	SetEmailVerified(ctx context.Context, userID primitive.ObjectID, verifiedAt time.Time) error
+	UpdatePassword(ctx context.Context, userID primitive.ObjectID, passwordHash string) error
 }
 
 type MongoUserRepository struct {
@@ -46,8 +46,12 @@ func (r *MongoUserRepository) Create(ctx context.Context, user *models.User) err
 	user.UpdatedAt = now
 
 	// Insert the new user
-	_, err = r.collection.InsertOne(ctx, user)
-	return err
+	result, err := r.collection.InsertOne(ctx, user)
+	if err != nil {
+		return err
+	}
+	user.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
 }
 
 func (r *MongoUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
@@ -89,24 +93,24 @@ func (r *MongoUserRepository) EnsureIndexes(ctx context.Context) error {
 	return err
 }
 
-func (r *MongoUserRepository) UpdateRefreshToken(ctx context.Context, userID primitive.ObjectID, refreshToken string) error {
-	logger.Info("Updating refresh token", zap.String("refreshToken", refreshToken))
-	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"refresh_token": refreshToken}})
-	logger.Info("Refresh token updated", zap.Int64("affectedDocuments", res.ModifiedCount))
-	if err != nil {
-		logger.Error("Error updating refresh token", zap.Error(err))
-	}
+func (r *MongoUserRepository) SetEmailVerified(ctx context.Context, userID primitive.ObjectID, verifiedAt time.Time) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "email_verified_at": nil},
+		bson.M{"$set": bson.M{"email_verified_at": verifiedAt, "updated_at": verifiedAt}},
+	)
 	return err
 }
 
-func (r *MongoUserRepository) FindByRefreshToken(ctx context.Context, refreshToken string) (*models.User, error) {
-	var user models.User
-	err := r.collection.FindOne(ctx, bson.M{"refresh_token": refreshToken}).Decode(&user)
+func (r *MongoUserRepository) UpdatePassword(ctx context.Context, userID primitive.ObjectID, passwordHash string) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"password": passwordHash, "updated_at": time.Now()}},
+	)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
+		return err
 	}
-	return &user, nil
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
 }
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrUserTokenNotFound = errors.New("user token not found")
+
+type UserTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewUserTokenRepository(db *mongo.Database) *UserTokenRepository {
+	return &UserTokenRepository{
+		collection: db.Collection("user_tokens"),
+	}
+}
+
+// EnsureIndexes also lets MongoDB remove tokens once they expire.
+func (r *UserTokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.M{"tokenHash": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}},
+		{
+			Keys:    bson.M{"expiresAt": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// ReplaceToken stores a token and removes the unused tokens the user had
+// for the same purpose, so only the latest email works.
+func (r *UserTokenRepository) ReplaceToken(ctx context.Context, token *models.UserToken) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{
+		"userId":  token.UserID,
+		"purpose": token.Purpose,
+		"usedAt":  nil,
+	}); err != nil {
+		return err
+	}
+
+	result, err := r.collection.InsertOne(ctx, token)
+	if err != nil {
+		return err
+	}
+	token.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ConsumeToken marks an unused, unexpired token as used and returns it.
+// Concurrent requests with the same token can not both succeed.
+func (r *UserTokenRepository) ConsumeToken(ctx context.Context, tokenHash, purpose string, now time.Time) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{
+			"tokenHash": tokenHash,
+			"purpose":   purpose,
+			"usedAt":    nil,
+			"expiresAt": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"usedAt": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&token)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrUserTokenNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
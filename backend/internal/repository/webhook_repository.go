@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+type WebhookRepository struct {
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+func NewWebhookRepository(db *mongo.Database) *WebhookRepository {
+	return &WebhookRepository{
+		webhooks:   db.Collection("webhooks"),
+		deliveries: db.Collection("webhook_deliveries"),
+	}
+}
+
+func (r *WebhookRepository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.webhooks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.M{"formId": 1},
+	}); err != nil {
+		return err
+	}
+
+	_, err := r.deliveries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+		{Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	result, err := r.webhooks.InsertOne(ctx, webhook)
+	if err != nil {
+		return err
+	}
+	webhook.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *WebhookRepository) ListWebhooks(ctx context.Context, formID primitive.ObjectID) ([]models.Webhook, error) {
+	cursor, err := r.webhooks.Find(ctx, bson.M{"formId": formID}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhooks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []models.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to decode webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) FindWebhook(ctx context.Context, id primitive.ObjectID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.webhooks.FindOne(ctx, bson.M{"_id": id}).Decode(&webhook)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id, formID primitive.ObjectID) error {
+	result, err := r.webhooks.DeleteOne(ctx, bson.M{"_id": id, "formId": formID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	result, err := r.deliveries.InsertOne(ctx, delivery)
+	if err != nil {
+		return err
+	}
+	delivery.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID primitive.ObjectID, limit int) ([]models.WebhookDelivery, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.deliveries.Find(ctx, bson.M{"webhookId": webhookID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deliveries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to decode deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id, webhookID primitive.ObjectID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.deliveries.FindOne(ctx, bson.M{"_id": id, "webhookId": webhookID}).Decode(&delivery)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ClaimDueDelivery atomically picks a pending delivery whose next attempt
+// is due and locks it for lease, so concurrent workers never send it twice.
+func (r *WebhookRepository) ClaimDueDelivery(ctx context.Context, now time.Time, lease time.Duration) (*models.WebhookDelivery, error) {
+	filter := bson.M{
+		"status":        models.DeliveryStatusPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"lockedUntil": bson.M{"$exists": false}},
+			bson.M{"lockedUntil": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"lockedUntil": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"nextAttemptAt": 1}).
+		SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	err := r.deliveries.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// FinishAttempt stores the outcome of a delivery attempt and releases the lock.
+func (r *WebhookRepository) FinishAttempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+	_, err := r.deliveries.UpdateOne(ctx,
+		bson.M{"_id": delivery.ID},
+		bson.M{
+			"$set": bson.M{
+				"status":         delivery.Status,
+				"attempts":       delivery.Attempts,
+				"nextAttemptAt":  delivery.NextAttemptAt,
+				"lastStatusCode": delivery.LastStatusCode,
+				"lastError":      delivery.LastError,
+				"deliveredAt":    delivery.DeliveredAt,
+				"updatedAt":      delivery.UpdatedAt,
+			},
+			"$unset": bson.M{"lockedUntil": ""},
+		},
+	)
+	return err
+}
+
+// ResetDelivery queues a failed delivery again from scratch.
+func (r *WebhookRepository) ResetDelivery(ctx context.Context, id primitive.ObjectID) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":        models.DeliveryStatusPending,
+			"attempts":      0,
+			"nextAttemptAt": now,
+			"updatedAt":     now,
+		},
+		"$unset": bson.M{"lockedUntil": "", "lastError": "", "lastStatusCode": "", "deliveredAt": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	err := r.deliveries.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": models.DeliveryStatusFailed}, update, opts).Decode(&delivery)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// DeleteFormWebhooks removes the webhooks of a form with their deliveries.
+func (r *WebhookRepository) DeleteFormWebhooks(ctx context.Context, formID primitive.ObjectID) error {
+	if _, err := r.deliveries.DeleteMany(ctx, bson.M{"formId": formID}); err != nil {
+		return err
+	}
+	_, err := r.webhooks.DeleteMany(ctx, bson.M{"formId": formID})
+	return err
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrMemberNotFound     = errors.New("workspace member not found")
+	ErrMemberExists       = errors.New("user is already a member of the workspace")
+	ErrInvitationNotFound = errors.New("invitation not found")
+)
+
+type WorkspaceRepository struct {
+	workspaces  *mongo.Collection
+	members     *mongo.Collection
+	invitations *mongo.Collection
+}
+
+func NewWorkspaceRepository(db *mongo.Database) *WorkspaceRepository {
+	return &WorkspaceRepository{
+		workspaces:  db.Collection("workspaces"),
+		members:     db.Collection("workspace_members"),
+		invitations: db.Collection("workspace_invitations"),
+	}
+}
+
+func (r *WorkspaceRepository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.members.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "workspaceId", Value: 1}, {Key: "userId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.M{"userId": 1}},
+	}); err != nil {
+		return err
+	}
+
+	_, err := r.invitations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.M{"tokenHash": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.M{"workspaceId": 1}},
+	})
+	return err
+}
+
+// workspaceOwnedCollections store the owning workspace in workspaceId.
+// Documents written before workspaces existed kept it in userId.
+var workspaceOwnedCollections = []string{"forms", "folders", "images", "form_revisions", "notification_settings", "webhooks"}
+
+// BackfillWorkspaceIDs moves userId to workspaceId in documents stored
+// before the field was renamed. The old value is the ID of the personal
+// workspace of the user, so it can be kept as is.
+func BackfillWorkspaceIDs(ctx context.Context, db *mongo.Database) error {
+	filter := bson.M{"workspaceId": bson.M{"$exists": false}, "userId": bson.M{"$exists": true}}
+	update := bson.M{"$rename": bson.M{"userId": "workspaceId"}}
+	for _, name := range workspaceOwnedCollections {
+		if _, err := db.Collection(name).UpdateMany(ctx, filter, update); err != nil {
+			return fmt.Errorf("failed to backfill workspace IDs of %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (r *WorkspaceRepository) CreateWorkspace(ctx context.Context, workspace *models.Workspace) error {
+	result, err := r.workspaces.InsertOne(ctx, workspace)
+	if err != nil {
+		return err
+	}
+	workspace.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *WorkspaceRepository) FindWorkspace(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := r.workspaces.FindOne(ctx, bson.M{"_id": id}).Decode(&workspace)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+func (r *WorkspaceRepository) FindWorkspaces(ctx context.Context, ids []primitive.ObjectID) ([]models.Workspace, error) {
+	cursor, err := r.workspaces.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workspaces: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	workspaces := []models.Workspace{}
+	if err := cursor.All(ctx, &workspaces); err != nil {
+		return nil, fmt.Errorf("failed to decode workspaces: %w", err)
+	}
+	return workspaces, nil
+}
+
+func (r *WorkspaceRepository) AddMember(ctx context.Context, member *models.WorkspaceMember) error {
+	result, err := r.members.InsertOne(ctx, member)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrMemberExists
+		}
+		return err
+	}
+	member.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (r *WorkspaceRepository) FindMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+	err := r.members.FindOne(ctx, bson.M{"workspaceId": workspaceID, "userId": userID}).Decode(&member)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID primitive.ObjectID) ([]models.WorkspaceMember, error) {
+	return r.findMembers(ctx, bson.M{"workspaceId": workspaceID})
+}
+
+// ListMemberships returns the memberships of a user in all workspaces.
+func (r *WorkspaceRepository) ListMemberships(ctx context.Context, userID primitive.ObjectID) ([]models.WorkspaceMember, error) {
+	return r.findMembers(ctx, bson.M{"userId": userID})
+}
+
+func (r *WorkspaceRepository) findMembers(ctx context.Context, filter bson.M) ([]models.WorkspaceMember, error) {
+	cursor, err := r.members.Find(ctx, filter, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workspace members: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	members := []models.WorkspaceMember{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace members: %w", err)
+	}
+	return members, nil
+}
+
+func (r *WorkspaceRepository) CountOwners(ctx context.Context, workspaceID primitive.ObjectID) (int64, error) {
+	return r.members.CountDocuments(ctx, bson.M{"workspaceId": workspaceID, "role": models.RoleOwner})
+}
+
+func (r *WorkspaceRepository) UpdateMemberRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role string) error {
+	result, err := r.members.UpdateOne(ctx,
+		bson.M{"workspaceId": workspaceID, "userId": userID},
+		bson.M{"$set": bson.M{"role": role}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+func (r *WorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID primitive.ObjectID) error {
+	result, err := r.members.DeleteOne(ctx, bson.M{"workspaceId": workspaceID, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+func (r *WorkspaceRepository) CreateInvitation(ctx context.Context, invitation *models.WorkspaceInvitation) error {
+	result, err := r.invitations.InsertOne(ctx, invitation)
+	if err != nil {
+		return err
+	}
+	invitation.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// ListInvitations returns the invitations of a workspace that were not accepted yet.
+func (r *WorkspaceRepository) ListInvitations(ctx context.Context, workspaceID primitive.ObjectID) ([]models.WorkspaceInvitation, error) {
+	cursor, err := r.invitations.Find(ctx,
+		bson.M{"workspaceId": workspaceID, "acceptedAt": nil},
+		options.Find().SetSort(bson.M{"createdAt": -1}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find invitations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []models.WorkspaceInvitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, fmt.Errorf("failed to decode invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+func (r *WorkspaceRepository) FindInvitationByToken(ctx context.Context, tokenHash string) (*models.WorkspaceInvitation, error) {
+	var invitation models.WorkspaceInvitation
+	err := r.invitations.FindOne(ctx, bson.M{"tokenHash": tokenHash}).Decode(&invitation)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// AcceptInvitation marks an open invitation as used. It fails with
+// ErrInvitationNotFound if the invitation was accepted or has expired in
+// the meantime, so a token can only be used once.
+func (r *WorkspaceRepository) AcceptInvitation(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	result, err := r.invitations.UpdateOne(ctx,
+		bson.M{"_id": id, "acceptedAt": nil, "expiresAt": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"acceptedAt": now}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (r *WorkspaceRepository) DeleteInvitation(ctx context.Context, id, workspaceID primitive.ObjectID) error {
+	result, err := r.invitations.DeleteOne(ctx, bson.M{"_id": id, "workspaceId": workspaceID, "acceptedAt": nil})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	apiKeyPrefix        = "fe_"
+	apiKeyDisplayLength = 10
+	maxAPIKeyNameLength = 100
+	maxAPIKeysPerUser   = 50
+
+	// lastUsedInterval limits how often the last use of a key is saved, so
+	// busy scripts do not cause a write per request
+	lastUsedInterval = time.Minute
+)
+
+// ErrInvalidAPIKey is returned for unknown, malformed and revoked keys.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+type APIKeyService struct {
+	repo     *repository.APIKeyRepository
+	userRepo repository.UserRepository
+}
+
+func NewAPIKeyService(repo *repository.APIKeyRepository, userRepo repository.UserRepository) *APIKeyService {
+	return &APIKeyService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// CreateKey creates a key of userID. The key itself is returned only here.
+func (s *APIKeyService) CreateKey(ctx context.Context, userID, name string, scopes []string) (*models.APIKey, string, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, "", apperrors.NewBadRequestError("invalid user ID")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", apperrors.NewBadRequestError("API key name is required")
+	}
+	if len([]rune(name)) > maxAPIKeyNameLength {
+		return nil, "", apperrors.NewBadRequestError(fmt.Sprintf("API key name must be at most %d characters", maxAPIKeyNameLength))
+	}
+	scopes, err = normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keys, err := s.repo.ListKeys(ctx, userObjectID)
+	if err != nil {
+		return nil, "", apperrors.NewInternalServerError("failed to list API keys", err)
+	}
+	active := 0
+	for _, k := range keys {
+		if k.RevokedAt == nil {
+			active++
+		}
+	}
+	if active >= maxAPIKeysPerUser {
+		return nil, "", apperrors.NewBadRequestError(fmt.Sprintf("a user can have at most %d active API keys", maxAPIKeysPerUser))
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", apperrors.NewInternalServerError("failed to generate API key", err)
+	}
+	secret := apiKeyPrefix + token
+
+	key := &models.APIKey{
+		UserID:    userObjectID,
+		Name:      name,
+		Prefix:    secret[:apiKeyDisplayLength],
+		KeyHash:   hashToken(secret),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.CreateKey(ctx, key); err != nil {
+		return nil, "", apperrors.NewInternalServerError("failed to create API key", err)
+	}
+	return key, secret, nil
+}
+
+func (s *APIKeyService) ListKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid user ID")
+	}
+
+	keys, err := s.repo.ListKeys(ctx, userObjectID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list API keys", err)
+	}
+	return keys, nil
+}
+
+// RevokeKey stops a key from working. Revoked keys stay listed.
+func (s *APIKeyService) RevokeKey(ctx context.Context, id, userID string) error {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return apperrors.NewBadRequestError("invalid user ID")
+	}
+	keyObjectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return apperrors.NewBadRequestError("invalid API key ID")
+	}
+
+	if err := s.repo.RevokeKey(ctx, keyObjectID, userObjectID, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return apperrors.NewNotFoundError("API key not found")
+		}
+		return apperrors.NewInternalServerError("failed to revoke API key", err)
+	}
+	return nil
+}
+
+// AuthenticateAPIKey returns an active key and the user it belongs to.
+// It fails with ErrInvalidAPIKey if secret does not belong to such a key.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, secret string) (*models.APIKey, *models.User, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.FindActiveKey(ctx, hashToken(secret))
+	if err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, apperrors.NewInternalServerError("failed to load API key", err)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, key.UserID.Hex())
+	if err != nil {
+		// Keys of deleted users do not work
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+		if err := s.repo.SetLastUsed(ctx, key.ID, now); err != nil {
+			logger.Error("Failed to save API key use", zap.String("keyId", key.ID.Hex()), zap.Error(err))
+		}
+		key.LastUsedAt = &now
+	}
+	return key, user, nil
+}
+
+// normalizeScopes checks scopes and removes duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope != models.APIKeyScopeRead && scope != models.APIKeyScopeWrite {
+			return nil, apperrors.NewBadRequestError("API key scopes must be read or write")
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, apperrors.NewBadRequestError("API key needs at least one scope")
+	}
+	return result, nil
+}
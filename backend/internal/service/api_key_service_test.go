@@ -0,0 +1,35 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "read", scopes: []string{"read"}, want: []string{"read"}},
+		{name: "both", scopes: []string{"read", "write"}, want: []string{"read", "write"}},
+		{name: "case and spaces", scopes: []string{" READ ", "Write"}, want: []string{"read", "write"}},
+		{name: "duplicates", scopes: []string{"write", "WRITE", "write"}, want: []string{"write"}},
+		{name: "unknown scope", scopes: []string{"read", "admin"}, wantErr: true},
+		{name: "blank scope", scopes: []string{""}, wantErr: true},
+		{name: "no scopes", scopes: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeScopes(tt.scopes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeScopes(%q) error = %v, wantErr %v", tt.scopes, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeScopes(%q) = %q, want %q", tt.scopes, got, tt.want)
+			}
+		})
+	}
+}
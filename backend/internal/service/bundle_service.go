@@ -0,0 +1,459 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	BundleFormatZip  = "zip"
+	BundleFormatJSON = "json"
+
+	bundleManifestName    = "form.json"
+	maxBundleManifestSize = 5 << 20 // 5 MB
+)
+
+// BundleService moves forms between installations as self-contained bundles.
+type BundleService struct {
+	formService  *FormService
+	imageService *ImageService
+}
+
+func NewBundleService(formService *FormService, imageService *ImageService) *BundleService {
+	return &BundleService{
+		formService:  formService,
+		imageService: imageService,
+	}
+}
+
+// BundleExport streams a form bundle with the images of the form.
+type BundleExport struct {
+	Filename    string
+	ContentType string
+
+	format       string
+	bundle       *models.FormBundle
+	imageService *ImageService
+}
+
+// ImportResult is the draft created from a bundle together with what had
+// to be changed on the way.
+type ImportResult struct {
+	Form      *models.Form            `json:"form"`
+	Warnings  []FormIssue             `json:"warnings,omitempty"`
+	Conflicts []models.ImportConflict `json:"conflicts"`
+}
+
+// PrepareExport checks access to the form and collects the library images
+// it references. Image data is only read while streaming.
+func (s *BundleService) PrepareExport(formID, workspaceID, format string) (*BundleExport, error) {
+	if format != BundleFormatZip && format != BundleFormatJSON {
+		return nil, apperrors.NewBadRequestError("unsupported bundle format")
+	}
+
+	form, err := s.formService.GetOwnedForm(formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := s.imageService.FindByURLs(workspaceID, formImageURLs(form))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to load form images", err)
+	}
+
+	bundle := &models.FormBundle{
+		Format:     models.BundleFormat,
+		Version:    models.BundleVersion,
+		ExportedAt: time.Now(),
+		Form:       models.NewFormSnapshot(form),
+		Images:     make([]models.BundleImage, 0, len(images)),
+	}
+	for i, image := range images {
+		bundled := models.BundleImage{
+			URL:         image.URL,
+			Filename:    image.Filename,
+			Type:        image.Type,
+			Title:       image.Title,
+			Description: image.Description,
+		}
+		if format == BundleFormatZip {
+			bundled.Path = "images/" + strconv.Itoa(i+1) + filepath.Ext(image.Filename)
+		}
+		bundle.Images = append(bundle.Images, bundled)
+	}
+
+	contentType := "application/zip"
+	if format == BundleFormatJSON {
+		contentType = "application/json"
+	}
+
+	return &BundleExport{
+		Filename:     fmt.Sprintf("%s-%s.%s", exportFilename(form.Name), time.Now().Format("20060102"), format),
+		ContentType:  contentType,
+		format:       format,
+		bundle:       bundle,
+		imageService: s.imageService,
+	}, nil
+}
+
+// WriteTo streams the bundle to w. Once writing started the response can
+// no longer change, so errors are only useful for logging.
+func (e *BundleExport) WriteTo(ctx context.Context, w io.Writer) error {
+	if e.format == BundleFormatJSON {
+		for i := range e.bundle.Images {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			data, err := e.readImage(e.bundle.Images[i].Filename)
+			if err != nil {
+				return err
+			}
+			e.bundle.Images[i].Data = data
+		}
+		return json.NewEncoder(w).Encode(e.bundle)
+	}
+
+	zw := zip.NewWriter(w)
+	manifest, err := zw.Create(bundleManifestName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifest)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(e.bundle); err != nil {
+		return err
+	}
+
+	for _, image := range e.bundle.Images {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f, err := zw.Create(image.Path)
+		if err != nil {
+			return err
+		}
+		if err := e.copyImage(f, image.Filename); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (e *BundleExport) readImage(filename string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := e.copyImage(&buf, filename); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *BundleExport) copyImage(w io.Writer, filename string) error {
+	file, err := e.imageService.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open image %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("failed to read image %s: %w", filename, err)
+	}
+	return nil
+}
+
+// ImportBundle creates a draft form of workspaceID from a zip or JSON bundle.
+// Images are uploaded into the library of the user unless it already has
+// them, questions are renumbered from 1. Anything that could not be
+// imported as is gets reported as a conflict.
+func (s *BundleService) ImportBundle(workspaceID string, r io.ReaderAt, size int64) (*ImportResult, error) {
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	bundle, archive, err := readBundle(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	form := &models.Form{
+		WorkspaceID: workspaceObjectID,
+		IsDraft:     true,
+	}
+	bundle.Form.Apply(form)
+
+	result := &ImportResult{Form: form, Conflicts: []models.ImportConflict{}}
+	result.Conflicts = append(result.Conflicts, renumberQuestions(form)...)
+
+	urls, uploaded, conflicts := s.importImages(workspaceID, bundle, archive)
+	result.Conflicts = append(result.Conflicts, conflicts...)
+	rewriteImageURLs(form, urls)
+
+	if conflict, err := s.resolveNameConflict(form, workspaceID); err != nil {
+		s.deleteImages(uploaded)
+		return nil, err
+	} else if conflict != nil {
+		result.Conflicts = append(result.Conflicts, *conflict)
+	}
+
+	warnings, err := s.formService.CreateForm(form)
+	if err != nil {
+		s.deleteImages(uploaded)
+		return nil, err
+	}
+	result.Warnings = warnings
+	return result, nil
+}
+
+// readBundle detects the bundle type by its content. The zip reader is nil
+// for JSON bundles.
+func readBundle(r io.ReaderAt, size int64) (*models.FormBundle, *zip.Reader, error) {
+	var (
+		manifest io.Reader
+		archive  *zip.Reader
+	)
+
+	magic := make([]byte, 4)
+	if n, _ := r.ReadAt(magic, 0); n == len(magic) && bytes.Equal(magic, []byte("PK\x03\x04")) {
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, nil, apperrors.NewBadRequestError("invalid bundle archive")
+		}
+		f, err := zr.Open(bundleManifestName)
+		if err != nil {
+			return nil, nil, apperrors.NewBadRequestError("bundle archive has no " + bundleManifestName)
+		}
+		defer f.Close()
+		manifest = io.LimitReader(f, maxBundleManifestSize)
+		archive = zr
+	} else {
+		manifest = io.NewSectionReader(r, 0, size)
+	}
+
+	var bundle models.FormBundle
+	if err := json.NewDecoder(manifest).Decode(&bundle); err != nil {
+		return nil, nil, apperrors.NewBadRequestError("invalid bundle: " + err.Error())
+	}
+	if bundle.Format != models.BundleFormat {
+		return nil, nil, apperrors.NewBadRequestError("not a form bundle")
+	}
+	if bundle.Version < 1 || bundle.Version > models.BundleVersion {
+		return nil, nil, apperrors.NewBadRequestError(fmt.Sprintf("unsupported bundle version %d", bundle.Version))
+	}
+	return &bundle, archive, nil
+}
+
+// importImages returns the new URL of every bundled image that is available
+// in the library of workspaceID, and the IDs of the images it uploaded.
+func (s *BundleService) importImages(workspaceID string, bundle *models.FormBundle, archive *zip.Reader) (map[string]string, []string, []models.ImportConflict) {
+	urls := make(map[string]string, len(bundle.Images))
+	var (
+		uploaded  []string
+		conflicts []models.ImportConflict
+	)
+
+	bundled := make([]string, 0, len(bundle.Images))
+	for _, image := range bundle.Images {
+		bundled = append(bundled, image.URL)
+	}
+	// Importing into the installation the bundle came from reuses the images
+	existing, err := s.imageService.FindByURLs(workspaceID, bundled)
+	if err != nil {
+		logger.Error("Failed to look up existing images", zap.Error(err))
+	}
+	for _, image := range existing {
+		urls[image.URL] = image.URL
+	}
+
+	for _, image := range bundle.Images {
+		if _, ok := urls[image.URL]; ok {
+			continue
+		}
+
+		data, err := bundleImageData(image, archive)
+		if err == nil {
+			var stored *models.Image
+			stored, err = s.imageService.UploadImage(&models.Image{
+				WorkspaceID: workspaceID,
+				Filename:    image.Filename,
+				Size:        int64(len(data)),
+				Type:        image.Type,
+				CreatedAt:   time.Now(),
+				Title:       image.Title,
+				Description: image.Description,
+			}, memoryFile{bytes.NewReader(data)})
+			if err == nil {
+				urls[image.URL] = stored.URL
+				uploaded = append(uploaded, stored.ID.Hex())
+				continue
+			}
+		}
+
+		conflicts = append(conflicts, models.ImportConflict{
+			Type:    models.ImportConflictImage,
+			URL:     image.URL,
+			Message: fmt.Sprintf("image was not imported, the original URL is kept: %v", err),
+		})
+	}
+	return urls, uploaded, conflicts
+}
+
+func bundleImageData(image models.BundleImage, archive *zip.Reader) ([]byte, error) {
+	if archive == nil || image.Path == "" {
+		if len(image.Data) == 0 {
+			return nil, errors.New("image data is missing")
+		}
+		return image.Data, nil
+	}
+
+	f, err := archive.Open(image.Path)
+	if err != nil {
+		return nil, fmt.Errorf("file %s is missing from the bundle", image.Path)
+	}
+	defer f.Close()
+
+	// Anything above the upload limit is rejected by the image service anyway
+	data, err := io.ReadAll(io.LimitReader(f, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", image.Path, err)
+	}
+	return data, nil
+}
+
+func (s *BundleService) deleteImages(ids []string) {
+	for _, id := range ids {
+		if err := s.imageService.Delete(id); err != nil {
+			logger.Error("Failed to delete imported image", zap.String("imageId", id), zap.Error(err))
+		}
+	}
+}
+
+// resolveNameConflict renames the form if workspaceID already has a form with
+// the same name.
+func (s *BundleService) resolveNameConflict(form *models.Form, workspaceID string) (*models.ImportConflict, error) {
+	forms, err := s.formService.ListForms(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list forms", err)
+	}
+
+	names := make(map[string]bool, len(forms))
+	for _, f := range forms {
+		names[f.Name] = true
+	}
+	if !names[form.Name] {
+		return nil, nil
+	}
+
+	name := form.Name + " (imported)"
+	for i := 2; names[name]; i++ {
+		name = fmt.Sprintf("%s (imported %d)", form.Name, i)
+	}
+	conflict := &models.ImportConflict{
+		Type:    models.ImportConflictName,
+		Message: fmt.Sprintf("a form named %q already exists, the import was named %q", form.Name, name),
+	}
+	form.Name = name
+	return conflict, nil
+}
+
+// renumberQuestions gives the questions IDs 1..n in their order and updates
+// the next question references. References to missing questions are removed.
+func renumberQuestions(form *models.Form) []models.ImportConflict {
+	var conflicts []models.ImportConflict
+
+	ids := make(map[int]int, len(form.Questions))
+	for i := range form.Questions {
+		q := &form.Questions[i]
+		newID := i + 1
+		if _, exists := ids[q.ID]; exists {
+			// References can only lead to the first question with the ID
+			conflicts = append(conflicts, models.ImportConflict{
+				Type:       models.ImportConflictQuestionID,
+				QuestionID: newID,
+				Message:    fmt.Sprintf("question ID %d is used more than once, the duplicate became question %d", q.ID, newID),
+			})
+		} else {
+			ids[q.ID] = newID
+		}
+		q.ID = newID
+	}
+
+	remap := func(q *models.Question, id int) int {
+		if id == 0 {
+			return 0
+		}
+		if newID, ok := ids[id]; ok {
+			return newID
+		}
+		conflicts = append(conflicts, models.ImportConflict{
+			Type:       models.ImportConflictReference,
+			QuestionID: q.ID,
+			Message:    fmt.Sprintf("reference to missing question %d was removed", id),
+		})
+		return 0
+	}
+	for i := range form.Questions {
+		q := &form.Questions[i]
+		q.NextQuestion.Default = remap(q, q.NextQuestion.Default)
+		for j := range q.NextQuestion.Conditions {
+			q.NextQuestion.Conditions[j].NextID = remap(q, q.NextQuestion.Conditions[j].NextID)
+		}
+	}
+	return conflicts
+}
+
+// formImageURLs lists the distinct image URLs used by the questions and
+// options of a form.
+func formImageURLs(form *models.Form) []string {
+	seen := map[string]bool{}
+	var urls []string
+	add := func(url string) {
+		if url != "" && !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	for _, q := range form.Questions {
+		add(q.Image)
+		for _, o := range q.Options {
+			add(o.Image)
+		}
+	}
+	return urls
+}
+
+func rewriteImageURLs(form *models.Form, urls map[string]string) {
+	for i := range form.Questions {
+		q := &form.Questions[i]
+		if url, ok := urls[q.Image]; ok {
+			q.Image = url
+		}
+		for j := range q.Options {
+			if url, ok := urls[q.Options[j].Image]; ok {
+				q.Options[j].Image = url
+			}
+		}
+	}
+}
+
+// memoryFile lets the image service read uploads that are already in memory.
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+)
+
+func TestRenumberQuestions(t *testing.T) {
+	// question builds a question with a default next ID and condition next IDs
+	question := func(id, next int, conditionIDs ...int) models.Question {
+		q := models.Question{ID: id, NextQuestion: models.NextQuestion{Default: next}}
+		for _, c := range conditionIDs {
+			q.NextQuestion.Conditions = append(q.NextQuestion.Conditions, models.Condition{NextID: c})
+		}
+		return q
+	}
+
+	tests := []struct {
+		name          string
+		questions     []models.Question
+		want          []models.Question
+		wantConflicts []string
+	}{
+		{
+			name:      "already numbered",
+			questions: []models.Question{question(1, 2), question(2, 0)},
+			want:      []models.Question{question(1, 2), question(2, 0)},
+		},
+		{
+			name:      "sparse IDs and references",
+			questions: []models.Question{question(10, 30, 20), question(20, 30), question(30, 0)},
+			want:      []models.Question{question(1, 3, 2), question(2, 3), question(3, 0)},
+		},
+		{
+			name:          "reference to missing question",
+			questions:     []models.Question{question(5, 9, 7), question(7, 0)},
+			want:          []models.Question{question(1, 0, 2), question(2, 0)},
+			wantConflicts: []string{models.ImportConflictReference},
+		},
+		{
+			name:          "duplicate ID",
+			questions:     []models.Question{question(4, 8), question(8, 0), question(8, 4)},
+			want:          []models.Question{question(1, 2), question(2, 0), question(3, 1)},
+			wantConflicts: []string{models.ImportConflictQuestionID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := &models.Form{Questions: tt.questions}
+			conflicts := renumberQuestions(form)
+
+			if !reflect.DeepEqual(form.Questions, tt.want) {
+				t.Errorf("questions = %+v, want %+v", form.Questions, tt.want)
+			}
+			var types []string
+			for _, c := range conflicts {
+				types = append(types, c.Type)
+			}
+			if !reflect.DeepEqual(types, tt.wantConflicts) {
+				t.Errorf("conflicts = %+v, want types %v", conflicts, tt.wantConflicts)
+			}
+		})
+	}
+}
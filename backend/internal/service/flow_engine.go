@@ -0,0 +1,101 @@
+package service
+
+import (
+	"github.com/maxzhirnov/formease/internal/models"
+)
+
+// FlowEngine walks the question graph of a form the same way the form
+// player does: it starts at the first question and follows NextQuestion
+// until a question without a next one is reached.
+type FlowEngine struct {
+	form      *models.Form
+	questions map[int]*models.Question
+}
+
+func NewFlowEngine(form *models.Form) *FlowEngine {
+	questions := make(map[int]*models.Question, len(form.Questions))
+	for i := range form.Questions {
+		questions[form.Questions[i].ID] = &form.Questions[i]
+	}
+	return &FlowEngine{
+		form:      form,
+		questions: questions,
+	}
+}
+
+// Start returns the ID of the first question, or 0 if the form is empty.
+func (e *FlowEngine) Start() int {
+	if len(e.form.Questions) == 0 {
+		return 0
+	}
+	return e.form.Questions[0].ID
+}
+
+// Question returns the question with the given ID.
+func (e *FlowEngine) Question(id int) (*models.Question, bool) {
+	q, ok := e.questions[id]
+	return q, ok
+}
+
+// Next returns the ID of the question that follows q for the given answer,
+// or 0 when the form ends after q. Conditions are only evaluated for
+// single-choice questions, every other type moves on to the default.
+func (e *FlowEngine) Next(q *models.Question, answer *models.Answer) int {
+	if q.Type == "single-choice" && answer != nil && len(answer.OptionIDs) > 0 {
+		selected := optionText(q, answer.OptionIDs[0])
+		for _, c := range q.NextQuestion.Conditions {
+			if c.Answer == selected && c.NextID != 0 {
+				return c.NextID
+			}
+		}
+	}
+	return q.NextQuestion.Default
+}
+
+// Replay follows the answers through the question graph and reports
+// required questions that were skipped on the way and answers to
+// questions that are not reachable with the given choices.
+func (e *FlowEngine) Replay(answers []models.Answer) []AnswerError {
+	answered := make(map[int]*models.Answer, len(answers))
+	for i := range answers {
+		answered[answers[i].QuestionID] = &answers[i]
+	}
+
+	var errs []AnswerError
+	visited := make(map[int]bool)
+	for id := e.Start(); id != 0; {
+		q, ok := e.questions[id]
+		if !ok {
+			errs = append(errs, AnswerError{QuestionID: id, Message: "form flow points to a missing question"})
+			break
+		}
+		if visited[id] {
+			errs = append(errs, AnswerError{QuestionID: id, Message: "form flow contains a cycle"})
+			break
+		}
+		visited[id] = true
+
+		answer := answered[id]
+		if answer == nil && !q.Optional {
+			errs = append(errs, AnswerError{QuestionID: id, Message: "required question was skipped"})
+		}
+		id = e.Next(q, answer)
+	}
+
+	for _, a := range answers {
+		if _, exists := e.questions[a.QuestionID]; exists && !visited[a.QuestionID] {
+			errs = append(errs, AnswerError{QuestionID: a.QuestionID, Message: "question is not reachable with the given answers"})
+		}
+	}
+
+	return errs
+}
+
+func optionText(q *models.Question, optionID int) string {
+	for _, o := range q.Options {
+		if o.ID == optionID {
+			return o.Text
+		}
+	}
+	return ""
+}
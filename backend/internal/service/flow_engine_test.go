@@ -0,0 +1,111 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+)
+
+// flowTestForm branches on question 1: "Yes" goes to 2, anything else to 3.
+// Question 3 is optional and both paths end after 4.
+func flowTestForm() *models.Form {
+	return &models.Form{Questions: []models.Question{
+		{
+			ID:      1,
+			Type:    "single-choice",
+			Options: []models.Option{{ID: 1, Text: "Yes"}, {ID: 2, Text: "No"}},
+			NextQuestion: models.NextQuestion{
+				Conditions: []models.Condition{{Answer: "Yes", NextID: 2}},
+				Default:    3,
+			},
+		},
+		{ID: 2, Type: "input", NextQuestion: models.NextQuestion{Default: 4}},
+		{ID: 3, Type: "input", Optional: true, NextQuestion: models.NextQuestion{Default: 4}},
+		{ID: 4, Type: "rating", MinValue: 1, MaxValue: 5},
+	}}
+}
+
+func TestFlowEngineReplay(t *testing.T) {
+	rating := 4.0
+	yes := models.Answer{QuestionID: 1, OptionIDs: []int{1}}
+	no := models.Answer{QuestionID: 1, OptionIDs: []int{2}}
+	last := models.Answer{QuestionID: 4, Rating: &rating}
+
+	type issue struct {
+		QuestionID int
+		Message    string
+	}
+	tests := []struct {
+		name    string
+		form    *models.Form
+		answers []models.Answer
+		want    []issue
+	}{
+		{
+			name:    "branch taken by condition",
+			form:    flowTestForm(),
+			answers: []models.Answer{yes, {QuestionID: 2, Text: "because"}, last},
+		},
+		{
+			name:    "default branch with optional question skipped",
+			form:    flowTestForm(),
+			answers: []models.Answer{no, last},
+		},
+		{
+			name:    "required question skipped",
+			form:    flowTestForm(),
+			answers: []models.Answer{yes, last},
+			want:    []issue{{2, "required question was skipped"}},
+		},
+		{
+			name:    "answer on the branch not taken",
+			form:    flowTestForm(),
+			answers: []models.Answer{no, {QuestionID: 2, Text: "because"}, last},
+			want:    []issue{{2, "question is not reachable with the given answers"}},
+		},
+		{
+			name:    "empty submission",
+			form:    flowTestForm(),
+			answers: nil,
+			want: []issue{
+				{1, "required question was skipped"},
+				{4, "required question was skipped"},
+			},
+		},
+		{
+			name: "missing next question",
+			form: &models.Form{Questions: []models.Question{
+				{ID: 1, Type: "input", NextQuestion: models.NextQuestion{Default: 9}},
+			}},
+			answers: []models.Answer{{QuestionID: 1, Text: "a"}},
+			want:    []issue{{9, "form flow points to a missing question"}},
+		},
+		{
+			name: "cycle",
+			form: &models.Form{Questions: []models.Question{
+				{ID: 1, Type: "input", NextQuestion: models.NextQuestion{Default: 2}},
+				{ID: 2, Type: "input", NextQuestion: models.NextQuestion{Default: 1}},
+			}},
+			answers: []models.Answer{{QuestionID: 1, Text: "a"}, {QuestionID: 2, Text: "b"}},
+			want:    []issue{{1, "form flow contains a cycle"}},
+		},
+		{
+			name:    "empty form",
+			form:    &models.Form{},
+			answers: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []issue
+			for _, e := range NewFlowEngine(tt.form).Replay(tt.answers) {
+				got = append(got, issue{e.QuestionID, e.Message})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Replay() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
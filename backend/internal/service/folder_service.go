@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxFolderDepth      = 10
+	maxFolderNameLength = 100
+)
+
+// FolderStore persists folders, *repository.FolderRepository implements it.
+type FolderStore interface {
+	CreateFolder(ctx context.Context, folder *models.Folder) error
+	ListFolders(ctx context.Context, workspaceID primitive.ObjectID) ([]models.Folder, error)
+	FindFolder(ctx context.Context, id, workspaceID primitive.ObjectID) (*models.Folder, error)
+	UpdateFolder(ctx context.Context, folder *models.Folder) error
+	MoveChildren(ctx context.Context, workspaceID, folderID primitive.ObjectID, parentID *primitive.ObjectID) error
+	DeleteFolder(ctx context.Context, id, workspaceID primitive.ObjectID) error
+}
+
+type FolderService struct {
+	repo     FolderStore
+	formRepo *repository.FormRepository
+}
+
+func NewFolderService(repo FolderStore, formRepo *repository.FormRepository) *FolderService {
+	return &FolderService{
+		repo:     repo,
+		formRepo: formRepo,
+	}
+}
+
+func (s *FolderService) ListFolders(ctx context.Context, workspaceID string) ([]models.Folder, error) {
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	folders, err := s.repo.ListFolders(ctx, workspaceObjectID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list folders", err)
+	}
+	return folders, nil
+}
+
+// CreateFolder creates a folder of workspaceID. An empty parentID creates it at
+// the top level.
+func (s *FolderService) CreateFolder(ctx context.Context, workspaceID, name, parentID string) (*models.Folder, error) {
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	now := time.Now()
+	folder := &models.Folder{
+		WorkspaceID: workspaceObjectID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.applyChanges(ctx, folder, name, parentID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateFolder(ctx, folder); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create folder", err)
+	}
+	return folder, nil
+}
+
+// UpdateFolder renames a folder and moves it under parentID, or to the top
+// level if parentID is empty.
+func (s *FolderService) UpdateFolder(ctx context.Context, id, workspaceID, name, parentID string) (*models.Folder, error) {
+	folder, err := s.loadFolder(ctx, id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyChanges(ctx, folder, name, parentID); err != nil {
+		return nil, err
+	}
+	folder.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateFolder(ctx, folder); err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return nil, apperrors.NewNotFoundError("folder not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to update folder", err)
+	}
+	return folder, nil
+}
+
+// DeleteFolder removes a folder. Its subfolders and forms move to the
+// parent of the folder.
+func (s *FolderService) DeleteFolder(ctx context.Context, id, workspaceID string) error {
+	folder, err := s.loadFolder(ctx, id, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.formRepo.MoveFolderForms(ctx, folder.WorkspaceID, folder.ID, folder.ParentID); err != nil {
+		return apperrors.NewInternalServerError("failed to move forms out of folder", err)
+	}
+	if err := s.repo.MoveChildren(ctx, folder.WorkspaceID, folder.ID, folder.ParentID); err != nil {
+		return apperrors.NewInternalServerError("failed to move subfolders", err)
+	}
+	if err := s.repo.DeleteFolder(ctx, folder.ID, folder.WorkspaceID); err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return apperrors.NewNotFoundError("folder not found")
+		}
+		return apperrors.NewInternalServerError("failed to delete folder", err)
+	}
+	return nil
+}
+
+// MoveForm puts a form of workspaceID into a folder, an empty folderID moves it
+// out of any folder.
+func (s *FolderService) MoveForm(ctx context.Context, formID, workspaceID, folderID string) (*models.Form, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *primitive.ObjectID
+	if folderID != "" {
+		folder, err := s.loadFolder(ctx, folderID, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		target = &folder.ID
+	}
+
+	if err := s.formRepo.SetFolder(ctx, form.ID, form.WorkspaceID, target); err != nil {
+		return nil, formWriteError("failed to move form", err)
+	}
+	form.FolderID = target
+	return form, nil
+}
+
+// ListTags returns the tags used on the forms of workspaceID.
+func (s *FolderService) ListTags(ctx context.Context, workspaceID string) ([]string, error) {
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	tags, err := s.formRepo.ListTags(ctx, workspaceObjectID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list tags", err)
+	}
+	return tags, nil
+}
+
+func (s *FolderService) applyChanges(ctx context.Context, folder *models.Folder, name, parentID string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return apperrors.NewBadRequestError("folder name is required")
+	}
+	if len([]rune(name)) > maxFolderNameLength {
+		return apperrors.NewBadRequestError("folder name is too long")
+	}
+	folder.Name = name
+
+	if parentID == "" {
+		folder.ParentID = nil
+		return nil
+	}
+	parent, err := s.loadFolder(ctx, parentID, folder.WorkspaceID.Hex())
+	if err != nil {
+		return err
+	}
+	if err := s.checkAncestors(ctx, folder, parent); err != nil {
+		return err
+	}
+	folder.ParentID = &parent.ID
+	return nil
+}
+
+// checkAncestors walks up from parent to make sure folder does not end up
+// inside itself and the tree does not get too deep.
+func (s *FolderService) checkAncestors(ctx context.Context, folder, parent *models.Folder) error {
+	current := parent
+	for depth := 1; ; depth++ {
+		if current.ID == folder.ID {
+			return apperrors.NewBadRequestError("a folder can not be moved into itself or its subfolders")
+		}
+		if depth >= maxFolderDepth {
+			return apperrors.NewBadRequestError("folders can not be nested this deep")
+		}
+		if current.ParentID == nil {
+			return nil
+		}
+
+		next, err := s.repo.FindFolder(ctx, *current.ParentID, folder.WorkspaceID)
+		if err != nil {
+			if errors.Is(err, repository.ErrFolderNotFound) {
+				return nil
+			}
+			return apperrors.NewInternalServerError("failed to load folder", err)
+		}
+		current = next
+	}
+}
+
+func (s *FolderService) loadFolder(ctx context.Context, id, workspaceID string) (*models.Folder, error) {
+	folderID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, apperrors.NewNotFoundError("folder not found")
+	}
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	folder, err := s.repo.FindFolder(ctx, folderID, workspaceObjectID)
+	if err != nil {
+		if errors.Is(err, repository.ErrFolderNotFound) {
+			return nil, apperrors.NewNotFoundError("folder not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to load folder", err)
+	}
+	return folder, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeFolderStore only implements FindFolder, the other methods panic.
+type fakeFolderStore struct {
+	FolderStore
+	folders map[primitive.ObjectID]*models.Folder
+}
+
+func (s *fakeFolderStore) FindFolder(ctx context.Context, id, workspaceID primitive.ObjectID) (*models.Folder, error) {
+	folder, ok := s.folders[id]
+	if !ok || folder.WorkspaceID != workspaceID {
+		return nil, repository.ErrFolderNotFound
+	}
+	return folder, nil
+}
+
+// add stores a chain of n nested folders and returns them, outermost first.
+func (s *fakeFolderStore) add(workspaceID primitive.ObjectID, n int) []*models.Folder {
+	chain := make([]*models.Folder, n)
+	for i := range chain {
+		chain[i] = &models.Folder{ID: primitive.NewObjectID(), WorkspaceID: workspaceID}
+		if i > 0 {
+			chain[i].ParentID = &chain[i-1].ID
+		}
+		s.folders[chain[i].ID] = chain[i]
+	}
+	return chain
+}
+
+func TestFolderCheckAncestors(t *testing.T) {
+	workspaceID := primitive.NewObjectID()
+	store := &fakeFolderStore{folders: map[primitive.ObjectID]*models.Folder{}}
+	s := NewFolderService(store, nil)
+
+	short := store.add(workspaceID, 3)
+	deepest := store.add(workspaceID, maxFolderDepth)
+	allowed := store.add(workspaceID, maxFolderDepth-1)
+	orphan := store.add(workspaceID, 2)
+	delete(store.folders, orphan[0].ID)
+	newFolder := &models.Folder{ID: primitive.NewObjectID(), WorkspaceID: workspaceID}
+
+	tests := []struct {
+		name       string
+		folder     *models.Folder
+		parent     *models.Folder
+		wantStatus int
+	}{
+		{"new folder in a subfolder", newFolder, short[2], 0},
+		{"up to an ancestor", short[2], short[0], 0},
+		{"into itself", short[1], short[1], http.StatusBadRequest},
+		{"into its own subfolder", short[0], short[2], http.StatusBadRequest},
+		{"deepest allowed level", newFolder, allowed[len(allowed)-1], 0},
+		{"too deep", newFolder, deepest[len(deepest)-1], http.StatusBadRequest},
+		{"ancestor was deleted", newFolder, orphan[1], 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.checkAncestors(context.Background(), tt.folder, tt.parent)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("checkAncestors() error = %v", err)
+				}
+				return
+			}
+			assertStatus(t, err, tt.wantStatus)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FormGetter loads a single form, *repository.FormRepository implements it.
+type FormGetter interface {
+	GetForm(id string) (*models.Form, error)
+}
+
+// loadForm loads a form by its hex ID and maps lookup failures to AppErrors.
+func loadForm(formRepo FormGetter, formID string) (*models.Form, error) {
+	if _, err := primitive.ObjectIDFromHex(formID); err != nil {
+		return nil, apperrors.NewNotFoundError("form not found")
+	}
+
+	form, err := formRepo.GetForm(formID)
+	if err != nil {
+		if errors.Is(err, repository.ErrFormNotFound) {
+			return nil, apperrors.NewNotFoundError("form not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to load form", err)
+	}
+	return form, nil
+}
+
+// loadOwnedForm loads a form and makes sure it belongs to workspaceID.
+func loadOwnedForm(formRepo FormGetter, formID, workspaceID string) (*models.Form, error) {
+	form, err := loadForm(formRepo, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	if form.WorkspaceID.Hex() != workspaceID {
+		return nil, apperrors.NewForbiddenError("access to this form is denied")
+	}
+	return form, nil
+}
+
+// loadPublishedForm loads a form that is open to respondents. Drafts are
+// reported as missing so their existence is not revealed.
+func loadPublishedForm(formRepo FormGetter, formID string) (*models.Form, error) {
+	form, err := loadForm(formRepo, formID)
+	if err != nil {
+		return nil, err
+	}
+
+	if form.IsDraft {
+		return nil, apperrors.NewNotFoundError("form not found")
+	}
+	return form, nil
+}
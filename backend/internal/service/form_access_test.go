@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLoadOwnedForm(t *testing.T) {
+	workspaceID := primitive.NewObjectID()
+	deletedAt := time.Now()
+	own := &models.Form{ID: primitive.NewObjectID(), WorkspaceID: workspaceID}
+	other := &models.Form{ID: primitive.NewObjectID(), WorkspaceID: primitive.NewObjectID()}
+	trashed := &models.Form{ID: primitive.NewObjectID(), WorkspaceID: workspaceID, DeletedAt: &deletedAt}
+	forms := fakeFormGetter{}
+	for _, form := range []*models.Form{own, other, trashed} {
+		forms[form.ID.Hex()] = form
+	}
+
+	tests := []struct {
+		name       string
+		forms      FormGetter
+		formID     string
+		wantStatus int
+	}{
+		{name: "own form", forms: forms, formID: own.ID.Hex()},
+		{name: "form of another workspace", forms: forms, formID: other.ID.Hex(), wantStatus: http.StatusForbidden},
+		{name: "missing form", forms: forms, formID: primitive.NewObjectID().Hex(), wantStatus: http.StatusNotFound},
+		{name: "form in the trash", forms: forms, formID: trashed.ID.Hex(), wantStatus: http.StatusNotFound},
+		{name: "invalid ID", forms: forms, formID: "nope", wantStatus: http.StatusNotFound},
+		{name: "storage failure", forms: failingFormGetter{}, formID: own.ID.Hex(), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form, err := loadOwnedForm(tt.forms, tt.formID, workspaceID.Hex())
+			if tt.wantStatus != 0 {
+				assertStatus(t, err, tt.wantStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadOwnedForm() error = %v", err)
+			}
+			if form.ID != own.ID {
+				t.Errorf("loaded form %s, want %s", form.ID.Hex(), own.ID.Hex())
+			}
+		})
+	}
+}
+
+func TestLoadPublishedForm(t *testing.T) {
+	published := &models.Form{ID: primitive.NewObjectID()}
+	draft := &models.Form{ID: primitive.NewObjectID(), IsDraft: true}
+	forms := fakeFormGetter{published.ID.Hex(): published, draft.ID.Hex(): draft}
+
+	if _, err := loadPublishedForm(forms, published.ID.Hex()); err != nil {
+		t.Errorf("loadPublishedForm() error = %v for a published form", err)
+	}
+	_, err := loadPublishedForm(forms, draft.ID.Hex())
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+type failingFormGetter struct{}
+
+func (failingFormGetter) GetForm(id string) (*models.Form, error) {
+	return nil, errors.New("connection refused")
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	FormSortCreated   = "created"
+	FormSortUpdated   = "updated"
+	FormSortResponses = "responses"
+
+	FormStatusDraft     = "draft"
+	FormStatusPublished = "published"
+
+	FormFolderRoot = "root"
+
+	defaultFormPageSize = 20
+	maxFormPageSize     = 100
+)
+
+// formSortFields maps the sort options to the stored fields.
+var formSortFields = map[string]string{
+	FormSortCreated:   "createdAt",
+	FormSortUpdated:   "updatedAt",
+	FormSortResponses: "responseCount",
+}
+
+// FormListParams are the options an owner can use to search and page
+// through their forms.
+type FormListParams struct {
+	Search    string
+	Status    string // draft, published or empty for both
+	Theme     string
+	Folder    string // folder ID, "root" for forms outside of folders
+	Tag       string
+	Sort      string
+	Ascending bool
+	Cursor    string
+	Limit     int
+}
+
+// SearchForms returns a page of the forms of workspaceID. Search matches the
+// form name and question texts, case insensitive.
+func (s *FormService) SearchForms(ctx context.Context, workspaceID string, params FormListParams) (*models.FormPage, error) {
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	sort := params.Sort
+	if sort == "" {
+		sort = FormSortCreated
+	}
+	sortField, ok := formSortFields[sort]
+	if !ok {
+		return nil, apperrors.NewBadRequestError("sort must be one of created, updated, responses")
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultFormPageSize
+	}
+	if limit > maxFormPageSize {
+		limit = maxFormPageSize
+	}
+
+	query := repository.FormQuery{
+		WorkspaceID: workspaceObjectID,
+		Search:      strings.TrimSpace(params.Search),
+		Theme:       params.Theme,
+		Tag:         strings.TrimSpace(params.Tag),
+		SortField:   sortField,
+		Ascending:   params.Ascending,
+		// Fetch one extra document to know whether there is a next page
+		Limit: limit + 1,
+	}
+	switch params.Status {
+	case "":
+	case FormStatusDraft, FormStatusPublished:
+		isDraft := params.Status == FormStatusDraft
+		query.IsDraft = &isDraft
+	default:
+		return nil, apperrors.NewBadRequestError("status must be draft or published")
+	}
+	switch params.Folder {
+	case "":
+	case FormFolderRoot:
+		query.InRoot = true
+	default:
+		folderID, err := primitive.ObjectIDFromHex(params.Folder)
+		if err != nil {
+			return nil, apperrors.NewBadRequestError("invalid folder")
+		}
+		query.FolderID = &folderID
+	}
+	if params.Cursor != "" {
+		cursor, err := decodeFormCursor(params.Cursor, sort)
+		if err != nil {
+			return nil, apperrors.NewBadRequestError("invalid cursor")
+		}
+		query.After = cursor
+	}
+
+	forms, err := s.formRepo.FindForms(ctx, query)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list forms", err)
+	}
+
+	page := &models.FormPage{Forms: forms}
+	if len(forms) > limit {
+		page.Forms = forms[:limit]
+		page.NextCursor = encodeFormCursor(&page.Forms[limit-1], sort)
+	}
+	return page, nil
+}
+
+// encodeFormCursor stores the sort option with the position, so a cursor
+// can not be used with a different sort.
+func encodeFormCursor(form *models.Form, sort string) string {
+	var value int64
+	switch sort {
+	case FormSortCreated:
+		value = form.CreatedAt.UnixMilli()
+	case FormSortUpdated:
+		value = form.UpdatedAt.UnixMilli()
+	case FormSortResponses:
+		value = form.ResponseCount
+	}
+	raw := fmt.Sprintf("%s:%d:%s", sort, value, form.ID.Hex())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFormCursor(cursor, sort string) (*repository.FormCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || parts[0] != sort {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	value, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := primitive.ObjectIDFromHex(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	if sort == FormSortResponses {
+		return &repository.FormCursor{Value: value, ID: id}, nil
+	}
+	return &repository.FormCursor{Value: time.UnixMilli(value), ID: id}, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFormCursorRoundTrip(t *testing.T) {
+	form := &models.Form{
+		ID:            primitive.NewObjectID(),
+		CreatedAt:     time.UnixMilli(1700000000123),
+		UpdatedAt:     time.UnixMilli(1700000999456),
+		ResponseCount: 42,
+	}
+
+	tests := []struct {
+		sort string
+		want interface{}
+	}{
+		{FormSortCreated, form.CreatedAt},
+		{FormSortUpdated, form.UpdatedAt},
+		{FormSortResponses, int64(42)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			cursor, err := decodeFormCursor(encodeFormCursor(form, tt.sort), tt.sort)
+			if err != nil {
+				t.Fatalf("decodeFormCursor() error = %v", err)
+			}
+			if cursor.ID != form.ID {
+				t.Errorf("ID = %s, want %s", cursor.ID.Hex(), form.ID.Hex())
+			}
+			switch want := tt.want.(type) {
+			case time.Time:
+				if got, ok := cursor.Value.(time.Time); !ok || !got.Equal(want) {
+					t.Errorf("Value = %v, want %v", cursor.Value, want)
+				}
+			default:
+				if cursor.Value != want {
+					t.Errorf("Value = %v (%T), want %v (%T)", cursor.Value, cursor.Value, want, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeFormCursorInvalid(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"other sort", encode(FormSortUpdated + ":1700000000123:" + id)},
+		{"missing part", encode(FormSortCreated + ":1700000000123")},
+		{"extra part", encode(FormSortCreated + ":1:" + id + ":x")},
+		{"bad value", encode(FormSortCreated + ":abc:" + id)},
+		{"bad ID", encode(FormSortCreated + ":1700000000123:xyz")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeFormCursor(tt.cursor, FormSortCreated); err == nil {
+				t.Errorf("decodeFormCursor(%q) error = nil, want an error", tt.cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+)
+
+// publishRevision snapshots a live form. A new revision is only created
+// when the content differs from the revision that is already live. form
+// must hold the version it was just saved with.
+func (s *FormService) publishRevision(ctx context.Context, form *models.Form) error {
+	if form.IsDraft {
+		return nil
+	}
+
+	snapshot := models.NewFormSnapshot(form)
+	if form.Revision > 0 {
+		current, err := s.revisionRepo.GetRevision(ctx, form.ID, form.Revision)
+		if err != nil && !errors.Is(err, repository.ErrRevisionNotFound) {
+			return err
+		}
+		if current != nil && sameJSON(current.Snapshot, snapshot) {
+			return nil
+		}
+	}
+
+	revision := &models.FormRevision{
+		FormID:      form.ID,
+		WorkspaceID: form.WorkspaceID,
+		Snapshot:    snapshot,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.revisionRepo.CreateRevision(ctx, revision); err != nil {
+		return err
+	}
+	if err := s.formRepo.SetRevision(form.ID, revision.Number, form.Version); err != nil {
+		if errors.Is(err, repository.ErrFormVersionConflict) {
+			// The form was written again after this save. The revision
+			// stays in the history, the later write decides what is live.
+			return nil
+		}
+		return err
+	}
+	form.Revision = revision.Number
+	form.Version++
+	return nil
+}
+
+func (s *FormService) ListRevisions(ctx context.Context, formID, workspaceID string) ([]models.FormRevision, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisionRepo.ListRevisions(ctx, form.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list revisions", err)
+	}
+	return revisions, nil
+}
+
+func (s *FormService) GetRevision(ctx context.Context, formID, workspaceID string, number int) (*models.FormRevision, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return s.loadRevision(ctx, form, number)
+}
+
+// DiffRevisions compares revision from with revision to.
+func (s *FormService) DiffRevisions(ctx context.Context, formID, workspaceID string, from, to int) (*models.RevisionDiff, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := s.loadRevision(ctx, form, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.loadRevision(ctx, form, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffSnapshots(before.Snapshot, after.Snapshot)
+	diff.From = from
+	diff.To = to
+	return diff, nil
+}
+
+// RollbackRevision restores the content of an earlier revision. A published
+// form gets a new revision with that content, history is never rewritten.
+func (s *FormService) RollbackRevision(ctx context.Context, formID, workspaceID string, number int) (*models.Form, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.loadRevision(ctx, form, number)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.Snapshot.Apply(form)
+	if err := s.formRepo.UpdateForm(form); err != nil {
+		return nil, formWriteError("failed to roll back form", err)
+	}
+	if err := s.publishRevision(ctx, form); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to publish revision", err)
+	}
+	return form, nil
+}
+
+func (s *FormService) loadRevision(ctx context.Context, form *models.Form, number int) (*models.FormRevision, error) {
+	revision, err := s.revisionRepo.GetRevision(ctx, form.ID, number)
+	if err != nil {
+		if errors.Is(err, repository.ErrRevisionNotFound) {
+			return nil, apperrors.NewNotFoundError("revision " + strconv.Itoa(number) + " not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to load revision", err)
+	}
+	return revision, nil
+}
+
+// diffSnapshots reports changed form fields by their JSON names and matches
+// questions by ID.
+func diffSnapshots(before, after models.FormSnapshot) *models.RevisionDiff {
+	diff := &models.RevisionDiff{
+		Fields:           changedFields(before, after, "questions"),
+		AddedQuestions:   []models.Question{},
+		RemovedQuestions: []models.Question{},
+		ChangedQuestions: []models.QuestionChange{},
+	}
+
+	old := make(map[int]models.Question, len(before.Questions))
+	for _, q := range before.Questions {
+		old[q.ID] = q
+	}
+	seen := make(map[int]bool, len(after.Questions))
+	for _, q := range after.Questions {
+		seen[q.ID] = true
+		prev, ok := old[q.ID]
+		if !ok {
+			diff.AddedQuestions = append(diff.AddedQuestions, q)
+			continue
+		}
+		if fields := changedFields(prev, q); len(fields) > 0 {
+			diff.ChangedQuestions = append(diff.ChangedQuestions, models.QuestionChange{
+				QuestionID: q.ID,
+				Fields:     fields,
+				Before:     prev,
+				After:      q,
+			})
+		}
+	}
+	for _, q := range before.Questions {
+		if !seen[q.ID] {
+			diff.RemovedQuestions = append(diff.RemovedQuestions, q)
+		}
+	}
+
+	// Reordering is a change as well, it affects the first question
+	if len(diff.AddedQuestions) == 0 && len(diff.RemovedQuestions) == 0 && !sameQuestionOrder(before, after) {
+		diff.Fields = append(diff.Fields, "questionOrder")
+	}
+	return diff
+}
+
+// changedFields compares the JSON representations of a and b field by field.
+func changedFields(a, b interface{}, skip ...string) []string {
+	fieldsA, fieldsB := jsonFields(a), jsonFields(b)
+
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	names := make(map[string]bool, len(fieldsA)+len(fieldsB))
+	for name := range fieldsA {
+		names[name] = true
+	}
+	for name := range fieldsB {
+		names[name] = true
+	}
+
+	changed := []string{}
+	for name := range names {
+		if !skipped[name] && !bytes.Equal(fieldsA[name], fieldsB[name]) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func jsonFields(v interface{}) map[string]json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	if data, err := json.Marshal(v); err == nil {
+		_ = json.Unmarshal(data, &fields)
+	}
+	return fields
+}
+
+func sameQuestionOrder(a, b models.FormSnapshot) bool {
+	if len(a.Questions) != len(b.Questions) {
+		return false
+	}
+	for i := range a.Questions {
+		if a.Questions[i].ID != b.Questions[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+func sameJSON(a, b interface{}) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	q1 := models.Question{ID: 1, Type: "input", Question: "Name?"}
+	q2 := models.Question{ID: 2, Type: "input", Question: "Email?"}
+	q3 := models.Question{ID: 3, Type: "rating", Question: "Score?"}
+	q2Changed := q2
+	q2Changed.Question = "Work email?"
+	q2Changed.Placeholder = "ann@example.com"
+
+	snapshot := func(name string, questions ...models.Question) models.FormSnapshot {
+		return models.FormSnapshot{Name: name, Theme: "light", Questions: questions}
+	}
+
+	tests := []struct {
+		name        string
+		before      models.FormSnapshot
+		after       models.FormSnapshot
+		wantFields  []string
+		wantAdded   []int
+		wantRemoved []int
+		wantChanged map[int][]string
+	}{
+		{
+			name:   "identical",
+			before: snapshot("Survey", q1, q2),
+			after:  snapshot("Survey", q1, q2),
+		},
+		{
+			name:       "form fields",
+			before:     snapshot("Survey", q1),
+			after:      models.FormSnapshot{Name: "Poll", Theme: "dark", Questions: []models.Question{q1}},
+			wantFields: []string{"name", "theme"},
+		},
+		{
+			name:        "added and removed questions",
+			before:      snapshot("Survey", q1, q2),
+			after:       snapshot("Survey", q1, q3),
+			wantAdded:   []int{3},
+			wantRemoved: []int{2},
+		},
+		{
+			name:        "changed question",
+			before:      snapshot("Survey", q1, q2),
+			after:       snapshot("Survey", q1, q2Changed),
+			wantChanged: map[int][]string{2: {"placeholder", "question"}},
+		},
+		{
+			name:       "reordered questions",
+			before:     snapshot("Survey", q1, q2),
+			after:      snapshot("Survey", q2, q1),
+			wantFields: []string{"questionOrder"},
+		},
+		{
+			name:      "reordering is not reported next to added questions",
+			before:    snapshot("Survey", q1, q2),
+			after:     snapshot("Survey", q2, q1, q3),
+			wantAdded: []int{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffSnapshots(tt.before, tt.after)
+
+			if !reflect.DeepEqual(diff.Fields, nonNil(tt.wantFields)) {
+				t.Errorf("Fields = %q, want %q", diff.Fields, tt.wantFields)
+			}
+			if got := questionIDs(diff.AddedQuestions); !reflect.DeepEqual(got, tt.wantAdded) {
+				t.Errorf("AddedQuestions = %v, want %v", got, tt.wantAdded)
+			}
+			if got := questionIDs(diff.RemovedQuestions); !reflect.DeepEqual(got, tt.wantRemoved) {
+				t.Errorf("RemovedQuestions = %v, want %v", got, tt.wantRemoved)
+			}
+			changed := map[int][]string{}
+			for _, c := range diff.ChangedQuestions {
+				changed[c.QuestionID] = c.Fields
+			}
+			if tt.wantChanged == nil {
+				tt.wantChanged = map[int][]string{}
+			}
+			if !reflect.DeepEqual(changed, tt.wantChanged) {
+				t.Errorf("ChangedQuestions = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b interface{}
+		skip []string
+		want []string
+	}{
+		{
+			name: "equal",
+			a:    models.Option{ID: 1, Text: "Yes"},
+			b:    models.Option{ID: 1, Text: "Yes"},
+			want: []string{},
+		},
+		{
+			name: "sorted by name",
+			a:    models.Option{ID: 1, Text: "Yes", Icon: "a"},
+			b:    models.Option{ID: 2, Text: "Yes", Icon: "b"},
+			want: []string{"icon", "id"},
+		},
+		{
+			name: "skipped field",
+			a:    models.Option{ID: 1, Text: "Yes"},
+			b:    models.Option{ID: 2, Text: "No"},
+			skip: []string{"text"},
+			want: []string{"id"},
+		},
+		{
+			name: "field omitted on one side",
+			a:    models.Question{ID: 1, Placeholder: "Name"},
+			b:    models.Question{ID: 1},
+			want: []string{"placeholder"},
+		},
+		{
+			name: "nested values",
+			a:    models.Question{ID: 1, NextQuestion: models.NextQuestion{Default: 2}},
+			b:    models.Question{ID: 1, NextQuestion: models.NextQuestion{Default: 3}},
+			want: []string{"nextQuestion"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changedFields(tt.a, tt.b, tt.skip...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changedFields() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameQuestionOrder(t *testing.T) {
+	snapshot := func(ids ...int) models.FormSnapshot {
+		var s models.FormSnapshot
+		for _, id := range ids {
+			s.Questions = append(s.Questions, models.Question{ID: id})
+		}
+		return s
+	}
+
+	tests := []struct {
+		name string
+		a, b models.FormSnapshot
+		want bool
+	}{
+		{"both empty", snapshot(), snapshot(), true},
+		{"same order", snapshot(1, 2, 3), snapshot(1, 2, 3), true},
+		{"swapped", snapshot(1, 2, 3), snapshot(2, 1, 3), false},
+		{"different length", snapshot(1, 2), snapshot(1, 2, 3), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameQuestionOrder(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameQuestionOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishRevision(t *testing.T) {
+	liveForm := func() *models.Form {
+		return &models.Form{
+			ID:          primitive.NewObjectID(),
+			WorkspaceID: primitive.NewObjectID(),
+			Name:        "Survey",
+			Questions:   []models.Question{{ID: 1, Type: "input", Question: "Name?"}},
+			Version:     3,
+		}
+	}
+
+	tests := []struct {
+		name string
+		// prepare changes the form after a first revision was published
+		prepare      func(form *models.Form)
+		conflict     bool
+		wantCreated  int
+		wantRevision int
+		wantVersion  int
+	}{
+		{
+			name:         "unchanged content",
+			prepare:      func(form *models.Form) { form.Version++ },
+			wantCreated:  1,
+			wantRevision: 1,
+			wantVersion:  5,
+		},
+		{
+			name:         "changes outside of the snapshot",
+			prepare:      func(form *models.Form) { form.Tags = []string{"hr"}; form.Version++ },
+			wantCreated:  1,
+			wantRevision: 1,
+			wantVersion:  5,
+		},
+		{
+			name:         "changed content",
+			prepare:      func(form *models.Form) { form.Name = "Poll"; form.Version++ },
+			wantCreated:  2,
+			wantRevision: 2,
+			wantVersion:  6,
+		},
+		{
+			name:         "unpublished",
+			prepare:      func(form *models.Form) { form.Name = "Poll"; form.IsDraft = true; form.Version++ },
+			wantCreated:  1,
+			wantRevision: 1,
+			wantVersion:  5,
+		},
+		{
+			name:         "written again in the meantime",
+			prepare:      func(form *models.Form) { form.Name = "Poll"; form.Version++ },
+			conflict:     true,
+			wantCreated:  2,
+			wantRevision: 1,
+			wantVersion:  5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := liveForm()
+			forms := &fakeRevisionFormStore{versions: map[primitive.ObjectID]int{form.ID: form.Version}}
+			revisions := &fakeRevisionStore{}
+			s := NewFormService(forms, revisions, nil)
+			ctx := context.Background()
+
+			if err := s.publishRevision(ctx, form); err != nil {
+				t.Fatalf("first publishRevision() error = %v", err)
+			}
+			if form.Revision != 1 || form.Version != 4 {
+				t.Fatalf("after first publish revision = %d, version = %d, want 1 and 4", form.Revision, form.Version)
+			}
+
+			tt.prepare(form)
+			forms.versions[form.ID] = form.Version
+			if tt.conflict {
+				forms.versions[form.ID]++
+			}
+			if err := s.publishRevision(ctx, form); err != nil {
+				t.Fatalf("publishRevision() error = %v", err)
+			}
+
+			if len(revisions.revisions) != tt.wantCreated {
+				t.Errorf("created %d revisions, want %d", len(revisions.revisions), tt.wantCreated)
+			}
+			if form.Revision != tt.wantRevision || form.Version != tt.wantVersion {
+				t.Errorf("revision = %d, version = %d, want %d and %d", form.Revision, form.Version, tt.wantRevision, tt.wantVersion)
+			}
+			if forms.live[form.ID] != tt.wantRevision {
+				t.Errorf("stored revision = %d, want %d", forms.live[form.ID], tt.wantRevision)
+			}
+		})
+	}
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func questionIDs(questions []models.Question) []int {
+	var ids []int
+	for _, q := range questions {
+		ids = append(ids, q.ID)
+	}
+	return ids
+}
+
+// fakeRevisionFormStore only tracks the versions and live revisions of forms.
+type fakeRevisionFormStore struct {
+	FormStore
+	versions map[primitive.ObjectID]int
+	live     map[primitive.ObjectID]int
+}
+
+func (f *fakeRevisionFormStore) CreateForm(form *models.Form) error {
+	form.ID = primitive.NewObjectID()
+	if f.versions == nil {
+		f.versions = map[primitive.ObjectID]int{}
+	}
+	f.versions[form.ID] = form.Version
+	return nil
+}
+
+func (f *fakeRevisionFormStore) SetRevision(formID primitive.ObjectID, number, version int) error {
+	if f.versions[formID] != version {
+		return repository.ErrFormVersionConflict
+	}
+	if f.live == nil {
+		f.live = map[primitive.ObjectID]int{}
+	}
+	f.live[formID] = number
+	f.versions[formID]++
+	return nil
+}
+
+type fakeRevisionStore struct {
+	revisions []models.FormRevision
+}
+
+func (f *fakeRevisionStore) CreateRevision(ctx context.Context, revision *models.FormRevision) error {
+	latest := 0
+	for _, r := range f.revisions {
+		if r.FormID == revision.FormID && r.Number > latest {
+			latest = r.Number
+		}
+	}
+	revision.ID = primitive.NewObjectID()
+	revision.Number = latest + 1
+	f.revisions = append(f.revisions, *revision)
+	return nil
+}
+
+func (f *fakeRevisionStore) GetRevision(ctx context.Context, formID primitive.ObjectID, number int) (*models.FormRevision, error) {
+	for _, r := range f.revisions {
+		if r.FormID == formID && r.Number == number {
+			copied := r
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrRevisionNotFound
+}
+
+func (f *fakeRevisionStore) ListRevisions(ctx context.Context, formID primitive.ObjectID) ([]models.FormRevision, error) {
+	var revisions []models.FormRevision
+	for i := len(f.revisions) - 1; i >= 0; i-- {
+		if f.revisions[i].FormID == formID {
+			revisions = append(revisions, f.revisions[i])
+		}
+	}
+	return revisions, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+)
+
+// FormClosedError is returned when a form does not accept responses.
+// Handlers respond to it with the closed payload, so the frontend can show
+// the reason and the custom message of the owner.
+type FormClosedError struct {
+	Closed models.FormClosed
+}
+
+func (e *FormClosedError) Error() string {
+	return "form is closed: " + e.Closed.Reason
+}
+
+func newFormClosedError(form *models.Form, reason string) *FormClosedError {
+	return &FormClosedError{Closed: models.FormClosed{
+		Reason:   reason,
+		Message:  form.ClosedMessage,
+		OpensAt:  form.OpensAt,
+		ClosesAt: form.ClosesAt,
+	}}
+}
+
+// checkFormOpen enforces the schedule and the response limit of a form.
+func checkFormOpen(form *models.Form, now time.Time) error {
+	if form.OpensAt != nil && now.Before(*form.OpensAt) {
+		return newFormClosedError(form, models.FormClosedReasonNotOpen)
+	}
+	if form.ClosesAt != nil && !now.Before(*form.ClosesAt) {
+		return newFormClosedError(form, models.FormClosedReasonClosed)
+	}
+	if form.MaxResponses > 0 && form.ResponseCount >= form.MaxResponses {
+		return newFormClosedError(form, models.FormClosedReasonResponseLimit)
+	}
+	return nil
+}
+
+func validateSchedule(form *models.Form) error {
+	if form.OpensAt != nil && form.ClosesAt != nil && !form.ClosesAt.After(*form.OpensAt) {
+		return apperrors.NewBadRequestError("closing time must be after opening time")
+	}
+	if form.MaxResponses < 0 {
+		return apperrors.NewBadRequestError("maximum number of responses can not be negative")
+	}
+	if len(form.ClosedMessage) > 1000 {
+		return apperrors.NewBadRequestError("closed message is too long")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+)
+
+func TestCheckFormOpen(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name string
+		form models.Form
+		// want is the reason the form is closed, empty if it is open
+		want string
+	}{
+		{name: "no schedule", form: models.Form{}},
+		{name: "before opening", form: models.Form{OpensAt: at(time.Second)}, want: models.FormClosedReasonNotOpen},
+		{name: "at opening", form: models.Form{OpensAt: at(0)}},
+		{name: "before closing", form: models.Form{ClosesAt: at(time.Second)}},
+		{name: "at closing", form: models.Form{ClosesAt: at(0)}, want: models.FormClosedReasonClosed},
+		{name: "after closing", form: models.Form{ClosesAt: at(-time.Second)}, want: models.FormClosedReasonClosed},
+		{name: "within the schedule", form: models.Form{OpensAt: at(-time.Hour), ClosesAt: at(time.Hour)}},
+		{name: "below the response limit", form: models.Form{MaxResponses: 10, ResponseCount: 9}},
+		{name: "at the response limit", form: models.Form{MaxResponses: 10, ResponseCount: 10}, want: models.FormClosedReasonResponseLimit},
+		{name: "over the response limit", form: models.Form{MaxResponses: 10, ResponseCount: 12}, want: models.FormClosedReasonResponseLimit},
+		{name: "no response limit", form: models.Form{ResponseCount: 1000}},
+		{
+			name: "not open yet and full",
+			form: models.Form{OpensAt: at(time.Hour), MaxResponses: 1, ResponseCount: 1},
+			want: models.FormClosedReasonNotOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.form.ClosedMessage = "See you next year"
+			err := checkFormOpen(&tt.form, now)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("checkFormOpen() error = %v, want the form open", err)
+				}
+				return
+			}
+
+			var closed *FormClosedError
+			if !errors.As(err, &closed) {
+				t.Fatalf("checkFormOpen() error = %v, want *FormClosedError", err)
+			}
+			if closed.Closed.Reason != tt.want {
+				t.Errorf("Reason = %q, want %q", closed.Closed.Reason, tt.want)
+			}
+			if closed.Closed.Message != "See you next year" || closed.Closed.OpensAt != tt.form.OpensAt || closed.Closed.ClosesAt != tt.form.ClosesAt {
+				t.Errorf("Closed = %+v, want the message and schedule of the form", closed.Closed)
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	opensAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := opensAt.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name    string
+		form    models.Form
+		wantErr bool
+	}{
+		{name: "no schedule", form: models.Form{}},
+		{name: "only opening time", form: models.Form{OpensAt: at(0)}},
+		{name: "only closing time", form: models.Form{ClosesAt: at(0)}},
+		{name: "closes after opening", form: models.Form{OpensAt: at(0), ClosesAt: at(time.Second)}},
+		{name: "closes when opening", form: models.Form{OpensAt: at(0), ClosesAt: at(0)}, wantErr: true},
+		{name: "closes before opening", form: models.Form{OpensAt: at(0), ClosesAt: at(-time.Hour)}, wantErr: true},
+		{name: "no response limit", form: models.Form{MaxResponses: 0}},
+		{name: "negative response limit", form: models.Form{MaxResponses: -1}, wantErr: true},
+		{name: "longest closed message", form: models.Form{ClosedMessage: strings.Repeat("x", 1000)}},
+		{name: "closed message too long", form: models.Form{ClosedMessage: strings.Repeat("x", 1001)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedule(&tt.form)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("validateSchedule() error = %v", err)
+				}
+				return
+			}
+			assertStatus(t, err, http.StatusBadRequest)
+		})
+	}
+}
@@ -1,63 +1,249 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/maxzhirnov/formease/internal/models"
 	"github.com/maxzhirnov/formease/internal/repository"
+	"github.com/maxzhirnov/formease/internal/utils"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// previewTokenTTL is how long a generated preview link stays valid.
+const previewTokenTTL = 24 * time.Hour
+
+const (
+	maxFormTags  = 20
+	maxTagLength = 50
+)
+
+// AnyFormVersion skips the version check of UpdateForm, for If-Match: *.
+const AnyFormVersion = -1
+
+// FormStore persists forms, *repository.FormRepository implements it.
+type FormStore interface {
+	FormGetter
+	CreateForm(form *models.Form) error
+	ListForms(workspaceID string) ([]models.Form, error)
+	FindForms(ctx context.Context, q repository.FormQuery) ([]models.Form, error)
+	UpdateForm(form *models.Form) error
+	DeleteForm(formID, workspaceID primitive.ObjectID) error
+	ListDeletedForms(ctx context.Context, workspaceID primitive.ObjectID) ([]models.Form, error)
+	RestoreForm(ctx context.Context, formID, workspaceID primitive.ObjectID) (*models.Form, error)
+	ToggleDraftStatus(formID, workspaceID primitive.ObjectID) error
+	SetRevision(formID primitive.ObjectID, number, version int) error
+}
+
+// RevisionStore persists form revisions,
+// *repository.RevisionRepository implements it.
+type RevisionStore interface {
+	CreateRevision(ctx context.Context, revision *models.FormRevision) error
+	GetRevision(ctx context.Context, formID primitive.ObjectID, number int) (*models.FormRevision, error)
+	ListRevisions(ctx context.Context, formID primitive.ObjectID) ([]models.FormRevision, error)
+}
+
 type FormService struct {
-	formRepo *repository.FormRepository
+	formRepo     FormStore
+	revisionRepo RevisionStore
+	jwtUtil      *utils.JWTUtil
 }
 
-func NewFormService(formRepo *repository.FormRepository) *FormService {
+func NewFormService(formRepo FormStore, revisionRepo RevisionStore, jwtUtil *utils.JWTUtil) *FormService {
 	return &FormService{
-		formRepo: formRepo,
+		formRepo:     formRepo,
+		revisionRepo: revisionRepo,
+		jwtUtil:      jwtUtil,
 	}
 }
 
-func (s *FormService) CreateForm(form *models.Form) error {
-	if err := s.validateForm(form); err != nil {
-		return fmt.Errorf("form validation failed: %w", err)
+// PreviewLink grants temporary access to a draft form.
+type PreviewLink struct {
+	Token     string    `json:"token"`
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CreateForm validates and stores a new form. Non-blocking issues found in
+// the question graph are returned as warnings.
+func (s *FormService) CreateForm(form *models.Form) ([]FormIssue, error) {
+	warnings, err := s.validateForm(form)
+	if err != nil {
+		return nil, fmt.Errorf("form validation failed: %w", err)
 	}
 
-	return s.formRepo.CreateForm(form)
+	// Revisions and versions are managed by the server, new forms are
+	// never in the trash
+	form.Revision = 0
+	form.Version = 1
+	form.ResponseCount = 0
+	form.DeletedAt = nil
+	form.Purging = false
+	form.CreatedAt = time.Now()
+	form.UpdatedAt = form.CreatedAt
+	if err := s.formRepo.CreateForm(form); err != nil {
+		return nil, err
+	}
+	if err := s.publishRevision(context.Background(), form); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to publish revision", err)
+	}
+	return warnings, nil
 }
 
-func (s *FormService) GetForm(id string) (*models.Form, error) {
-	form, err := s.formRepo.GetForm(id)
+// GetPublicForm returns the respondent view of a form. Drafts are only
+// shown with a valid preview token and are reported as missing otherwise.
+// Forms outside of their schedule return a *FormClosedError.
+func (s *FormService) GetPublicForm(id, previewToken string) (*models.PublicForm, error) {
+	form, err := loadForm(s.formRepo, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get form: %w", err)
+		return nil, err
 	}
-	return form, nil
+
+	preview := previewToken != "" && s.jwtUtil.ValidatePreviewToken(previewToken, form.ID.Hex()) == nil
+	if form.IsDraft && !preview {
+		return nil, apperrors.NewNotFoundError("form not found")
+	}
+	// Owners can preview a form outside of its schedule
+	if !preview {
+		if err := checkFormOpen(form, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return models.NewPublicForm(form), nil
+}
+
+// CreatePreviewLink issues a short-lived preview token for a form owned by workspaceID.
+func (s *FormService) CreatePreviewLink(id, workspaceID string) (*PreviewLink, error) {
+	form, err := loadOwnedForm(s.formRepo, id, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := s.jwtUtil.GeneratePreviewToken(form.ID.Hex(), previewTokenTTL)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate preview token", err)
+	}
+	return &PreviewLink{
+		Token:     token,
+		Path:      fmt.Sprintf("/form/%s?previewToken=%s", form.ID.Hex(), url.QueryEscape(token)),
+		ExpiresAt: expiresAt,
+	}, nil
 }
 
-func (s *FormService) ListForms(userID string) ([]models.Form, error) {
-	forms, err := s.formRepo.ListForms(userID)
+// GetOwnedForm returns a form for editing. Missing forms are reported as
+// not found, forms of other workspaces as forbidden.
+func (s *FormService) GetOwnedForm(id, workspaceID string) (*models.Form, error) {
+	return loadOwnedForm(s.formRepo, id, workspaceID)
+}
+
+func (s *FormService) ListForms(workspaceID string) ([]models.Form, error) {
+	forms, err := s.formRepo.ListForms(workspaceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list forms: %w", err)
 	}
 	return forms, nil
 }
 
-func (s *FormService) UpdateForm(form *models.Form) error {
-	if err := s.validateForm(form); err != nil {
-		return fmt.Errorf("form validation failed: %w", err)
+// UpdateForm validates and stores a form owned by workspaceID. The update is
+// only applied if the stored form still has the given version, otherwise
+// someone else saved it in the meantime. Non-blocking issues found in the
+// question graph are returned as warnings.
+func (s *FormService) UpdateForm(form *models.Form, workspaceID string, version int) ([]FormIssue, error) {
+	existing, err := loadOwnedForm(s.formRepo, form.ID.Hex(), workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if version != AnyFormVersion && version != existing.Version {
+		return nil, apperrors.NewPreconditionFailedError("form was modified by someone else, reload it and try again")
+	}
+	// Ownership, revisions, versions and the folder can not be changed through an update
+	form.WorkspaceID = existing.WorkspaceID
+	form.Revision = existing.Revision
+	form.Version = existing.Version
+	form.ResponseCount = existing.ResponseCount
+	form.CreatedAt = existing.CreatedAt
+	form.FolderID = existing.FolderID
+
+	warnings, err := s.validateForm(form)
+	if err != nil {
+		return nil, fmt.Errorf("form validation failed: %w", err)
 	}
 
-	return s.formRepo.UpdateForm(form)
+	if err := s.formRepo.UpdateForm(form); err != nil {
+		return nil, formWriteError("failed to update form", err)
+	}
+	// Saving a published form publishes the changes right away
+	if err := s.publishRevision(context.Background(), form); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to publish revision", err)
+	}
+	return warnings, nil
 }
 
-func (s *FormService) DeleteForm(id string) error {
-	return s.formRepo.DeleteForm(id)
+// DeleteForm moves a form owned by workspaceID to the trash.
+func (s *FormService) DeleteForm(id, workspaceID string) error {
+	form, err := loadOwnedForm(s.formRepo, id, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.formRepo.DeleteForm(form.ID, form.WorkspaceID); err != nil {
+		return formWriteError("failed to delete form", err)
+	}
+	return nil
 }
 
-func (s *FormService) validateForm(form *models.Form) error {
+// ListTrash returns the deleted forms of workspaceID that were not purged yet.
+func (s *FormService) ListTrash(workspaceID string) ([]models.Form, error) {
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	forms, err := s.formRepo.ListDeletedForms(context.Background(), workspaceObjectID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list trash", err)
+	}
+	return forms, nil
+}
+
+// RestoreForm takes a form of workspaceID out of the trash.
+func (s *FormService) RestoreForm(id, workspaceID string) (*models.Form, error) {
+	formID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, apperrors.NewNotFoundError("form not found in trash")
+	}
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	form, err := s.formRepo.RestoreForm(context.Background(), formID, workspaceObjectID)
+	if err != nil {
+		if errors.Is(err, repository.ErrFormNotFound) {
+			return nil, apperrors.NewNotFoundError("form not found in trash")
+		}
+		return nil, apperrors.NewInternalServerError("failed to restore form", err)
+	}
+	return form, nil
+}
+
+func (s *FormService) validateForm(form *models.Form) ([]FormIssue, error) {
 	if form.Name == "" {
-		return fmt.Errorf("form name is required")
+		return nil, apperrors.NewBadRequestError("form name is required")
 	}
+	if err := validateSchedule(form); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(form.Tags)
+	if err != nil {
+		return nil, err
+	}
+	form.Tags = tags
 
 	// if len(form.Questions) == 0 {
 	// 	return fmt.Errorf("form must have at least one question")
@@ -70,7 +256,37 @@ func (s *FormService) validateForm(form *models.Form) error {
 	// 	}
 	// }
 
-	return nil
+	errs, warnings := splitIssues(ValidateFormGraph(form))
+	if len(errs) > 0 {
+		return nil, &FormValidationError{Issues: errs}
+	}
+
+	return warnings, nil
+}
+
+// normalizeTags trims tags and drops empty and repeated ones.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len([]rune(tag)) > maxTagLength {
+			return nil, apperrors.NewBadRequestError(fmt.Sprintf("tag %q is longer than %d characters", tag, maxTagLength))
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxFormTags {
+		return nil, apperrors.NewBadRequestError(fmt.Sprintf("a form can have at most %d tags", maxFormTags))
+	}
+	return normalized, nil
 }
 
 func (s *FormService) validateQuestion(question models.Question, index int) error {
@@ -98,16 +314,35 @@ func (s *FormService) validateQuestion(question models.Question, index int) erro
 	return nil
 }
 
-func (s *FormService) ToggleDraftStatus(formID string, userID string) error {
-	objectID, err := primitive.ObjectIDFromHex(formID)
+// ToggleDraftStatus publishes or unpublishes a form and returns it with
+// the new status and version.
+func (s *FormService) ToggleDraftStatus(formID string, workspaceID string) (*models.Form, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
 	if err != nil {
-		return fmt.Errorf("invalid form ID: %w", err)
+		return nil, err
 	}
 
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
-	if err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+	if err := s.formRepo.ToggleDraftStatus(form.ID, form.WorkspaceID); err != nil {
+		return nil, formWriteError("failed to update draft status", err)
 	}
+	form.IsDraft = !form.IsDraft
+	form.Version++
 
-	return s.formRepo.ToggleDraftStatus(objectID, userObjectID)
+	if err := s.publishRevision(context.Background(), form); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to publish revision", err)
+	}
+	return form, nil
+}
+
+// formWriteError maps a failed owner-scoped write. The form was checked
+// just before, so not found means it was deleted in the meantime and a
+// version conflict that it was saved by someone else.
+func formWriteError(message string, err error) error {
+	if errors.Is(err, repository.ErrFormNotFound) {
+		return apperrors.NewNotFoundError("form not found")
+	}
+	if errors.Is(err, repository.ErrFormVersionConflict) {
+		return apperrors.NewPreconditionFailedError("form was modified by someone else, reload it and try again")
+	}
+	return apperrors.NewInternalServerError(message, err)
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tooMany := make([]string, maxFormTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag%d", i)
+	}
+
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "nil", tags: nil, want: nil},
+		{name: "trimmed", tags: []string{"  sales ", "hr"}, want: []string{"sales", "hr"}},
+		{name: "duplicates and blanks removed", tags: []string{"a", " ", "a", "b", " a"}, want: []string{"a", "b"}},
+		{name: "case is kept", tags: []string{"Sales", "sales"}, want: []string{"Sales", "sales"}},
+		{name: "only blanks", tags: []string{"", "  "}, want: []string{}},
+		{name: "longest allowed", tags: []string{strings.Repeat("я", maxTagLength)}, want: []string{strings.Repeat("я", maxTagLength)}},
+		{name: "too long", tags: []string{strings.Repeat("x", maxTagLength+1)}, wantErr: true},
+		{name: "too many", tags: tooMany, wantErr: true},
+		{name: "duplicates do not count", tags: append(tooMany[:maxFormTags:maxFormTags], "tag0"), want: tooMany[:maxFormTags]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTags(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeTags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateFormResetsServerFields(t *testing.T) {
+	deletedAt := time.Now().Add(-time.Hour)
+	form := &models.Form{
+		WorkspaceID:   primitive.NewObjectID(),
+		Name:          "Survey",
+		Questions:     []models.Question{{ID: 1, Type: "input", Question: "Name?"}},
+		Revision:      7,
+		Version:       12,
+		ResponseCount: 40,
+		DeletedAt:     &deletedAt,
+		Purging:       true,
+	}
+	forms := &fakeRevisionFormStore{}
+	s := NewFormService(forms, &fakeRevisionStore{}, nil)
+
+	if _, err := s.CreateForm(form); err != nil {
+		t.Fatalf("CreateForm() error = %v", err)
+	}
+	if form.DeletedAt != nil || form.Purging {
+		t.Errorf("new form is in the trash: deletedAt = %v, purging = %v", form.DeletedAt, form.Purging)
+	}
+	if form.ResponseCount != 0 {
+		t.Errorf("ResponseCount = %d, want 0", form.ResponseCount)
+	}
+	if form.Revision != 1 || forms.live[form.ID] != 1 {
+		t.Errorf("revision = %d, stored revision = %d, want 1", form.Revision, forms.live[form.ID])
+	}
+}
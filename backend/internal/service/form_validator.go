@@ -0,0 +1,195 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/maxzhirnov/formease/internal/models"
+)
+
+const (
+	IssueSeverityError   = "error"
+	IssueSeverityWarning = "warning"
+)
+
+// FormIssue is a single problem found in the question graph of a form.
+type FormIssue struct {
+	Severity   string `json:"severity"`
+	Code       string `json:"code"`
+	QuestionID int    `json:"questionId,omitempty"`
+	Message    string `json:"message"`
+}
+
+// FormValidationError is returned when the form has issues with error
+// severity. Handlers respond to it with 422 and the list of issues.
+type FormValidationError struct {
+	Issues []FormIssue
+}
+
+func (e *FormValidationError) Error() string {
+	return fmt.Sprintf("form has %d error(s)", len(e.Issues))
+}
+
+// ValidateFormGraph statically checks the branching of a form: duplicate
+// question IDs, next question references that lead nowhere, cycles,
+// questions that can never be shown and conditions that can never match.
+func ValidateFormGraph(form *models.Form) []FormIssue {
+	var issues []FormIssue
+
+	questions := make(map[int]*models.Question, len(form.Questions))
+	for i := range form.Questions {
+		q := &form.Questions[i]
+		if _, exists := questions[q.ID]; exists {
+			issues = append(issues, FormIssue{
+				Severity:   IssueSeverityError,
+				Code:       "duplicate_question_id",
+				QuestionID: q.ID,
+				Message:    fmt.Sprintf("question ID %d is used more than once", q.ID),
+			})
+			continue
+		}
+		questions[q.ID] = q
+	}
+
+	// Collect outgoing edges and report references to missing questions
+	edges := make(map[int][]int, len(questions))
+	for _, q := range form.Questions {
+		for _, c := range q.NextQuestion.Conditions {
+			if c.NextID == 0 {
+				continue
+			}
+			if _, ok := questions[c.NextID]; !ok {
+				issues = append(issues, FormIssue{
+					Severity:   IssueSeverityError,
+					Code:       "dangling_next_id",
+					QuestionID: q.ID,
+					Message:    fmt.Sprintf("condition %q points to missing question %d", c.Answer, c.NextID),
+				})
+				continue
+			}
+			edges[q.ID] = append(edges[q.ID], c.NextID)
+		}
+
+		if next := q.NextQuestion.Default; next != 0 {
+			if _, ok := questions[next]; !ok {
+				issues = append(issues, FormIssue{
+					Severity:   IssueSeverityError,
+					Code:       "dangling_next_id",
+					QuestionID: q.ID,
+					Message:    fmt.Sprintf("default next question %d does not exist", next),
+				})
+			} else {
+				edges[q.ID] = append(edges[q.ID], next)
+			}
+		}
+
+		issues = append(issues, checkConditionAnswers(q)...)
+	}
+
+	issues = append(issues, findCycles(form, edges)...)
+
+	// Everything not reachable from the first question is never shown
+	if len(form.Questions) > 0 {
+		reachable := make(map[int]bool, len(questions))
+		stack := []int{form.Questions[0].ID}
+		for len(stack) > 0 {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if reachable[id] {
+				continue
+			}
+			reachable[id] = true
+			stack = append(stack, edges[id]...)
+		}
+
+		for _, q := range form.Questions {
+			if !reachable[q.ID] {
+				issues = append(issues, FormIssue{
+					Severity:   IssueSeverityWarning,
+					Code:       "unreachable_question",
+					QuestionID: q.ID,
+					Message:    fmt.Sprintf("question %d can not be reached from the first question", q.ID),
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+func checkConditionAnswers(q models.Question) []FormIssue {
+	if q.Type != "single-choice" && q.Type != "multiple-choice" {
+		return nil
+	}
+
+	options := make(map[string]bool, len(q.Options))
+	for _, o := range q.Options {
+		options[o.Text] = true
+	}
+
+	var issues []FormIssue
+	for _, c := range q.NextQuestion.Conditions {
+		if !options[c.Answer] {
+			issues = append(issues, FormIssue{
+				Severity:   IssueSeverityWarning,
+				Code:       "condition_matches_no_option",
+				QuestionID: q.ID,
+				Message:    fmt.Sprintf("condition answer %q does not match any option", c.Answer),
+			})
+		}
+	}
+	return issues
+}
+
+// findCycles reports every question that closes a loop in the graph.
+func findCycles(form *models.Form, edges map[int][]int) []FormIssue {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+
+	var issues []FormIssue
+	state := make(map[int]int, len(edges))
+	reported := make(map[int]bool)
+
+	var visit func(id int)
+	visit = func(id int) {
+		state[id] = inProgress
+		for _, next := range edges[id] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case inProgress:
+				if !reported[id] {
+					reported[id] = true
+					issues = append(issues, FormIssue{
+						Severity:   IssueSeverityError,
+						Code:       "cycle",
+						QuestionID: id,
+						Message:    fmt.Sprintf("question %d leads back to question %d", id, next),
+					})
+				}
+			}
+		}
+		state[id] = done
+	}
+
+	for _, q := range form.Questions {
+		if state[q.ID] == unvisited {
+			visit(q.ID)
+		}
+	}
+	return issues
+}
+
+// splitIssues separates issues by severity.
+func splitIssues(issues []FormIssue) (errs, warnings []FormIssue) {
+	for _, issue := range issues {
+		if issue.Severity == IssueSeverityError {
+			errs = append(errs, issue)
+		} else {
+			warnings = append(warnings, issue)
+		}
+	}
+	return errs, warnings
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+)
+
+func TestValidateFormGraph(t *testing.T) {
+	next := func(id int) models.NextQuestion { return models.NextQuestion{Default: id} }
+	choice := func(id int, conditions ...models.Condition) models.Question {
+		return models.Question{
+			ID:           id,
+			Type:         "single-choice",
+			Options:      []models.Option{{ID: 1, Text: "Yes"}, {ID: 2, Text: "No"}},
+			NextQuestion: models.NextQuestion{Conditions: conditions},
+		}
+	}
+
+	type issue struct {
+		Code       string
+		QuestionID int
+	}
+	tests := []struct {
+		name      string
+		questions []models.Question
+		want      []issue
+	}{
+		{
+			name: "linear form",
+			questions: []models.Question{
+				{ID: 1, Type: "input", NextQuestion: next(2)},
+				{ID: 2, Type: "input"},
+			},
+		},
+		{
+			name: "branches that join again",
+			questions: []models.Question{
+				choice(1, models.Condition{Answer: "Yes", NextID: 2}, models.Condition{Answer: "No", NextID: 3}),
+				{ID: 2, Type: "input", NextQuestion: next(4)},
+				{ID: 3, Type: "input", NextQuestion: next(4)},
+				{ID: 4, Type: "input"},
+			},
+		},
+		{
+			name: "duplicate question ID",
+			questions: []models.Question{
+				{ID: 1, Type: "input", NextQuestion: next(2)},
+				{ID: 2, Type: "input"},
+				{ID: 2, Type: "input"},
+			},
+			want: []issue{{"duplicate_question_id", 2}},
+		},
+		{
+			name: "dangling default and condition",
+			questions: []models.Question{
+				choice(1, models.Condition{Answer: "Yes", NextID: 7}),
+				{ID: 2, Type: "input", NextQuestion: next(9)},
+			},
+			want: []issue{
+				{"dangling_next_id", 1},
+				{"dangling_next_id", 2},
+				{"unreachable_question", 2},
+			},
+		},
+		{
+			name: "cycle",
+			questions: []models.Question{
+				{ID: 1, Type: "input", NextQuestion: next(2)},
+				{ID: 2, Type: "input", NextQuestion: next(3)},
+				{ID: 3, Type: "input", NextQuestion: next(1)},
+			},
+			want: []issue{{"cycle", 3}},
+		},
+		{
+			name: "unreachable question",
+			questions: []models.Question{
+				{ID: 1, Type: "input"},
+				{ID: 2, Type: "input"},
+			},
+			want: []issue{{"unreachable_question", 2}},
+		},
+		{
+			name: "condition matches no option",
+			questions: []models.Question{
+				choice(1, models.Condition{Answer: "Maybe", NextID: 2}),
+				{ID: 2, Type: "input"},
+			},
+			want: []issue{{"condition_matches_no_option", 1}},
+		},
+		{
+			name: "empty form",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []issue
+			for _, i := range ValidateFormGraph(&models.Form{Questions: tt.questions}) {
+				got = append(got, issue{i.Code, i.QuestionID})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateFormGraph() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitIssues(t *testing.T) {
+	issues := []FormIssue{
+		{Severity: IssueSeverityWarning, Code: "unreachable_question"},
+		{Severity: IssueSeverityError, Code: "cycle"},
+	}
+
+	errs, warnings := splitIssues(issues)
+	if len(errs) != 1 || errs[0].Code != "cycle" {
+		t.Errorf("errors = %+v, want the cycle", errs)
+	}
+	if len(warnings) != 1 || warnings[0].Code != "unreachable_question" {
+		t.Errorf("warnings = %+v, want the unreachable question", warnings)
+	}
+}
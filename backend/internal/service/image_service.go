@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
@@ -44,7 +45,7 @@ func NewImageService(repo repository.ImageRepository, fileStore storage.FileStor
 func (s *ImageService) UploadImage(image *models.Image, file multipart.File) (*models.Image, error) {
 
 	// Проверка квоты пользователя
-	if err := s.checkUserQuota(image.UserID); err != nil {
+	if err := s.checkWorkspaceQuota(image.WorkspaceID); err != nil {
 		return nil, err
 	}
 
@@ -154,10 +155,10 @@ func isValidExtension(ext string) bool {
 }
 
 // Добавим также метод для проверки квоты пользователя (опционально)
-func (s *ImageService) checkUserQuota(userID string) error {
+func (s *ImageService) checkWorkspaceQuota(workspaceID string) error {
 
 	// Получаем количество изображений пользователя
-	count, err := s.repo.CountByUserID(userID)
+	count, err := s.repo.CountByWorkspaceID(workspaceID)
 	if err != nil {
 		logger.Error("Failed to get user images count", zap.Error(err))
 		return err
@@ -166,7 +167,7 @@ func (s *ImageService) checkUserQuota(userID string) error {
 	// Проверяем квоту (например, максимум 100 изображений)
 	if count >= 100 {
 		logger.Error("User quota exceeded",
-			zap.String("userId", userID),
+			zap.String("workspaceId", workspaceID),
 			zap.Int64("imageCount", count))
 		return errors.New("image quota exceeded")
 	}
@@ -174,9 +175,9 @@ func (s *ImageService) checkUserQuota(userID string) error {
 	return nil
 }
 
-func (s *ImageService) FindByUserID(userID string, page, limit int) ([]*models.Image, error) {
+func (s *ImageService) FindByWorkspaceID(workspaceID string, page, limit int) ([]*models.Image, error) {
 
-	images, err := s.repo.FindByUserID(userID, page, limit)
+	images, err := s.repo.FindByWorkspaceID(workspaceID, page, limit)
 	if err != nil {
 		logger.Error("Failed to find images", zap.Error(err))
 		return nil, fmt.Errorf("failed to find images: %w", err)
@@ -185,8 +186,8 @@ func (s *ImageService) FindByUserID(userID string, page, limit int) ([]*models.I
 	return images, nil
 }
 
-func (s *ImageService) CountByUserID(userID string) (int64, error) {
-	return s.repo.CountByUserID(userID)
+func (s *ImageService) CountByWorkspaceID(workspaceID string) (int64, error) {
+	return s.repo.CountByWorkspaceID(workspaceID)
 }
 
 func (s *ImageService) FindByID(imageID string) (*models.Image, error) {
@@ -202,6 +203,24 @@ func (s *ImageService) FindByID(imageID string) (*models.Image, error) {
 	return image, nil
 }
 
+// FindByURLs returns the images of workspaceID referenced by the given URLs.
+func (s *ImageService) FindByURLs(workspaceID string, urls []string) ([]*models.Image, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	images, err := s.repo.FindByURLs(workspaceID, urls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find images: %w", err)
+	}
+	return images, nil
+}
+
+// Open returns the content of a stored image file. The caller closes it.
+func (s *ImageService) Open(filename string) (io.ReadCloser, error) {
+	return s.fileStore.Open(filename)
+}
+
 func (s *ImageService) Delete(imageID string) error {
 	// First, find the image to get its filename
 	image, err := s.FindByID(imageID)
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/notifier"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	digestPeriod        = 24 * time.Hour
+	digestPollInterval  = 10 * time.Minute
+	notificationTimeout = 30 * time.Second
+)
+
+// NotificationStore keeps the notification settings of forms,
+// *repository.NotificationRepository implements it.
+type NotificationStore interface {
+	GetSettings(ctx context.Context, formID primitive.ObjectID) (*models.NotificationSettings, error)
+	SaveSettings(ctx context.Context, settings *models.NotificationSettings) error
+	ClaimDueDigest(ctx context.Context, now time.Time, period time.Duration) (*models.NotificationSettings, error)
+}
+
+// SubmissionCounter counts the submissions of a form,
+// *repository.SubmissionRepository implements it.
+type SubmissionCounter interface {
+	CountSubmissions(ctx context.Context, formID primitive.ObjectID) (int64, error)
+	CountSubmissionsBetween(ctx context.Context, formID primitive.ObjectID, from, to time.Time) (int64, error)
+}
+
+// WorkspaceFinder loads stored workspaces,
+// *repository.WorkspaceRepository implements it.
+type WorkspaceFinder interface {
+	FindWorkspace(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error)
+}
+
+type NotificationService struct {
+	repo     NotificationStore
+	formRepo FormGetter
+	subRepo  SubmissionCounter
+	userRepo repository.UserRepository
+	wsRepo   WorkspaceFinder
+	sender   notifier.Sender
+	appURL   string
+}
+
+// NewNotificationService creates the service. appURL is the address of the
+// frontend used for links in emails, it may be empty.
+func NewNotificationService(
+	repo NotificationStore,
+	formRepo FormGetter,
+	subRepo SubmissionCounter,
+	userRepo repository.UserRepository,
+	wsRepo WorkspaceFinder,
+	sender notifier.Sender,
+	appURL string,
+) *NotificationService {
+	return &NotificationService{
+		repo:     repo,
+		formRepo: formRepo,
+		subRepo:  subRepo,
+		userRepo: userRepo,
+		wsRepo:   wsRepo,
+		sender:   sender,
+		appURL:   strings.TrimRight(appURL, "/"),
+	}
+}
+
+// GetSettings returns the notification settings of a form owned by workspaceID.
+// Forms without saved settings have notifications turned off.
+func (s *NotificationService) GetSettings(ctx context.Context, formID, workspaceID string) (*models.NotificationSettings, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := s.repo.GetSettings(ctx, form.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to load notification settings", err)
+	}
+	if settings == nil {
+		settings = &models.NotificationSettings{FormID: form.ID, WorkspaceID: form.WorkspaceID, Mode: models.NotificationModeOff}
+	}
+	return settings, nil
+}
+
+func (s *NotificationService) UpdateSettings(ctx context.Context, formID, workspaceID, mode string) (*models.NotificationSettings, error) {
+	switch mode {
+	case models.NotificationModeOff, models.NotificationModeImmediate, models.NotificationModeDigest:
+	default:
+		return nil, apperrors.NewBadRequestError("mode must be one of off, immediate, digest")
+	}
+
+	settings, err := s.GetSettings(ctx, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if mode == models.NotificationModeDigest {
+		// The first digest covers the day after it was turned on
+		if settings.Mode != models.NotificationModeDigest || settings.DigestSentAt == nil {
+			settings.DigestSentAt = &now
+		}
+	} else {
+		settings.DigestSentAt = nil
+	}
+	settings.Mode = mode
+	settings.UpdatedAt = now
+
+	if err := s.repo.SaveSettings(ctx, settings); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to save notification settings", err)
+	}
+	return settings, nil
+}
+
+// SubmissionCreated emails the owner right away if the form is set to
+// immediate notifications. The email is sent in the background.
+func (s *NotificationService) SubmissionCreated(ctx context.Context, form *models.Form, sub *models.Submission) {
+	settings, err := s.repo.GetSettings(ctx, form.ID)
+	if err != nil {
+		logger.Error("Failed to load notification settings", zap.String("formId", form.ID.Hex()), zap.Error(err))
+		return
+	}
+	if settings == nil || settings.Mode != models.NotificationModeImmediate {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		defer cancel()
+
+		msg := notifier.Message{
+			Subject: fmt.Sprintf("New response to %q", form.Name),
+			Body:    s.submissionBody(form, sub),
+		}
+		if err := s.sendToOwner(ctx, form, msg); err != nil {
+			logger.Error("Failed to send submission notification",
+				zap.String("submissionId", sub.ID.Hex()),
+				zap.Error(err))
+		}
+	}()
+}
+
+// Run sends daily digests until ctx is cancelled.
+func (s *NotificationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(digestPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sendDueDigests(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *NotificationService) sendDueDigests(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		settings, err := s.repo.ClaimDueDigest(ctx, now, digestPeriod)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error("Failed to claim notification digest", zap.Error(err))
+			}
+			return
+		}
+		if settings == nil {
+			return
+		}
+
+		if err := s.sendDigest(ctx, settings, now); err != nil {
+			// The period is already marked as sent, a failed digest is not retried
+			logger.Error("Failed to send notification digest",
+				zap.String("formId", settings.FormID.Hex()),
+				zap.Error(err))
+		}
+	}
+}
+
+func (s *NotificationService) sendDigest(ctx context.Context, settings *models.NotificationSettings, until time.Time) error {
+	form, err := s.formRepo.GetForm(settings.FormID.Hex())
+	if err != nil {
+		return err
+	}
+
+	count, err := s.subRepo.CountSubmissionsBetween(ctx, form.ID, *settings.DigestSentAt, until)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	total, err := s.subRepo.CountSubmissions(ctx, form.ID)
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Your form %q received %d new response(s) since %s.\n",
+		form.Name, count, settings.DigestSentAt.UTC().Format("2006-01-02 15:04 UTC"))
+	fmt.Fprintf(&body, "Total responses: %d\n", total)
+	s.writeFormLink(&body, form)
+
+	msg := notifier.Message{
+		Subject: fmt.Sprintf("Daily summary for %q: %d new response(s)", form.Name, count),
+		Body:    body.String(),
+	}
+	return s.sendToOwner(ctx, form, msg)
+}
+
+func (s *NotificationService) sendToOwner(ctx context.Context, form *models.Form, msg notifier.Message) error {
+	if s.sender == nil {
+		logger.Info("Email sender is not configured, notification skipped", zap.String("formId", form.ID.Hex()))
+		return nil
+	}
+
+	// Forms of a team workspace notify the user who created the workspace,
+	// forms of a personal workspace are owned by the user directly
+	ownerID := form.WorkspaceID
+	workspace, err := s.wsRepo.FindWorkspace(ctx, form.WorkspaceID)
+	if err == nil {
+		ownerID = workspace.OwnerID
+	} else if !errors.Is(err, repository.ErrWorkspaceNotFound) {
+		return fmt.Errorf("failed to load form workspace: %w", err)
+	}
+
+	owner, err := s.userRepo.FindByID(ctx, ownerID.Hex())
+	if err != nil {
+		return fmt.Errorf("failed to load form owner: %w", err)
+	}
+	msg.To = []string{owner.Email}
+	return s.sender.Send(ctx, msg)
+}
+
+func (s *NotificationService) submissionBody(form *models.Form, sub *models.Submission) string {
+	questions := make(map[int]*models.Question, len(form.Questions))
+	for i := range form.Questions {
+		questions[form.Questions[i].ID] = &form.Questions[i]
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Your form %q received a new response at %s.\n\n",
+		form.Name, sub.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	for _, a := range sub.Answers {
+		q, ok := questions[a.QuestionID]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&body, "%s\n  %s\n\n", q.Question, formatAnswer(q, a))
+	}
+	s.writeFormLink(&body, form)
+	return body.String()
+}
+
+func (s *NotificationService) writeFormLink(body *strings.Builder, form *models.Form) {
+	if s.appURL != "" {
+		fmt.Fprintf(body, "\nOpen the form: %s/app/form/%s/edit\n", s.appURL, form.ID.Hex())
+	}
+}
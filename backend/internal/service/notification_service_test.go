@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/notifier"
+	"github.com/maxzhirnov/formease/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSubmissionCreatedNotifiesOwner(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		workspace bool
+		wantTo    string
+	}{
+		{name: "personal workspace", mode: models.NotificationModeImmediate, wantTo: "ann@example.com"},
+		{name: "team workspace", mode: models.NotificationModeImmediate, workspace: true, wantTo: "owner@example.com"},
+		{name: "digest mode", mode: models.NotificationModeDigest},
+		{name: "notifications off", mode: models.NotificationModeOff},
+		{name: "no settings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newNotificationTestEnv(t)
+			form := env.addForm(env.ann.ID, "Feedback")
+			if tt.workspace {
+				form.WorkspaceID = env.teamWorkspace.ID
+			}
+			if tt.mode != "" {
+				env.settings.save(&models.NotificationSettings{FormID: form.ID, Mode: tt.mode})
+			}
+
+			sub := &models.Submission{
+				ID:        primitive.NewObjectID(),
+				FormID:    form.ID,
+				CreatedAt: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+				Answers: []models.Answer{
+					{QuestionID: 1, Text: "Great service"},
+					{QuestionID: 2, OptionIDs: []int{2}},
+				},
+			}
+			env.service.SubmissionCreated(context.Background(), form, sub)
+
+			if tt.wantTo == "" {
+				if messages := env.mail.Messages(); len(messages) != 0 {
+					t.Errorf("sent %d emails, want 0", len(messages))
+				}
+				return
+			}
+
+			msg := env.waitForMessage(t)
+			if len(msg.To) != 1 || msg.To[0] != tt.wantTo {
+				t.Errorf("To = %v, want %s", msg.To, tt.wantTo)
+			}
+			if msg.Subject != `New response to "Feedback"` {
+				t.Errorf("Subject = %q", msg.Subject)
+			}
+			for _, want := range []string{
+				"received a new response at 2024-03-01 12:30 UTC",
+				"How was it?\n  Great service\n",
+				"Recommend us?\n  No\n",
+				"https://app.example.com/app/form/" + form.ID.Hex() + "/edit",
+			} {
+				if !strings.Contains(msg.Body, want) {
+					t.Errorf("body %q does not contain %q", msg.Body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSendDueDigests(t *testing.T) {
+	env := newNotificationTestEnv(t)
+	now := time.Now()
+	dayAgo := now.Add(-digestPeriod - time.Minute)
+	hourAgo := now.Add(-time.Hour)
+
+	due := env.addForm(env.ann.ID, "Feedback")
+	env.settings.save(&models.NotificationSettings{FormID: due.ID, Mode: models.NotificationModeDigest, DigestSentAt: &dayAgo})
+	env.submissions.add(due.ID, now.Add(-48*time.Hour), now.Add(-2*time.Hour), now.Add(-time.Minute))
+
+	team := env.addForm(env.teamWorkspace.ID, "Team survey")
+	env.settings.save(&models.NotificationSettings{FormID: team.ID, Mode: models.NotificationModeDigest, DigestSentAt: &dayAgo})
+	env.submissions.add(team.ID, now.Add(-time.Hour))
+
+	quiet := env.addForm(env.ann.ID, "Quiet")
+	env.settings.save(&models.NotificationSettings{FormID: quiet.ID, Mode: models.NotificationModeDigest, DigestSentAt: &dayAgo})
+	env.submissions.add(quiet.ID, now.Add(-72*time.Hour))
+
+	recent := env.addForm(env.ann.ID, "Recent")
+	env.settings.save(&models.NotificationSettings{FormID: recent.ID, Mode: models.NotificationModeDigest, DigestSentAt: &hourAgo})
+	env.submissions.add(recent.ID, now.Add(-time.Minute))
+
+	immediate := env.addForm(env.ann.ID, "Immediate")
+	env.settings.save(&models.NotificationSettings{FormID: immediate.ID, Mode: models.NotificationModeImmediate})
+	env.submissions.add(immediate.ID, now.Add(-time.Minute))
+
+	env.service.sendDueDigests(context.Background())
+
+	messages := env.mail.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("sent %d emails, want 2: %+v", len(messages), messages)
+	}
+	want := []struct {
+		to, subject string
+		body        []string
+	}{
+		{
+			to:      "ann@example.com",
+			subject: `Daily summary for "Feedback": 2 new response(s)`,
+			body:    []string{"received 2 new response(s) since", "Total responses: 3\n", "/app/form/" + due.ID.Hex() + "/edit"},
+		},
+		{
+			to:      "owner@example.com",
+			subject: `Daily summary for "Team survey": 1 new response(s)`,
+			body:    []string{"Total responses: 1\n"},
+		},
+	}
+	for i, w := range want {
+		msg := messages[i]
+		if len(msg.To) != 1 || msg.To[0] != w.to || msg.Subject != w.subject {
+			t.Errorf("email %d = %v %q, want %s %q", i, msg.To, msg.Subject, w.to, w.subject)
+		}
+		for _, part := range w.body {
+			if !strings.Contains(msg.Body, part) {
+				t.Errorf("email %d body %q does not contain %q", i, msg.Body, part)
+			}
+		}
+	}
+
+	for _, form := range []*models.Form{due, team, quiet} {
+		if sentAt := env.settings.byForm[form.ID].DigestSentAt; sentAt.Before(now) {
+			t.Errorf("digest of %q was not moved to now, digestSentAt = %s", form.Name, sentAt)
+		}
+	}
+	if sentAt := env.settings.byForm[recent.ID].DigestSentAt; !sentAt.Equal(hourAgo) {
+		t.Errorf("digest of a recent form was claimed, digestSentAt = %s", sentAt)
+	}
+
+	env.mail.Reset()
+	env.service.sendDueDigests(context.Background())
+	if got := len(env.mail.Messages()); got != 0 {
+		t.Errorf("second run sent %d emails, want 0", got)
+	}
+}
+
+func TestNotificationsWithoutSender(t *testing.T) {
+	env := newNotificationTestEnv(t)
+	env.service.sender = nil
+
+	dayAgo := time.Now().Add(-digestPeriod - time.Minute)
+	form := env.addForm(env.ann.ID, "Feedback")
+	env.settings.save(&models.NotificationSettings{FormID: form.ID, Mode: models.NotificationModeDigest, DigestSentAt: &dayAgo})
+	env.submissions.add(form.ID, time.Now().Add(-time.Minute))
+
+	env.service.sendDueDigests(context.Background())
+	if got := len(env.mail.Messages()); got != 0 {
+		t.Errorf("sent %d emails without a sender", got)
+	}
+}
+
+type notificationTestEnv struct {
+	service       *NotificationService
+	mail          *notifier.MemorySender
+	settings      *fakeNotificationStore
+	forms         fakeFormGetter
+	submissions   fakeSubmissionCounter
+	ann           *models.User
+	teamWorkspace *models.Workspace
+}
+
+func newNotificationTestEnv(t *testing.T) *notificationTestEnv {
+	t.Helper()
+	ctx := context.Background()
+	users := &fakeUserRepository{}
+	ann := &models.User{Email: "ann@example.com"}
+	owner := &models.User{Email: "owner@example.com"}
+	for _, user := range []*models.User{ann, owner} {
+		if err := users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env := &notificationTestEnv{
+		mail:          notifier.NewMemorySender(),
+		settings:      &fakeNotificationStore{byForm: map[primitive.ObjectID]*models.NotificationSettings{}},
+		forms:         fakeFormGetter{},
+		submissions:   fakeSubmissionCounter{},
+		ann:           ann,
+		teamWorkspace: &models.Workspace{ID: primitive.NewObjectID(), Name: "Team", OwnerID: owner.ID},
+	}
+	workspaces := fakeWorkspaceFinder{env.teamWorkspace.ID: env.teamWorkspace}
+	env.service = NewNotificationService(env.settings, env.forms, env.submissions, users, workspaces, env.mail, "https://app.example.com/")
+	return env
+}
+
+func (e *notificationTestEnv) addForm(workspaceID primitive.ObjectID, name string) *models.Form {
+	form := &models.Form{
+		ID:          primitive.NewObjectID(),
+		WorkspaceID: workspaceID,
+		Name:        name,
+		Questions: []models.Question{
+			{ID: 1, Type: "input", Question: "How was it?"},
+			{ID: 2, Type: "single-choice", Question: "Recommend us?", Options: []models.Option{{ID: 1, Text: "Yes"}, {ID: 2, Text: "No"}}},
+		},
+	}
+	e.forms[form.ID.Hex()] = form
+	return form
+}
+
+// waitForMessage waits for the email sent in the background.
+func (e *notificationTestEnv) waitForMessage(t *testing.T) notifier.Message {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if messages := e.mail.Messages(); len(messages) > 0 {
+			if len(messages) > 1 {
+				t.Errorf("sent %d emails, want 1", len(messages))
+			}
+			return messages[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("no email was sent")
+	return notifier.Message{}
+}
+
+type fakeNotificationStore struct {
+	byForm map[primitive.ObjectID]*models.NotificationSettings
+	order  []primitive.ObjectID
+}
+
+func (s *fakeNotificationStore) save(settings *models.NotificationSettings) {
+	if _, ok := s.byForm[settings.FormID]; !ok {
+		s.order = append(s.order, settings.FormID)
+	}
+	s.byForm[settings.FormID] = settings
+}
+
+func (s *fakeNotificationStore) GetSettings(ctx context.Context, formID primitive.ObjectID) (*models.NotificationSettings, error) {
+	settings, ok := s.byForm[formID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *settings
+	return &copied, nil
+}
+
+func (s *fakeNotificationStore) SaveSettings(ctx context.Context, settings *models.NotificationSettings) error {
+	copied := *settings
+	s.save(&copied)
+	return nil
+}
+
+func (s *fakeNotificationStore) ClaimDueDigest(ctx context.Context, now time.Time, period time.Duration) (*models.NotificationSettings, error) {
+	for _, formID := range s.order {
+		settings := s.byForm[formID]
+		if settings.Mode != models.NotificationModeDigest || settings.DigestSentAt == nil || settings.DigestSentAt.After(now.Add(-period)) {
+			continue
+		}
+		claimed := *settings
+		settings.DigestSentAt = &now
+		return &claimed, nil
+	}
+	return nil, nil
+}
+
+type fakeFormGetter map[string]*models.Form
+
+func (f fakeFormGetter) GetForm(id string) (*models.Form, error) {
+	form, ok := f[id]
+	if !ok || form.DeletedAt != nil {
+		return nil, repository.ErrFormNotFound
+	}
+	copied := *form
+	return &copied, nil
+}
+
+type fakeSubmissionCounter map[primitive.ObjectID][]time.Time
+
+func (f fakeSubmissionCounter) add(formID primitive.ObjectID, createdAt ...time.Time) {
+	f[formID] = append(f[formID], createdAt...)
+}
+
+func (f fakeSubmissionCounter) CountSubmissions(ctx context.Context, formID primitive.ObjectID) (int64, error) {
+	return int64(len(f[formID])), nil
+}
+
+func (f fakeSubmissionCounter) CountSubmissionsBetween(ctx context.Context, formID primitive.ObjectID, from, to time.Time) (int64, error) {
+	var count int64
+	for _, createdAt := range f[formID] {
+		if !createdAt.Before(from) && createdAt.Before(to) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+type fakeWorkspaceFinder map[primitive.ObjectID]*models.Workspace
+
+func (f fakeWorkspaceFinder) FindWorkspace(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	workspace, ok := f[id]
+	if !ok {
+		return nil, repository.ErrWorkspaceNotFound
+	}
+	return workspace, nil
+}
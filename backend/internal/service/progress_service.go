@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+)
+
+const maxSessionIDLength = 64
+
+type ProgressService struct {
+	progressRepo *repository.ProgressRepository
+	formRepo     *repository.FormRepository
+}
+
+func NewProgressService(progressRepo *repository.ProgressRepository, formRepo *repository.FormRepository) *ProgressService {
+	return &ProgressService{
+		progressRepo: progressRepo,
+		formRepo:     formRepo,
+	}
+}
+
+// RecordEvent stores a view or answer event sent by the form player.
+// Completion events are only recorded by the server on submission.
+func (s *ProgressService) RecordEvent(ctx context.Context, formID string, event *models.ProgressEvent) error {
+	if event.SessionID == "" || len(event.SessionID) > maxSessionIDLength {
+		return apperrors.NewBadRequestError("invalid session ID")
+	}
+	if event.Type != models.ProgressEventView && event.Type != models.ProgressEventAnswer {
+		return apperrors.NewBadRequestError("invalid event type")
+	}
+
+	form, err := loadPublishedForm(s.formRepo, formID)
+	if err != nil {
+		return err
+	}
+	if _, ok := NewFlowEngine(form).Question(event.QuestionID); !ok {
+		return apperrors.NewBadRequestError("question does not exist in this form")
+	}
+
+	event.FormID = form.ID
+	event.CreatedAt = time.Now()
+	if err := s.progressRepo.CreateEvent(ctx, event); err != nil {
+		return apperrors.NewInternalServerError("failed to record progress event", err)
+	}
+	return nil
+}
+
+// GetFunnel reports how many sessions viewed, started and completed a form
+// owned by workspaceID, and where respondents abandoned it.
+func (s *ProgressService) GetFunnel(ctx context.Context, formID, workspaceID string) (*models.FunnelReport, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.progressRepo.CountSessions(ctx, form.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to count sessions", err)
+	}
+	return buildFunnel(form, counts), nil
+}
+
+// buildFunnel computes the funnel of a form from the distinct sessions per
+// event type and question.
+func buildFunnel(form *models.Form, counts *repository.SessionCounts) *models.FunnelReport {
+	report := &models.FunnelReport{
+		FormID:      form.ID,
+		Views:       counts.Sessions,
+		Starts:      counts.ByType[models.ProgressEventAnswer],
+		Completions: counts.ByType[models.ProgressEventComplete],
+		Questions:   make([]models.FunnelStep, 0, len(form.Questions)),
+	}
+	report.CompletionRate = percentage(report.Completions, report.Views)
+
+	for _, q := range form.Questions {
+		views := counts.ByQuestion[q.ID][models.ProgressEventView]
+		answers := counts.ByQuestion[q.ID][models.ProgressEventAnswer]
+
+		// Respondents can only move on by answering, so a session that saw
+		// a question without answering it abandoned the form there.
+		dropOffs := views - answers
+		if dropOffs < 0 {
+			dropOffs = 0
+		}
+
+		report.Questions = append(report.Questions, models.FunnelStep{
+			QuestionID:  q.ID,
+			Question:    q.Question,
+			Views:       views,
+			Answers:     answers,
+			DropOffs:    dropOffs,
+			DropOffRate: percentage(dropOffs, views),
+		})
+	}
+
+	return report
+}
+
+// percentage returns part of total in percent rounded to two places, 0 if
+// total is 0.
+func percentage(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return roundTo(float64(part)*100/float64(total), 2)
+}
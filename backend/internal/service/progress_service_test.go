@@ -0,0 +1,116 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPercentage(t *testing.T) {
+	tests := []struct {
+		part, total int64
+		want        float64
+	}{
+		{0, 0, 0},
+		{5, 0, 0},
+		{0, 7, 0},
+		{7, 7, 100},
+		{1, 3, 33.33},
+		{2, 3, 66.67},
+		{1, 8, 12.5},
+		{3, 2, 150},
+	}
+
+	for _, tt := range tests {
+		if got := percentage(tt.part, tt.total); got != tt.want {
+			t.Errorf("percentage(%d, %d) = %v, want %v", tt.part, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestBuildFunnel(t *testing.T) {
+	form := &models.Form{
+		ID: primitive.NewObjectID(),
+		Questions: []models.Question{
+			{ID: 1, Question: "Name?"},
+			{ID: 2, Question: "Email?"},
+			{ID: 3, Question: "Score?"},
+		},
+	}
+	events := func(views, answers int64) map[string]int64 {
+		return map[string]int64{models.ProgressEventView: views, models.ProgressEventAnswer: answers}
+	}
+
+	tests := []struct {
+		name           string
+		counts         repository.SessionCounts
+		wantStarts     int64
+		wantCompletion float64
+		want           []models.FunnelStep
+	}{
+		{
+			name: "no views",
+			want: []models.FunnelStep{
+				{QuestionID: 1, Question: "Name?"},
+				{QuestionID: 2, Question: "Email?"},
+				{QuestionID: 3, Question: "Score?"},
+			},
+		},
+		{
+			name: "drop-offs per question",
+			counts: repository.SessionCounts{
+				Sessions: 10,
+				ByType:   map[string]int64{models.ProgressEventAnswer: 8, models.ProgressEventComplete: 3},
+				ByQuestion: map[int]map[string]int64{
+					1: events(10, 8),
+					2: events(8, 3),
+					3: events(3, 3),
+				},
+			},
+			wantStarts:     8,
+			wantCompletion: 30,
+			want: []models.FunnelStep{
+				{QuestionID: 1, Question: "Name?", Views: 10, Answers: 8, DropOffs: 2, DropOffRate: 20},
+				{QuestionID: 2, Question: "Email?", Views: 8, Answers: 3, DropOffs: 5, DropOffRate: 62.5},
+				{QuestionID: 3, Question: "Score?", Views: 3, Answers: 3},
+			},
+		},
+		{
+			name: "answers without a view event",
+			counts: repository.SessionCounts{
+				Sessions: 3,
+				ByType:   map[string]int64{models.ProgressEventAnswer: 3, models.ProgressEventComplete: 1},
+				ByQuestion: map[int]map[string]int64{
+					1: events(2, 3),
+					2: events(3, 1),
+				},
+			},
+			wantStarts:     3,
+			wantCompletion: 33.33,
+			want: []models.FunnelStep{
+				{QuestionID: 1, Question: "Name?", Views: 2, Answers: 3},
+				{QuestionID: 2, Question: "Email?", Views: 3, Answers: 1, DropOffs: 2, DropOffRate: 66.67},
+				{QuestionID: 3, Question: "Score?"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := buildFunnel(form, &tt.counts)
+
+			if report.FormID != form.ID || report.Views != tt.counts.Sessions || report.Starts != tt.wantStarts {
+				t.Errorf("report = %+v, want views %d and starts %d", report, tt.counts.Sessions, tt.wantStarts)
+			}
+			if report.CompletionRate != tt.wantCompletion {
+				t.Errorf("CompletionRate = %v, want %v", report.CompletionRate, tt.wantCompletion)
+			}
+			if !reflect.DeepEqual(report.Questions, tt.want) {
+				t.Errorf("Questions = %+v, want %+v", report.Questions, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+)
+
+// maxRatingBuckets limits how many empty buckets are added to a rating
+// distribution for values nobody picked.
+const maxRatingBuckets = 100
+
+// GetAnalytics returns per-question statistics for a form owned by workspaceID.
+// All counting happens in MongoDB, only the aggregated rows are loaded.
+func (s *SubmissionService) GetAnalytics(ctx context.Context, formID, workspaceID string) (*models.FormAnalytics, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.subRepo.CountSubmissions(ctx, form.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to count submissions", err)
+	}
+	answers, err := s.subRepo.CountAnswers(ctx, form.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to count answers", err)
+	}
+	optionRows, err := s.subRepo.CountOptions(ctx, form.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to count options", err)
+	}
+	ratingRows, err := s.subRepo.CountRatings(ctx, form.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to count ratings", err)
+	}
+
+	options := make(map[int]map[int]int64)
+	for _, row := range optionRows {
+		if options[row.QuestionID] == nil {
+			options[row.QuestionID] = make(map[int]int64)
+		}
+		options[row.QuestionID][row.OptionID] = row.Count
+	}
+	ratings := make(map[int][]repository.RatingCount)
+	for _, row := range ratingRows {
+		ratings[row.QuestionID] = append(ratings[row.QuestionID], row)
+	}
+
+	analytics := &models.FormAnalytics{
+		FormID:         form.ID,
+		TotalResponses: total,
+		Questions:      make([]models.QuestionAnalytics, 0, len(form.Questions)),
+	}
+	for _, q := range form.Questions {
+		stats := models.QuestionAnalytics{
+			QuestionID: q.ID,
+			Type:       q.Type,
+			Question:   q.Question,
+			Responses:  answers[q.ID],
+		}
+
+		switch q.Type {
+		case "single-choice", "multiple-choice":
+			stats.Options = optionStats(q, options[q.ID], stats.Responses)
+		case "rating":
+			stats.Rating = ratingStats(q, ratings[q.ID])
+		}
+
+		analytics.Questions = append(analytics.Questions, stats)
+	}
+
+	return analytics, nil
+}
+
+// optionStats reports picks per option. Percentages are relative to the
+// number of respondents, so for multiple-choice they may add up to more than 100.
+func optionStats(q models.Question, counts map[int]int64, responses int64) []models.OptionStats {
+	stats := make([]models.OptionStats, 0, len(q.Options))
+	for _, o := range q.Options {
+		count := counts[o.ID]
+		stats = append(stats, models.OptionStats{
+			OptionID:   o.ID,
+			Text:       o.Text,
+			Count:      count,
+			Percentage: percentage(count, responses),
+		})
+	}
+	return stats
+}
+
+// ratingStats computes mean and median from the value histogram, which is
+// sorted by value, and fills in empty buckets of the rating scale.
+func ratingStats(q models.Question, rows []repository.RatingCount) *models.RatingStats {
+	stats := &models.RatingStats{Distribution: []models.RatingBucket{}}
+
+	var total int64
+	var sum float64
+	for _, row := range rows {
+		total += row.Count
+		sum += row.Value * float64(row.Count)
+	}
+
+	if total > 0 {
+		stats.Mean = roundTo(sum/float64(total), 2)
+		stats.Median = histogramMedian(rows, total)
+	}
+
+	observed := make(map[float64]int64, len(rows))
+	for _, row := range rows {
+		observed[row.Value] = row.Count
+	}
+
+	step := q.Step
+	if step <= 0 {
+		step = 1
+	}
+	if q.MaxValue > q.MinValue && float64(q.MaxValue-q.MinValue)/step <= maxRatingBuckets {
+		for i := 0; ; i++ {
+			value := roundTo(float64(q.MinValue)+float64(i)*step, 6)
+			if value > float64(q.MaxValue) {
+				break
+			}
+			stats.Distribution = append(stats.Distribution, models.RatingBucket{Value: value, Count: observed[value]})
+			delete(observed, value)
+		}
+	}
+	// Values outside of the configured scale, e.g. after the scale was edited
+	for _, row := range rows {
+		if count, ok := observed[row.Value]; ok {
+			stats.Distribution = append(stats.Distribution, models.RatingBucket{Value: row.Value, Count: count})
+		}
+	}
+	sort.Slice(stats.Distribution, func(i, j int) bool {
+		return stats.Distribution[i].Value < stats.Distribution[j].Value
+	})
+
+	return stats
+}
+
+// histogramMedian returns the median of total values counted in rows, which
+// are sorted by value. An empty histogram has a median of 0.
+func histogramMedian(rows []repository.RatingCount, total int64) float64 {
+	if total == 0 || len(rows) == 0 {
+		return 0
+	}
+
+	valueAt := func(position int64) float64 {
+		var seen int64
+		for _, row := range rows {
+			seen += row.Count
+			if seen > position {
+				return row.Value
+			}
+		}
+		return rows[len(rows)-1].Value
+	}
+
+	if total%2 == 1 {
+		return valueAt(total / 2)
+	}
+	return (valueAt(total/2-1) + valueAt(total/2)) / 2
+}
+
+func roundTo(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
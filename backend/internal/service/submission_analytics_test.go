@@ -0,0 +1,152 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+)
+
+func TestOptionStats(t *testing.T) {
+	question := models.Question{
+		ID:   1,
+		Type: "multiple-choice",
+		Options: []models.Option{
+			{ID: 1, Text: "Red"},
+			{ID: 2, Text: "Green"},
+			{ID: 3, Text: "Blue"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		counts    map[int]int64
+		responses int64
+		want      []float64
+	}{
+		{name: "no responses", counts: nil, responses: 0, want: []float64{0, 0, 0}},
+		{name: "single choice adds up to 100", counts: map[int]int64{1: 1, 2: 3}, responses: 4, want: []float64{25, 75, 0}},
+		{name: "rounded to two places", counts: map[int]int64{1: 1, 2: 2}, responses: 3, want: []float64{33.33, 66.67, 0}},
+		{name: "multiple choice may exceed 100", counts: map[int]int64{1: 2, 2: 2, 3: 1}, responses: 2, want: []float64{100, 100, 50}},
+		{name: "picks of removed options are ignored", counts: map[int]int64{1: 1, 9: 5}, responses: 6, want: []float64{16.67, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := optionStats(question, tt.counts, tt.responses)
+			if len(stats) != len(question.Options) {
+				t.Fatalf("got %d options, want %d", len(stats), len(question.Options))
+			}
+			for i, s := range stats {
+				o := question.Options[i]
+				if s.OptionID != o.ID || s.Text != o.Text || s.Count != tt.counts[o.ID] {
+					t.Errorf("option %d = %+v, want ID %d, text %q and count %d", i, s, o.ID, o.Text, tt.counts[o.ID])
+				}
+				if s.Percentage != tt.want[i] {
+					t.Errorf("option %d percentage = %v, want %v", o.ID, s.Percentage, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRatingStats(t *testing.T) {
+	scale := models.Question{ID: 1, Type: "rating", MinValue: 1, MaxValue: 5}
+
+	tests := []struct {
+		name       string
+		question   models.Question
+		rows       []repository.RatingCount
+		wantMean   float64
+		wantMedian float64
+		want       []models.RatingBucket
+	}{
+		{
+			name:     "no answers",
+			question: scale,
+			want:     []models.RatingBucket{{Value: 1}, {Value: 2}, {Value: 3}, {Value: 4}, {Value: 5}},
+		},
+		{
+			name:       "empty buckets are filled in",
+			question:   scale,
+			rows:       []repository.RatingCount{{Value: 2, Count: 1}, {Value: 5, Count: 2}},
+			wantMean:   4,
+			wantMedian: 5,
+			want:       []models.RatingBucket{{Value: 1}, {Value: 2, Count: 1}, {Value: 3}, {Value: 4}, {Value: 5, Count: 2}},
+		},
+		{
+			name:       "mean rounded to two places",
+			question:   scale,
+			rows:       []repository.RatingCount{{Value: 1, Count: 1}, {Value: 2, Count: 1}, {Value: 4, Count: 1}},
+			wantMean:   2.33,
+			wantMedian: 2,
+			want:       []models.RatingBucket{{Value: 1, Count: 1}, {Value: 2, Count: 1}, {Value: 3}, {Value: 4, Count: 1}, {Value: 5}},
+		},
+		{
+			name:       "fractional steps",
+			question:   models.Question{ID: 1, Type: "rating", MinValue: 0, MaxValue: 1, Step: 0.5},
+			rows:       []repository.RatingCount{{Value: 0.5, Count: 2}},
+			wantMean:   0.5,
+			wantMedian: 0.5,
+			want:       []models.RatingBucket{{Value: 0}, {Value: 0.5, Count: 2}, {Value: 1}},
+		},
+		{
+			name:       "values outside of the scale are kept",
+			question:   scale,
+			rows:       []repository.RatingCount{{Value: 3, Count: 1}, {Value: 10, Count: 1}},
+			wantMean:   6.5,
+			wantMedian: 6.5,
+			want:       []models.RatingBucket{{Value: 1}, {Value: 2}, {Value: 3, Count: 1}, {Value: 4}, {Value: 5}, {Value: 10, Count: 1}},
+		},
+		{
+			name:       "scales too large to fill in",
+			question:   models.Question{ID: 1, Type: "rating", MinValue: 0, MaxValue: maxRatingBuckets + 1},
+			rows:       []repository.RatingCount{{Value: 7, Count: 3}},
+			wantMean:   7,
+			wantMedian: 7,
+			want:       []models.RatingBucket{{Value: 7, Count: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := ratingStats(tt.question, tt.rows)
+			if stats.Mean != tt.wantMean || stats.Median != tt.wantMedian {
+				t.Errorf("mean = %v, median = %v, want %v and %v", stats.Mean, stats.Median, tt.wantMean, tt.wantMedian)
+			}
+			if !reflect.DeepEqual(stats.Distribution, tt.want) {
+				t.Errorf("Distribution = %+v, want %+v", stats.Distribution, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistogramMedian(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []repository.RatingCount
+		want float64
+	}{
+		{name: "empty", rows: nil, want: 0},
+		{name: "single value", rows: []repository.RatingCount{{Value: 4, Count: 1}}, want: 4},
+		{name: "odd count", rows: []repository.RatingCount{{Value: 1, Count: 1}, {Value: 2, Count: 1}, {Value: 5, Count: 1}}, want: 2},
+		{name: "even count averages the middle values", rows: []repository.RatingCount{{Value: 1, Count: 1}, {Value: 2, Count: 1}, {Value: 4, Count: 1}, {Value: 5, Count: 1}}, want: 3},
+		{name: "even count within one bucket", rows: []repository.RatingCount{{Value: 1, Count: 1}, {Value: 3, Count: 2}, {Value: 5, Count: 1}}, want: 3},
+		{name: "middle on a bucket boundary", rows: []repository.RatingCount{{Value: 2, Count: 2}, {Value: 3, Count: 2}}, want: 2.5},
+		{name: "skewed", rows: []repository.RatingCount{{Value: 1, Count: 5}, {Value: 5, Count: 2}}, want: 1},
+		{name: "empty buckets are skipped", rows: []repository.RatingCount{{Value: 1, Count: 1}, {Value: 2, Count: 0}, {Value: 3, Count: 1}}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var total int64
+			for _, row := range tt.rows {
+				total += row.Count
+			}
+			if got := histogramMedian(tt.rows, total); got != tt.want {
+				t.Errorf("histogramMedian() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"github.com/maxzhirnov/formease/pkg/xlsx"
+)
+
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// SubmissionExport streams all submissions of a form as a table with
+// metadata columns followed by one column per question.
+type SubmissionExport struct {
+	Filename    string
+	ContentType string
+
+	format  string
+	form    *models.Form
+	subRepo *repository.SubmissionRepository
+}
+
+// rowWriter is implemented by the CSV and XLSX table writers.
+type rowWriter interface {
+	WriteRow(cells []string) error
+	Close() error
+}
+
+// PrepareExport checks access to the form and returns an export that can be
+// streamed to the client once the response headers are set.
+func (s *SubmissionService) PrepareExport(formID, workspaceID, format string) (*SubmissionExport, error) {
+	if format != ExportFormatCSV && format != ExportFormatXLSX {
+		return nil, apperrors.NewBadRequestError("unsupported export format")
+	}
+
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == ExportFormatXLSX {
+		contentType = xlsx.ContentType
+	}
+
+	return &SubmissionExport{
+		Filename:    fmt.Sprintf("%s-submissions-%s.%s", exportFilename(form.Name), time.Now().Format("20060102"), format),
+		ContentType: contentType,
+		format:      format,
+		form:        form,
+		subRepo:     s.subRepo,
+	}, nil
+}
+
+// WriteTo streams the table to w. Once writing started the response can
+// no longer change, so errors are only useful for logging.
+func (e *SubmissionExport) WriteTo(ctx context.Context, w io.Writer) error {
+	var rows rowWriter
+	switch e.format {
+	case ExportFormatXLSX:
+		writer, err := xlsx.NewWriter(w, e.form.Name)
+		if err != nil {
+			return fmt.Errorf("failed to start xlsx export: %w", err)
+		}
+		rows = writer
+	default:
+		// The BOM makes Excel detect UTF-8 in CSV files
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return err
+		}
+		rows = &csvRowWriter{w: csv.NewWriter(w)}
+	}
+
+	header := []string{"Submission ID", "Submitted At"}
+	for _, q := range e.form.Questions {
+		header = append(header, q.Question)
+	}
+	if err := rows.WriteRow(header); err != nil {
+		return err
+	}
+
+	questions := e.form.Questions
+	err := e.subRepo.StreamSubmissions(ctx, e.form.ID, func(sub *models.Submission) error {
+		answers := make(map[int]models.Answer, len(sub.Answers))
+		for _, a := range sub.Answers {
+			answers[a.QuestionID] = a
+		}
+
+		row := make([]string, 0, len(questions)+2)
+		row = append(row, sub.ID.Hex(), sub.CreatedAt.UTC().Format(time.RFC3339))
+		for i := range questions {
+			answer, ok := answers[questions[i].ID]
+			if !ok {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, formatAnswer(&questions[i], answer))
+		}
+		return rows.WriteRow(row)
+	})
+	if err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// formatAnswer renders an answer as a single cell. Selected options are
+// shown by their text and joined for multiple-choice questions.
+func formatAnswer(q *models.Question, a models.Answer) string {
+	switch {
+	case len(a.OptionIDs) > 0:
+		texts := make([]string, 0, len(a.OptionIDs))
+		for _, id := range a.OptionIDs {
+			text := optionText(q, id)
+			if text == "" {
+				text = strconv.Itoa(id)
+			}
+			texts = append(texts, text)
+		}
+		return strings.Join(texts, "; ")
+	case a.Rating != nil:
+		return strconv.FormatFloat(*a.Rating, 'f', -1, 64)
+	default:
+		return a.Text
+	}
+}
+
+func exportFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '_' {
+			return '-'
+		}
+		if r < 128 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, name)
+	if name == "" {
+		return "form"
+	}
+	return name
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(cells []string) error {
+	for i, cell := range cells {
+		cells[i] = escapeCSVFormula(cell)
+	}
+	return c.w.Write(cells)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Cells starting with + or - that match these are left alone, they can not
+// call functions.
+var (
+	csvNumberPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+	csvPhonePattern  = regexp.MustCompile(`^\+[\d\s().-]+$`)
+)
+
+// escapeCSVFormula keeps spreadsheet apps from evaluating respondent input
+// that looks like a formula. Numbers and phone numbers are kept as they are.
+func escapeCSVFormula(cell string) string {
+	if cell == "" {
+		return cell
+	}
+	switch cell[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + cell
+	case '+', '-':
+		if csvNumberPattern.MatchString(cell) || csvPhonePattern.MatchString(cell) {
+			return cell
+		}
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,36 @@
+package service
+
+import "testing"
+
+func TestEscapeCSVFormula(t *testing.T) {
+	tests := []struct {
+		cell string
+		want string
+	}{
+		{"", ""},
+		{"hello", "hello"},
+		{"42", "42"},
+		{"-42", "-42"},
+		{"+42", "+42"},
+		{"-3.5", "-3.5"},
+		{"-.5", "-.5"},
+		{"+1e5", "+1e5"},
+		{"+7 (912) 345-67-89", "+7 (912) 345-67-89"},
+		{"+44 20.7946.0000", "+44 20.7946.0000"},
+		{"=SUM(A1:A2)", "'=SUM(A1:A2)"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcell", "'\tcell"},
+		{"\rcell", "'\rcell"},
+		{"+SUM(A1)", "'+SUM(A1)"},
+		{"-2+3+cmd|' /C calc'!A0", "'-2+3+cmd|' /C calc'!A0"},
+		{"-", "'-"},
+		{"- item", "'- item"},
+		{"+", "'+"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeCSVFormula(tt.cell); got != tt.want {
+			t.Errorf("escapeCSVFormula(%q) = %q, want %q", tt.cell, got, tt.want)
+		}
+	}
+}
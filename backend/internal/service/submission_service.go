@@ -1,31 +1,206 @@
 package service
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/maxzhirnov/formease/internal/models"
 	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
 )
 
+const (
+	defaultSubmissionPageSize = 20
+	maxSubmissionPageSize     = 100
+)
+
+// SubmissionListParams are the options an owner can use to page
+// through the submissions of a form.
+type SubmissionListParams struct {
+	From          *time.Time
+	To            *time.Time
+	Ascending     bool
+	Cursor        string
+	Limit         int
+	AnswerFilters map[int]string // question ID -> expected answer value
+}
+
+// SubmissionListener is notified after a submission has been stored.
+// Implementations must not block: the respondent is still waiting.
+type SubmissionListener interface {
+	SubmissionCreated(ctx context.Context, form *models.Form, sub *models.Submission)
+}
+
 type SubmissionService struct {
-	subRepo *repository.SubmissionRepository
+	subRepo      *repository.SubmissionRepository
+	formRepo     *repository.FormRepository
+	progressRepo *repository.ProgressRepository
+	validator    *SubmissionValidator
+	listeners    []SubmissionListener
 }
 
-func NewSubmissionService(subRepo *repository.SubmissionRepository) *SubmissionService {
+func NewSubmissionService(subRepo *repository.SubmissionRepository, formRepo *repository.FormRepository, progressRepo *repository.ProgressRepository) *SubmissionService {
 	return &SubmissionService{
-		subRepo: subRepo,
+		subRepo:      subRepo,
+		formRepo:     formRepo,
+		progressRepo: progressRepo,
+		validator:    NewSubmissionValidator(),
 	}
 }
 
+// AddListener registers l to be called for every new submission.
+func (s *SubmissionService) AddListener(l SubmissionListener) {
+	s.listeners = append(s.listeners, l)
+}
+
 func (s *SubmissionService) CreateSubmission(sub *models.Submission) error {
-	if err := s.validateSubmission(sub); err != nil {
+	if sub.FormID.IsZero() {
+		return apperrors.NewBadRequestError("form ID is required")
+	}
+
+	form, err := loadPublishedForm(s.formRepo, sub.FormID.Hex())
+	if err != nil {
+		return err
+	}
+
+	if err := s.validator.Validate(form, sub); err != nil {
 		return fmt.Errorf("submission validation failed: %w", err)
 	}
 
-	return s.subRepo.CreateSubmission(sub)
-}
+	// Answers are well-formed, make sure they follow a legal path
+	if errs := NewFlowEngine(form).Replay(sub.Answers); len(errs) > 0 {
+		return fmt.Errorf("submission flow check failed: %w", &SubmissionValidationError{Errors: errs})
+	}
+
+	now := time.Now()
+	if err := checkFormOpen(form, now); err != nil {
+		return err
+	}
+	// The form may have been loaded before other submissions took the last slots
+	reserved, err := s.formRepo.ReserveResponse(context.Background(), form.ID)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to reserve response", err)
+	}
+	if !reserved {
+		return newFormClosedError(form, models.FormClosedReasonResponseLimit)
+	}
+
+	sub.Revision = form.Revision
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+
+	if err := s.subRepo.CreateSubmission(sub); err != nil {
+		if releaseErr := s.formRepo.ReleaseResponse(context.Background(), form.ID); releaseErr != nil {
+			logger.Error("Failed to release response slot",
+				zap.String("formId", form.ID.Hex()),
+				zap.Error(releaseErr))
+		}
+		return err
+	}
+
+	if sub.SessionID != "" && len(sub.SessionID) <= maxSessionIDLength {
+		event := &models.ProgressEvent{
+			FormID:    sub.FormID,
+			SessionID: sub.SessionID,
+			Type:      models.ProgressEventComplete,
+			CreatedAt: now,
+		}
+		// The submission is already saved, a missing event only affects the funnel
+		if err := s.progressRepo.CreateEvent(context.Background(), event); err != nil {
+			logger.Error("Failed to record completion event",
+				zap.String("submissionId", sub.ID.Hex()),
+				zap.Error(err))
+		}
+	}
+
+	for _, l := range s.listeners {
+		l.SubmissionCreated(context.Background(), form, sub)
+	}
 
-func (s *SubmissionService) validateSubmission(sub *models.Submission) error {
-	//TODO: Add validation
 	return nil
 }
+
+// ListSubmissions returns a page of submissions of a form owned by workspaceID.
+func (s *SubmissionService) ListSubmissions(ctx context.Context, formID, workspaceID string, params SubmissionListParams) (*models.SubmissionPage, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultSubmissionPageSize
+	}
+	if limit > maxSubmissionPageSize {
+		limit = maxSubmissionPageSize
+	}
+
+	query := repository.SubmissionQuery{
+		FormID:    form.ID,
+		From:      params.From,
+		To:        params.To,
+		Ascending: params.Ascending,
+		// Fetch one extra document to know whether there is a next page
+		Limit: limit + 1,
+	}
+	for questionID, value := range params.AnswerFilters {
+		query.AnswerFilters = append(query.AnswerFilters, repository.AnswerFilter{
+			QuestionID: questionID,
+			Value:      value,
+		})
+	}
+	if params.Cursor != "" {
+		cursor, err := decodeSubmissionCursor(params.Cursor)
+		if err != nil {
+			return nil, apperrors.NewBadRequestError("invalid cursor")
+		}
+		query.After = cursor
+	}
+
+	submissions, err := s.subRepo.ListSubmissions(ctx, query)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list submissions", err)
+	}
+
+	page := &models.SubmissionPage{Submissions: submissions}
+	if len(submissions) > limit {
+		page.Submissions = submissions[:limit]
+		last := page.Submissions[limit-1]
+		page.NextCursor = encodeSubmissionCursor(repository.SubmissionCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return page, nil
+}
+
+func encodeSubmissionCursor(c repository.SubmissionCursor) string {
+	raw := fmt.Sprintf("%d:%s", c.CreatedAt.UnixMilli(), c.ID.Hex())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSubmissionCursor(cursor string) (*repository.SubmissionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	millis, hexID, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository.SubmissionCursor{CreatedAt: time.UnixMilli(ms), ID: id}, nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSubmissionCursorRoundTrip(t *testing.T) {
+	want := repository.SubmissionCursor{
+		CreatedAt: time.UnixMilli(1700000000123),
+		ID:        primitive.NewObjectID(),
+	}
+
+	got, err := decodeSubmissionCursor(encodeSubmissionCursor(want))
+	if err != nil {
+		t.Fatalf("decodeSubmissionCursor() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("decodeSubmissionCursor() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestDecodeSubmissionCursorInvalid(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"no separator", encode("1700000000123")},
+		{"bad time", encode("abc:" + primitive.NewObjectID().Hex())},
+		{"bad ID", encode("1700000000123:xyz")},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeSubmissionCursor(tt.cursor); err == nil {
+				t.Errorf("decodeSubmissionCursor(%q) error = nil, want an error", tt.cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// inputMatcher checks a text answer. *regexp.Regexp implements it.
+type inputMatcher interface {
+	MatchString(s string) bool
+}
+
+// matcherFunc adapts validators that RE2 can not express, e.g. lookaheads.
+type matcherFunc func(s string) bool
+
+func (f matcherFunc) MatchString(s string) bool { return f(s) }
+
+// Named validators accepted in Question.Validation. They mirror
+// frontend/src/lib/validators/formValidators.ts so both sides agree on
+// what is valid.
+var inputValidators = map[string]inputMatcher{
+	"email":        regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`),
+	"phone":        regexp.MustCompile(`^(\+7|7|8)?[\s\-]?\(?[489][0-9]{2}\)?[\s\-]?[0-9]{3}[\s\-]?[0-9]{2}[\s\-]?[0-9]{2}$`),
+	"name":         regexp.MustCompile(`^[A-Za-zА-Яа-яЁё\s'-]{2,50}$`),
+	"username":     regexp.MustCompile(`^[a-zA-Z0-9_-]{3,20}$`),
+	"password":     matcherFunc(isStrongPassword),
+	"url":          regexp.MustCompile(`^(https?://)?([\da-z.-]+)\.([a-z.]{2,6})([/\w .-]*)*/?$`),
+	"date":         regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])-(0[1-9]|[12]\d|3[01])$`),
+	"time":         regexp.MustCompile(`^([01]\d|2[0-3]):([0-5]\d)$`),
+	"datetime":     regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])-(0[1-9]|[12]\d|3[01])T([01]\d|2[0-3]):([0-5]\d)$`),
+	"color":        regexp.MustCompile(`^#([A-Fa-f0-9]{6}|[A-Fa-f0-9]{3})$`),
+	"ipv4":         regexp.MustCompile(`^(\d{1,3}\.){3}\d{1,3}$`),
+	"ipv6":         regexp.MustCompile(`^([0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}$`),
+	"mac":          regexp.MustCompile(`^([0-9A-Fa-f]{2}[:-]){5}([0-9A-Fa-f]{2})$`),
+	"latitude":     regexp.MustCompile(`^-?([1-8]?\d(?:\.\d{1,})?|90(?:\.0{1,})?)$`),
+	"longitude":    regexp.MustCompile(`^-?((?:1[0-7]|[1-9])?\d(?:\.\d{1,})?|180(?:\.0{1,})?)$`),
+	"number":       regexp.MustCompile(`^-?\d*\.?\d+$`),
+	"integer":      regexp.MustCompile(`^-?\d+$`),
+	"float":        regexp.MustCompile(`^-?\d*\.\d+$`),
+	"alphanumeric": regexp.MustCompile(`^[a-zA-Z0-9]+$`),
+	"text":         regexp.MustCompile(`^.+$`),
+	"cyrillicText": regexp.MustCompile(`^[а-яА-ЯёЁ\s.,!?-]{1,}$`),
+	"passport":     regexp.MustCompile(`^(\d{4})\s*(\d{6})$`),
+	"inn":          regexp.MustCompile(`^(\d{10}|\d{12})$`),
+	"snils":        regexp.MustCompile(`^\d{3}-\d{3}-\d{3}\s\d{2}$`),
+	"creditCard":   regexp.MustCompile(`^(?:4[0-9]{12}(?:[0-9]{3})?|5[1-5][0-9]{14}|3[47][0-9]{13}|3(?:0[0-5]|[68][0-9])[0-9]{11}|6(?:011|5[0-9]{2})[0-9]{12}|(?:2131|1800|35\d{3})\d{11})$`),
+}
+
+// isStrongPassword requires at least 8 characters with a digit, a lowercase
+// and an uppercase letter.
+func isStrongPassword(s string) bool {
+	var digit, lower, upper bool
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		}
+	}
+	return utf8.RuneCountInString(s) >= 8 && digit && lower && upper
+}
+
+const ratingEpsilon = 1e-9
+
+// AnswerError describes why the answer to a single question was rejected.
+type AnswerError struct {
+	QuestionID int    `json:"questionId"`
+	Message    string `json:"message"`
+}
+
+// SubmissionValidationError is returned when answers do not match the form
+// definition. Handlers respond to it with 422 and the per-question details.
+type SubmissionValidationError struct {
+	Errors []AnswerError
+}
+
+func (e *SubmissionValidationError) Error() string {
+	return fmt.Sprintf("%d invalid answer(s)", len(e.Errors))
+}
+
+type SubmissionValidator struct {
+	patterns sync.Map // custom regex patterns from Question.Validation
+}
+
+func NewSubmissionValidator() *SubmissionValidator {
+	return &SubmissionValidator{}
+}
+
+// Validate checks every answer of the submission against its question
+// in the form and returns a *SubmissionValidationError listing all problems.
+func (v *SubmissionValidator) Validate(form *models.Form, sub *models.Submission) error {
+	questions := make(map[int]*models.Question, len(form.Questions))
+	for i := range form.Questions {
+		questions[form.Questions[i].ID] = &form.Questions[i]
+	}
+
+	var errs []AnswerError
+	seen := make(map[int]bool, len(sub.Answers))
+	for _, answer := range sub.Answers {
+		if seen[answer.QuestionID] {
+			errs = append(errs, AnswerError{QuestionID: answer.QuestionID, Message: "question answered more than once"})
+			continue
+		}
+		seen[answer.QuestionID] = true
+
+		question, ok := questions[answer.QuestionID]
+		if !ok {
+			errs = append(errs, AnswerError{QuestionID: answer.QuestionID, Message: "question does not exist in this form"})
+			continue
+		}
+
+		if msg := v.validateAnswer(question, answer); msg != "" {
+			errs = append(errs, AnswerError{QuestionID: answer.QuestionID, Message: msg})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &SubmissionValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validateAnswer returns a human readable reason if the answer is invalid
+// for the question, or an empty string otherwise.
+func (v *SubmissionValidator) validateAnswer(q *models.Question, a models.Answer) string {
+	switch q.Type {
+	case "single-choice":
+		if len(a.OptionIDs) != 1 {
+			return "exactly one option must be selected"
+		}
+		return validateOptions(q, a.OptionIDs)
+	case "multiple-choice":
+		if len(a.OptionIDs) == 0 {
+			return "at least one option must be selected"
+		}
+		if q.MaxSelections > 0 && len(a.OptionIDs) > q.MaxSelections {
+			return fmt.Sprintf("no more than %d options can be selected", q.MaxSelections)
+		}
+		return validateOptions(q, a.OptionIDs)
+	case "rating":
+		if a.Rating == nil {
+			return "rating value is required"
+		}
+		return validateRating(q, *a.Rating)
+	case "input":
+		return v.validateInput(q, a.Text)
+	default:
+		return fmt.Sprintf("unsupported question type %q", q.Type)
+	}
+}
+
+func validateOptions(q *models.Question, optionIDs []int) string {
+	options := make(map[int]bool, len(q.Options))
+	for _, o := range q.Options {
+		options[o.ID] = true
+	}
+
+	selected := make(map[int]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		if !options[id] {
+			return fmt.Sprintf("option %d does not exist", id)
+		}
+		if selected[id] {
+			return fmt.Sprintf("option %d is selected more than once", id)
+		}
+		selected[id] = true
+	}
+	return ""
+}
+
+func validateRating(q *models.Question, value float64) string {
+	if q.MaxValue > q.MinValue {
+		if value < float64(q.MinValue) || value > float64(q.MaxValue) {
+			return fmt.Sprintf("rating must be between %d and %d", q.MinValue, q.MaxValue)
+		}
+	}
+
+	step := q.Step
+	if step <= 0 {
+		step = 1
+	}
+	steps := (value - float64(q.MinValue)) / step
+	if math.Abs(steps-math.Round(steps)) > ratingEpsilon {
+		return fmt.Sprintf("rating must be a multiple of %g starting from %d", step, q.MinValue)
+	}
+	return ""
+}
+
+func (v *SubmissionValidator) validateInput(q *models.Question, text string) string {
+	if q.Validation == "" {
+		return ""
+	}
+
+	pattern, err := v.pattern(q.Validation)
+	if err != nil {
+		// A broken pattern is a problem with the form, not with the answer
+		logger.Error("Skipping invalid validation pattern",
+			zap.Int("questionId", q.ID),
+			zap.Error(err))
+		return ""
+	}
+	if pattern == nil {
+		return ""
+	}
+	// Surrounding whitespace is ignored, like in the frontend validators
+	if !pattern.MatchString(strings.TrimSpace(text)) {
+		if _, named := inputValidators[q.Validation]; named {
+			return fmt.Sprintf("answer is not a valid %s", q.Validation)
+		}
+		return "answer does not match the required format"
+	}
+	return ""
+}
+
+// pattern resolves Question.Validation to a matcher. It accepts a named
+// validator or a JavaScript style literal like /^\d+$/i. Unknown names
+// return nil, so a validator added to the editor later is not mistaken for
+// a regular expression.
+func (v *SubmissionValidator) pattern(validation string) (inputMatcher, error) {
+	if re, ok := inputValidators[validation]; ok {
+		return re, nil
+	}
+	if cached, ok := v.patterns.Load(validation); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	end := strings.LastIndex(validation, "/")
+	if !strings.HasPrefix(validation, "/") || end < 1 {
+		logger.Info("Ignoring unknown validator", zap.String("validation", validation))
+		return nil, nil
+	}
+	expr := validation[1:end]
+	if strings.Contains(validation[end+1:], "i") {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validation pattern %q: %w", validation, err)
+	}
+	v.patterns.Store(validation, re)
+	return re, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+)
+
+func TestSubmissionValidatorInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		validation string
+		text       string
+		wantErr    bool
+	}{
+		{"email ok", "email", "user@example.com", false},
+		{"email bad", "email", "user@example", true},
+		{"password ok", "password", "Secret123", false},
+		{"password no upper", "password", "secret123", true},
+		{"password too short", "password", "Sec123", true},
+		{"datetime ok", "datetime", "2024-02-29T23:59", false},
+		{"datetime bad", "datetime", "2024-02-29 23:59", true},
+		{"color ok", "color", "#a1B", false},
+		{"color bad", "color", "#ggg", true},
+		{"ipv4 ok", "ipv4", "192.168.0.1", false},
+		{"ipv6 ok", "ipv6", "2001:0db8:85a3:0000:0000:8a2e:0370:7334", false},
+		{"ipv6 bad", "ipv6", "2001:db8::1", true},
+		{"mac ok", "mac", "00:1A:2b:3C:4d:5E", false},
+		{"latitude ok", "latitude", "-89.5", false},
+		{"latitude bad", "latitude", "91", true},
+		{"longitude ok", "longitude", "179.99", false},
+		{"longitude bad", "longitude", "181", true},
+		{"float ok", "float", "-0.5", false},
+		{"float bad", "float", "5", true},
+		{"cyrillic ok", "cyrillicText", "Привет, мир!", false},
+		{"cyrillic bad", "cyrillicText", "hello", true},
+		{"passport ok", "passport", "4510 123456", false},
+		{"inn 10 digits", "inn", "7707083893", false},
+		{"inn 12 digits", "inn", "500100732259", false},
+		{"inn 11 digits", "inn", "77070838931", true},
+		{"inn 10 digits followed by letters", "inn", "7707083893abc", true},
+		{"inn 12 digits after letters", "inn", "abc500100732259", true},
+		{"inn padded", "inn", " 7707083893\t", false},
+		{"email padded", "email", "  user@example.com ", false},
+		{"phone padded", "phone", " +7 912 345-67-89 ", false},
+		{"text only whitespace", "text", "   ", true},
+		{"regex literal padded", "/^\\d{3}$/", " 123 ", false},
+		{"snils ok", "snils", "112-233-445 95", false},
+		{"credit card ok", "creditCard", "4111111111111111", false},
+		{"credit card bad", "creditCard", "1234567890123456", true},
+		{"regex literal", "/^\\d{3}$/", "123", false},
+		{"regex literal mismatch", "/^\\d{3}$/", "12a", true},
+		{"regex literal case flag", "/^abc$/i", "ABC", false},
+		{"unknown name is ignored", "postcode", "anything", false},
+		{"bare regex is ignored", "^\\d+$", "abc", false},
+		{"broken literal is ignored", "/[/", "abc", false},
+	}
+
+	v := NewSubmissionValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := &models.Form{Questions: []models.Question{
+				{ID: 1, Type: "input", Validation: tt.validation},
+			}}
+			sub := &models.Submission{Answers: []models.Answer{
+				{QuestionID: 1, Text: tt.text},
+			}}
+
+			err := v.Validate(form, sub)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate(%q, %q) error = %v, wantErr %v", tt.validation, tt.text, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSubmissionValidatorAnswers(t *testing.T) {
+	rating := func(v float64) *float64 { return &v }
+	form := &models.Form{Questions: []models.Question{
+		{ID: 1, Type: "single-choice", Options: []models.Option{{ID: 1}, {ID: 2}}},
+		{ID: 2, Type: "multiple-choice", MaxSelections: 2, Options: []models.Option{{ID: 1}, {ID: 2}, {ID: 3}}},
+		{ID: 3, Type: "rating", MinValue: 1, MaxValue: 5, Step: 0.5},
+	}}
+
+	tests := []struct {
+		name    string
+		answers []models.Answer
+		wantIDs []int
+	}{
+		{"valid answers", []models.Answer{
+			{QuestionID: 1, OptionIDs: []int{2}},
+			{QuestionID: 2, OptionIDs: []int{1, 3}},
+			{QuestionID: 3, Rating: rating(3.5)},
+		}, nil},
+		{"unknown option", []models.Answer{{QuestionID: 1, OptionIDs: []int{7}}}, []int{1}},
+		{"two options on single choice", []models.Answer{{QuestionID: 1, OptionIDs: []int{1, 2}}}, []int{1}},
+		{"too many selections", []models.Answer{{QuestionID: 2, OptionIDs: []int{1, 2, 3}}}, []int{2}},
+		{"duplicate option", []models.Answer{{QuestionID: 2, OptionIDs: []int{1, 1}}}, []int{2}},
+		{"rating out of range", []models.Answer{{QuestionID: 3, Rating: rating(6)}}, []int{3}},
+		{"rating off step", []models.Answer{{QuestionID: 3, Rating: rating(2.2)}}, []int{3}},
+		{"missing rating", []models.Answer{{QuestionID: 3}}, []int{3}},
+		{"unknown question", []models.Answer{{QuestionID: 9, OptionIDs: []int{1}}}, []int{9}},
+		{"answered twice", []models.Answer{
+			{QuestionID: 1, OptionIDs: []int{1}},
+			{QuestionID: 1, OptionIDs: []int{2}},
+		}, []int{1}},
+	}
+
+	v := NewSubmissionValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(form, &models.Submission{Answers: tt.answers})
+			if tt.wantIDs == nil {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var verr *SubmissionValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() error = %v, want *SubmissionValidationError", err)
+			}
+			if len(verr.Errors) != len(tt.wantIDs) {
+				t.Fatalf("Validate() errors = %+v, want questions %v", verr.Errors, tt.wantIDs)
+			}
+			for i, id := range tt.wantIDs {
+				if verr.Errors[i].QuestionID != id {
+					t.Errorf("error %d question = %d, want %d", i, verr.Errors[i].QuestionID, id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// builtinTemplatePrefix marks IDs of templates loaded from files, so they
+// can not be confused with form IDs.
+const builtinTemplatePrefix = "builtin:"
+
+type TemplateService struct {
+	formService *FormService
+	formRepo    *repository.FormRepository
+	builtins    []models.FormTemplate
+}
+
+func NewTemplateService(formService *FormService, formRepo *repository.FormRepository, builtins []models.FormTemplate) *TemplateService {
+	return &TemplateService{
+		formService: formService,
+		formRepo:    formRepo,
+		builtins:    builtins,
+	}
+}
+
+// LoadTemplates reads built-in templates from the JSON files in dir. The
+// files have the same format as a form, the file name becomes the template ID.
+func LoadTemplates(dir string) ([]models.FormTemplate, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	templates := make([]models.FormTemplate, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var form models.Form
+		if err := json.Unmarshal(data, &form); err != nil {
+			return nil, fmt.Errorf("invalid template %s: %w", path, err)
+		}
+		AssignOptionIDs(&form)
+		if errs, _ := splitIssues(ValidateFormGraph(&form)); form.Name == "" || len(errs) > 0 {
+			return nil, fmt.Errorf("invalid template %s: name is missing or questions have errors", path)
+		}
+
+		templates = append(templates, models.FormTemplate{
+			ID:      builtinTemplatePrefix + strings.TrimSuffix(filepath.Base(path), ".json"),
+			Builtin: true,
+			Form:    models.NewFormSnapshot(&form),
+		})
+	}
+	return templates, nil
+}
+
+// AssignOptionIDs gives options without an ID, or with an ID an earlier
+// option of the question already has, a new ID above the largest one in
+// their question. Template files and generated forms often get them wrong.
+func AssignOptionIDs(form *models.Form) {
+	for i := range form.Questions {
+		q := &form.Questions[i]
+		next := 0
+		for _, o := range q.Options {
+			if o.ID > next {
+				next = o.ID
+			}
+		}
+		seen := make(map[int]bool, len(q.Options))
+		for j := range q.Options {
+			if q.Options[j].ID <= 0 || seen[q.Options[j].ID] {
+				next++
+				q.Options[j].ID = next
+			}
+			seen[q.Options[j].ID] = true
+		}
+	}
+}
+
+// ListTemplates returns the built-in templates followed by the templates of workspaceID.
+func (s *TemplateService) ListTemplates(ctx context.Context, workspaceID string) ([]models.FormTemplate, error) {
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	forms, err := s.formRepo.ListTemplates(ctx, workspaceObjectID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list templates", err)
+	}
+
+	templates := make([]models.FormTemplate, 0, len(s.builtins)+len(forms))
+	templates = append(templates, s.builtins...)
+	for i := range forms {
+		templates = append(templates, models.FormTemplate{
+			ID:   forms[i].ID.Hex(),
+			Form: models.NewFormSnapshot(&forms[i]),
+		})
+	}
+	return templates, nil
+}
+
+// CreateFromTemplate creates a draft form of workspaceID from a built-in template
+// or from one of the workspace's own templates. An empty name keeps the template name.
+func (s *TemplateService) CreateFromTemplate(templateID, workspaceID, name string) (*models.Form, []FormIssue, error) {
+	var snapshot *models.FormSnapshot
+	if strings.HasPrefix(templateID, builtinTemplatePrefix) {
+		for i := range s.builtins {
+			if s.builtins[i].ID == templateID {
+				snapshot = &s.builtins[i].Form
+				break
+			}
+		}
+		if snapshot == nil {
+			return nil, nil, apperrors.NewNotFoundError("template not found")
+		}
+	} else {
+		source, err := loadOwnedForm(s.formRepo, templateID, workspaceID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !source.IsTemplate {
+			return nil, nil, apperrors.NewNotFoundError("template not found")
+		}
+		own := models.NewFormSnapshot(source)
+		snapshot = &own
+	}
+
+	form, err := newFormFromSnapshot(*snapshot, workspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if name != "" {
+		form.Name = name
+	}
+
+	warnings, err := s.formService.CreateForm(form)
+	if err != nil {
+		return nil, nil, err
+	}
+	return form, warnings, nil
+}
+
+// DuplicateForm copies a form of workspaceID into a new draft.
+func (s *TemplateService) DuplicateForm(formID, workspaceID string) (*models.Form, []FormIssue, error) {
+	source, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	form, err := newFormFromSnapshot(models.NewFormSnapshot(source), workspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	form.Name = source.Name + " (copy)"
+	form.ClosedMessage = source.ClosedMessage
+	form.MaxResponses = source.MaxResponses
+	form.FolderID = source.FolderID
+	form.Tags = append([]string(nil), source.Tags...)
+
+	warnings, err := s.formService.CreateForm(form)
+	if err != nil {
+		return nil, nil, err
+	}
+	return form, warnings, nil
+}
+
+// newFormFromSnapshot builds an unsaved draft with a deep copy of the
+// snapshot content, so the new form shares no slices with its source.
+func newFormFromSnapshot(snapshot models.FormSnapshot, workspaceID string) (*models.Form, error) {
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	data, err := bson.Marshal(snapshot)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to copy form", err)
+	}
+	var copied models.FormSnapshot
+	if err := bson.Unmarshal(data, &copied); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to copy form", err)
+	}
+
+	form := &models.Form{
+		WorkspaceID: workspaceObjectID,
+		IsDraft:     true,
+	}
+	copied.Apply(form)
+	return form, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+)
+
+func TestAssignOptionIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []int
+		want []int
+	}{
+		{"keeps valid IDs", []int{1, 2, 3}, []int{1, 2, 3}},
+		{"fills missing IDs", []int{0, 0, 0}, []int{1, 2, 3}},
+		{"new IDs go above the largest", []int{2, 0, 0}, []int{2, 3, 4}},
+		{"missing before existing", []int{0, 1}, []int{2, 1}},
+		{"duplicates", []int{1, 1, 2}, []int{1, 3, 2}},
+		{"negative IDs", []int{-1, 5}, []int{6, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := make([]models.Option, len(tt.ids))
+			for i, id := range tt.ids {
+				options[i].ID = id
+			}
+			form := &models.Form{Questions: []models.Question{{Type: "single-choice", Options: options}}}
+
+			AssignOptionIDs(form)
+
+			got := make([]int, len(options))
+			for i, o := range form.Questions[0].Options {
+				got[i] = o.ID
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AssignOptionIDs(%v) = %v, want %v", tt.ids, got, tt.want)
+			}
+		})
+	}
+}
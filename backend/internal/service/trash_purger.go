@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	trashPollInterval = time.Hour
+	trashPurgeBatch   = 100
+)
+
+// TrashOptions controls how long deleted forms are kept and what is
+// removed together with them.
+type TrashOptions struct {
+	Retention time.Duration
+	// Remove the submissions and progress events of purged forms
+	PurgeSubmissions bool
+	// Remove library images that no other form or revision references
+	PurgeImages bool
+}
+
+// TrashFormStore lists and removes forms in the trash,
+// *repository.FormRepository implements it.
+type TrashFormStore interface {
+	ListDeletedBefore(ctx context.Context, before time.Time, limit int64) ([]models.Form, error)
+	MarkPurging(ctx context.Context, formID primitive.ObjectID, before time.Time) error
+	PurgeForm(ctx context.Context, formID primitive.ObjectID, before time.Time) error
+	ImageInUse(ctx context.Context, workspaceID, formID primitive.ObjectID, url string) (bool, error)
+}
+
+// TrashRevisionStore removes the revisions of purged forms,
+// *repository.RevisionRepository implements it.
+type TrashRevisionStore interface {
+	DeleteRevisions(ctx context.Context, formID primitive.ObjectID) error
+	ImageInUse(ctx context.Context, workspaceID, formID primitive.ObjectID, url string) (bool, error)
+}
+
+// SubmissionDeleter removes the submissions of a form,
+// *repository.SubmissionRepository implements it.
+type SubmissionDeleter interface {
+	DeleteSubmissions(ctx context.Context, formID primitive.ObjectID) (int64, error)
+}
+
+// ProgressDeleter removes the progress events of a form,
+// *repository.ProgressRepository implements it.
+type ProgressDeleter interface {
+	DeleteEvents(ctx context.Context, formID primitive.ObjectID) error
+}
+
+// WebhookDeleter removes the webhooks of a form,
+// *repository.WebhookRepository implements it.
+type WebhookDeleter interface {
+	DeleteFormWebhooks(ctx context.Context, formID primitive.ObjectID) error
+}
+
+// NotificationDeleter removes the notification settings of a form,
+// *repository.NotificationRepository implements it.
+type NotificationDeleter interface {
+	DeleteSettings(ctx context.Context, formID primitive.ObjectID) error
+}
+
+// ImageLibrary finds and deletes library images, *ImageService implements it.
+type ImageLibrary interface {
+	FindByURLs(workspaceID string, urls []string) ([]*models.Image, error)
+	Delete(imageID string) error
+}
+
+// TrashPurger permanently removes forms that stayed in the trash longer
+// than the retention period. Revisions, webhooks and notification settings
+// of a purged form are always removed.
+type TrashPurger struct {
+	formRepo         TrashFormStore
+	revisionRepo     TrashRevisionStore
+	subRepo          SubmissionDeleter
+	progressRepo     ProgressDeleter
+	webhookRepo      WebhookDeleter
+	notificationRepo NotificationDeleter
+	imageService     ImageLibrary
+	options          TrashOptions
+}
+
+func NewTrashPurger(
+	formRepo TrashFormStore,
+	revisionRepo TrashRevisionStore,
+	subRepo SubmissionDeleter,
+	progressRepo ProgressDeleter,
+	webhookRepo WebhookDeleter,
+	notificationRepo NotificationDeleter,
+	imageService ImageLibrary,
+	options TrashOptions,
+) *TrashPurger {
+	return &TrashPurger{
+		formRepo:         formRepo,
+		revisionRepo:     revisionRepo,
+		subRepo:          subRepo,
+		progressRepo:     progressRepo,
+		webhookRepo:      webhookRepo,
+		notificationRepo: notificationRepo,
+		imageService:     imageService,
+		options:          options,
+	}
+}
+
+// Run purges expired forms until ctx is cancelled.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(trashPollInterval)
+	defer ticker.Stop()
+
+	for {
+		p.purgeExpired(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) purgeExpired(ctx context.Context) {
+	cutoff := time.Now().Add(-p.options.Retention)
+
+	for ctx.Err() == nil {
+		forms, err := p.formRepo.ListDeletedBefore(ctx, cutoff, trashPurgeBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error("Failed to list expired forms", zap.Error(err))
+			}
+			return
+		}
+
+		failed := false
+		for i := range forms {
+			if err := p.purgeForm(ctx, &forms[i], cutoff); err != nil {
+				if errors.Is(err, repository.ErrFormNotFound) {
+					// Restored after it was listed
+					continue
+				}
+				// Left in the trash, the next run tries again
+				logger.Error("Failed to purge form",
+					zap.String("formId", forms[i].ID.Hex()),
+					zap.Error(err))
+				failed = true
+				continue
+			}
+			logger.Info("Form purged from trash", zap.String("formId", forms[i].ID.Hex()))
+		}
+
+		// A failing form would be listed again, wait for the next run instead
+		if failed || len(forms) < trashPurgeBatch {
+			return
+		}
+	}
+}
+
+// purgeForm first marks the form so it can not be restored while its data
+// is removed, and removes the form last, so a purge that fails halfway is
+// picked up again by the next run.
+func (p *TrashPurger) purgeForm(ctx context.Context, form *models.Form, cutoff time.Time) error {
+	if err := p.formRepo.MarkPurging(ctx, form.ID, cutoff); err != nil {
+		return err
+	}
+	if p.options.PurgeSubmissions {
+		if _, err := p.subRepo.DeleteSubmissions(ctx, form.ID); err != nil {
+			return err
+		}
+		if err := p.progressRepo.DeleteEvents(ctx, form.ID); err != nil {
+			return err
+		}
+	}
+	if err := p.revisionRepo.DeleteRevisions(ctx, form.ID); err != nil {
+		return err
+	}
+	if err := p.webhookRepo.DeleteFormWebhooks(ctx, form.ID); err != nil {
+		return err
+	}
+	if err := p.notificationRepo.DeleteSettings(ctx, form.ID); err != nil {
+		return err
+	}
+	if p.options.PurgeImages {
+		if err := p.purgeImages(ctx, form); err != nil {
+			return err
+		}
+	}
+	return p.formRepo.PurgeForm(ctx, form.ID, cutoff)
+}
+
+// purgeImages deletes the library images of a form that nothing else uses.
+func (p *TrashPurger) purgeImages(ctx context.Context, form *models.Form) error {
+	var unused []string
+	for _, url := range formImageURLs(form) {
+		inForms, err := p.formRepo.ImageInUse(ctx, form.WorkspaceID, form.ID, url)
+		if err != nil {
+			return err
+		}
+		if inForms {
+			continue
+		}
+		inRevisions, err := p.revisionRepo.ImageInUse(ctx, form.WorkspaceID, form.ID, url)
+		if err != nil {
+			return err
+		}
+		if !inRevisions {
+			unused = append(unused, url)
+		}
+	}
+
+	images, err := p.imageService.FindByURLs(form.WorkspaceID.Hex(), unused)
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		if err := p.imageService.Delete(image.ID.Hex()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
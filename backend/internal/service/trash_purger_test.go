@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPurgeForm(t *testing.T) {
+	tests := []struct {
+		name    string
+		options TrashOptions
+		want    []string
+	}{
+		{
+			name: "form data only",
+			want: []string{"MarkPurging", "DeleteRevisions", "DeleteFormWebhooks", "DeleteSettings", "PurgeForm"},
+		},
+		{
+			name:    "with submissions and images",
+			options: TrashOptions{PurgeSubmissions: true, PurgeImages: true},
+			want: []string{
+				"MarkPurging", "DeleteSubmissions", "DeleteEvents", "DeleteRevisions",
+				"DeleteFormWebhooks", "DeleteSettings", "DeleteImage", "PurgeForm",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTrashTestEnv(tt.options)
+			form := env.addForm(env.expired, "/uploads/a.png")
+			env.images.add(form.WorkspaceID.Hex(), "/uploads/a.png")
+
+			if err := env.purger.purgeForm(context.Background(), form, env.cutoff); err != nil {
+				t.Fatalf("purgeForm() error = %v", err)
+			}
+			if !reflect.DeepEqual(env.log.calls, tt.want) {
+				t.Errorf("calls = %q, want %q", env.log.calls, tt.want)
+			}
+			if _, ok := env.forms.byID[form.ID]; ok {
+				t.Error("form was not removed")
+			}
+		})
+	}
+}
+
+func TestPurgeFormRestored(t *testing.T) {
+	env := newTrashTestEnv(TrashOptions{PurgeSubmissions: true, PurgeImages: true})
+	form := env.addForm(env.expired, "/uploads/a.png")
+	// Restored after the purger listed it
+	env.forms.byID[form.ID].DeletedAt = nil
+
+	err := env.purger.purgeForm(context.Background(), form, env.cutoff)
+	if !errors.Is(err, repository.ErrFormNotFound) {
+		t.Fatalf("purgeForm() error = %v, want ErrFormNotFound", err)
+	}
+	if want := []string{"MarkPurging"}; !reflect.DeepEqual(env.log.calls, want) {
+		t.Errorf("calls = %q, want %q", env.log.calls, want)
+	}
+}
+
+func TestPurgeExpiredRetriesFailedForms(t *testing.T) {
+	env := newTrashTestEnv(TrashOptions{PurgeSubmissions: true})
+	failing := env.addForm(env.expired)
+	other := env.addForm(env.expired)
+	recent := env.addForm(env.cutoff.Add(time.Hour))
+	env.log.fail["DeleteEvents"] = 1
+
+	env.purger.purgeExpired(context.Background())
+
+	if stored, ok := env.forms.byID[failing.ID]; !ok || !stored.Purging {
+		t.Fatal("form that failed to purge should stay in the trash marked as purging")
+	}
+	if _, ok := env.forms.byID[other.ID]; ok {
+		t.Error("a failure stopped the purge of the other expired form")
+	}
+
+	env.log.calls = nil
+	env.purger.purgeExpired(context.Background())
+
+	if _, ok := env.forms.byID[failing.ID]; ok {
+		t.Error("form was not purged by the next run")
+	}
+	want := []string{"MarkPurging", "DeleteSubmissions", "DeleteEvents", "DeleteRevisions", "DeleteFormWebhooks", "DeleteSettings", "PurgeForm"}
+	if !reflect.DeepEqual(env.log.calls, want) {
+		t.Errorf("calls of the next run = %q, want %q", env.log.calls, want)
+	}
+	if stored, ok := env.forms.byID[recent.ID]; !ok || stored.Purging {
+		t.Error("form deleted after the cutoff was purged")
+	}
+}
+
+func TestPurgeImages(t *testing.T) {
+	env := newTrashTestEnv(TrashOptions{PurgeImages: true})
+	form := env.addForm(env.expired,
+		"/uploads/unused.png",
+		"/uploads/in-form.png",
+		"/uploads/in-revision.png",
+		"/uploads/not-in-library.png",
+	)
+	for _, url := range []string{"/uploads/unused.png", "/uploads/in-form.png", "/uploads/in-revision.png"} {
+		env.images.add(form.WorkspaceID.Hex(), url)
+	}
+	// Images of another workspace with the same URL are never touched
+	env.images.add(primitive.NewObjectID().Hex(), "/uploads/unused.png")
+	env.forms.inUse["/uploads/in-form.png"] = true
+	env.revisions.inUse["/uploads/in-revision.png"] = true
+
+	if err := env.purger.purgeImages(context.Background(), form); err != nil {
+		t.Fatalf("purgeImages() error = %v", err)
+	}
+	if want := []string{"/uploads/unused.png"}; !reflect.DeepEqual(env.images.deletedURLs(), want) {
+		t.Errorf("deleted %q, want %q", env.images.deletedURLs(), want)
+	}
+
+	t.Run("failed image deletion", func(t *testing.T) {
+		env := newTrashTestEnv(TrashOptions{PurgeImages: true})
+		form := env.addForm(env.expired, "/uploads/unused.png")
+		env.images.add(form.WorkspaceID.Hex(), "/uploads/unused.png")
+		env.log.fail["DeleteImage"] = 1
+
+		if err := env.purger.purgeForm(context.Background(), form, env.cutoff); err == nil {
+			t.Fatal("purgeForm() error = nil, want an error")
+		}
+		if _, ok := env.forms.byID[form.ID]; !ok {
+			t.Error("form was removed although its images were not")
+		}
+	})
+}
+
+type trashTestEnv struct {
+	purger    *TrashPurger
+	log       *trashCallLog
+	forms     *fakeTrashForms
+	revisions *fakeTrashRevisions
+	images    *fakeImageLibrary
+	cutoff    time.Time
+	expired   time.Time
+}
+
+func newTrashTestEnv(options TrashOptions) *trashTestEnv {
+	options.Retention = 30 * 24 * time.Hour
+	log := &trashCallLog{fail: map[string]int{}}
+	env := &trashTestEnv{
+		log:       log,
+		forms:     &fakeTrashForms{log: log, byID: map[primitive.ObjectID]*models.Form{}, inUse: map[string]bool{}},
+		revisions: &fakeTrashRevisions{log: log, inUse: map[string]bool{}},
+		images:    &fakeImageLibrary{log: log},
+		// purgeExpired computes its own cutoff, keep a margin to it
+		cutoff:  time.Now().Add(-options.Retention - time.Minute),
+		expired: time.Now().Add(-options.Retention - time.Hour),
+	}
+	data := fakeFormData{log: log}
+	env.purger = NewTrashPurger(env.forms, env.revisions, data, data, data, data, env.images, options)
+	return env
+}
+
+// addForm puts a form deleted at deletedAt with the given question images
+// into the trash.
+func (e *trashTestEnv) addForm(deletedAt time.Time, images ...string) *models.Form {
+	form := &models.Form{ID: primitive.NewObjectID(), WorkspaceID: primitive.NewObjectID(), DeletedAt: &deletedAt}
+	for i, url := range images {
+		form.Questions = append(form.Questions, models.Question{ID: i + 1, Image: url})
+	}
+	e.forms.byID[form.ID] = form
+	e.forms.order = append(e.forms.order, form.ID)
+	copied := *form
+	return &copied
+}
+
+// trashCallLog records the calls of all fakes in order. Calls listed in
+// fail fail as many times as given.
+type trashCallLog struct {
+	calls []string
+	fail  map[string]int
+}
+
+func (l *trashCallLog) record(call string) error {
+	l.calls = append(l.calls, call)
+	if l.fail[call] > 0 {
+		l.fail[call]--
+		return errors.New(call + " failed")
+	}
+	return nil
+}
+
+type fakeTrashForms struct {
+	log   *trashCallLog
+	byID  map[primitive.ObjectID]*models.Form
+	order []primitive.ObjectID
+	inUse map[string]bool
+}
+
+func (f *fakeTrashForms) ListDeletedBefore(ctx context.Context, before time.Time, limit int64) ([]models.Form, error) {
+	forms := []models.Form{}
+	for _, id := range f.order {
+		form, ok := f.byID[id]
+		if ok && form.DeletedAt != nil && form.DeletedAt.Before(before) && int64(len(forms)) < limit {
+			forms = append(forms, *form)
+		}
+	}
+	return forms, nil
+}
+
+func (f *fakeTrashForms) MarkPurging(ctx context.Context, formID primitive.ObjectID, before time.Time) error {
+	if err := f.log.record("MarkPurging"); err != nil {
+		return err
+	}
+	form, ok := f.byID[formID]
+	if !ok || form.DeletedAt == nil || !form.DeletedAt.Before(before) {
+		return repository.ErrFormNotFound
+	}
+	form.Purging = true
+	return nil
+}
+
+func (f *fakeTrashForms) PurgeForm(ctx context.Context, formID primitive.ObjectID, before time.Time) error {
+	if err := f.log.record("PurgeForm"); err != nil {
+		return err
+	}
+	form, ok := f.byID[formID]
+	if !ok || !form.Purging {
+		return repository.ErrFormNotFound
+	}
+	delete(f.byID, formID)
+	return nil
+}
+
+func (f *fakeTrashForms) ImageInUse(ctx context.Context, workspaceID, formID primitive.ObjectID, url string) (bool, error) {
+	return f.inUse[url], nil
+}
+
+type fakeTrashRevisions struct {
+	log   *trashCallLog
+	inUse map[string]bool
+}
+
+func (f *fakeTrashRevisions) DeleteRevisions(ctx context.Context, formID primitive.ObjectID) error {
+	return f.log.record("DeleteRevisions")
+}
+
+func (f *fakeTrashRevisions) ImageInUse(ctx context.Context, workspaceID, formID primitive.ObjectID, url string) (bool, error) {
+	return f.inUse[url], nil
+}
+
+// fakeFormData stands in for the submission, progress, webhook and
+// notification repositories.
+type fakeFormData struct {
+	log *trashCallLog
+}
+
+func (f fakeFormData) DeleteSubmissions(ctx context.Context, formID primitive.ObjectID) (int64, error) {
+	return 0, f.log.record("DeleteSubmissions")
+}
+
+func (f fakeFormData) DeleteEvents(ctx context.Context, formID primitive.ObjectID) error {
+	return f.log.record("DeleteEvents")
+}
+
+func (f fakeFormData) DeleteFormWebhooks(ctx context.Context, formID primitive.ObjectID) error {
+	return f.log.record("DeleteFormWebhooks")
+}
+
+func (f fakeFormData) DeleteSettings(ctx context.Context, formID primitive.ObjectID) error {
+	return f.log.record("DeleteSettings")
+}
+
+type fakeImageLibrary struct {
+	log     *trashCallLog
+	images  []*models.Image
+	deleted []*models.Image
+}
+
+func (f *fakeImageLibrary) add(workspaceID, url string) {
+	f.images = append(f.images, &models.Image{ID: primitive.NewObjectID(), WorkspaceID: workspaceID, URL: url})
+}
+
+func (f *fakeImageLibrary) deletedURLs() []string {
+	var urls []string
+	for _, image := range f.deleted {
+		urls = append(urls, image.URL)
+	}
+	return urls
+}
+
+func (f *fakeImageLibrary) FindByURLs(workspaceID string, urls []string) ([]*models.Image, error) {
+	var found []*models.Image
+	for _, image := range f.images {
+		for _, url := range urls {
+			if image.WorkspaceID == workspaceID && image.URL == url {
+				found = append(found, image)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeImageLibrary) Delete(imageID string) error {
+	if err := f.log.record("DeleteImage"); err != nil {
+		return err
+	}
+	for i, image := range f.images {
+		if image.ID.Hex() == imageID {
+			f.deleted = append(f.deleted, image)
+			f.images = append(f.images[:i], f.images[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("image not found")
+}
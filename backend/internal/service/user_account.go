@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/notifier"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+	passwordResetTimeout  = 30 * time.Second
+	minPasswordLength     = 8
+)
+
+// SendVerificationEmail emails userID a new verification link. Links sent
+// earlier stop working.
+func (s *UserService) SendVerificationEmail(ctx context.Context, userID string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return apperrors.NewNotFoundError("user not found")
+	}
+	if user.EmailVerifiedAt != nil {
+		return apperrors.NewConflictError("email is already verified")
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// VerifyEmail marks the email of the user the token was sent to as verified.
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	userToken, err := s.consumeUserToken(ctx, token, models.UserTokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.SetEmailVerified(ctx, userToken.UserID, time.Now()); err != nil {
+		return apperrors.NewInternalServerError("failed to verify email", err)
+	}
+	return nil
+}
+
+// ForgotPassword emails a password reset link if an account with email
+// exists. It succeeds either way, so it can not be used to find accounts.
+// The account is looked up and the email sent in the background, otherwise
+// the response time would tell whether the account exists.
+func (s *UserService) ForgotPassword(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+
+	s.emails.Add(1)
+	go func() {
+		defer s.emails.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetTimeout)
+		defer cancel()
+		s.sendPasswordReset(ctx, email)
+	}()
+	return nil
+}
+
+// WaitForEmails blocks until the emails sent in the background are done.
+func (s *UserService) WaitForEmails() {
+	s.emails.Wait()
+}
+
+func (s *UserService) sendPasswordReset(ctx context.Context, email string) {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		logger.Info("Password reset requested for unknown email")
+		return
+	}
+	if s.sender == nil {
+		logger.Info("Email sender is not configured, password reset email skipped", zap.String("userId", user.ID.Hex()))
+		return
+	}
+
+	token, err := s.createUserToken(ctx, user.ID, models.UserTokenResetPassword, resetPasswordTokenTTL)
+	if err != nil {
+		logger.Error("Failed to create password reset token", zap.String("userId", user.ID.Hex()), zap.Error(err))
+		return
+	}
+
+	var body strings.Builder
+	body.WriteString("A password reset was requested for your account.\n")
+	s.writeTokenLink(&body, "Reset your password", "/reset-password", token)
+	body.WriteString("\nThe link expires in 1 hour. If you did not request it, you can ignore this email.\n")
+
+	msg := notifier.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your password",
+		Body:    body.String(),
+	}
+	if err := s.sender.Send(ctx, msg); err != nil {
+		logger.Error("Failed to send password reset email", zap.String("userId", user.ID.Hex()), zap.Error(err))
+	}
+}
+
+// ResetPassword sets a new password for the user the token was sent to and
+// signs the user out everywhere. Receiving the email also proves the user
+// owns the address, so it is marked as verified.
+func (s *UserService) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < minPasswordLength {
+		return apperrors.NewBadRequestError(fmt.Sprintf("password must be at least %d characters", minPasswordLength))
+	}
+
+	userToken, err := s.consumeUserToken(ctx, token, models.UserTokenResetPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to hash password", err)
+	}
+	if err := s.userRepo.UpdatePassword(ctx, userToken.UserID, string(hashedPassword)); err != nil {
+		return apperrors.NewInternalServerError("failed to update password", err)
+	}
+	if err := s.userRepo.SetEmailVerified(ctx, userToken.UserID, time.Now()); err != nil {
+		logger.Error("Failed to mark email as verified", zap.Error(err))
+	}
+
+	return s.LogoutAll(ctx, userToken.UserID.Hex())
+}
+
+func (s *UserService) sendVerification(ctx context.Context, user *models.User) error {
+	if s.sender == nil {
+		logger.Info("Email sender is not configured, verification email skipped", zap.String("userId", user.ID.Hex()))
+		return nil
+	}
+
+	token, err := s.createUserToken(ctx, user.ID, models.UserTokenVerifyEmail, verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	body.WriteString("Welcome to Formease! Please confirm your email address.\n")
+	s.writeTokenLink(&body, "Verify your email", "/verify-email", token)
+	body.WriteString("\nThe link expires in 24 hours.\n")
+
+	msg := notifier.Message{
+		To:      []string{user.Email},
+		Subject: "Verify your email",
+		Body:    body.String(),
+	}
+	if err := s.sender.Send(ctx, msg); err != nil {
+		return apperrors.NewInternalServerError("failed to send verification email", err)
+	}
+	return nil
+}
+
+func (s *UserService) createUserToken(ctx context.Context, userID primitive.ObjectID, purpose string, ttl time.Duration) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", apperrors.NewInternalServerError("failed to generate token", err)
+	}
+
+	now := time.Now()
+	userToken := &models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := s.tokenRepo.ReplaceToken(ctx, userToken); err != nil {
+		return "", apperrors.NewInternalServerError("failed to save token", err)
+	}
+	return token, nil
+}
+
+func (s *UserService) consumeUserToken(ctx context.Context, token, purpose string) (*models.UserToken, error) {
+	userToken, err := s.tokenRepo.ConsumeToken(ctx, hashToken(token), purpose, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrUserTokenNotFound) {
+			return nil, apperrors.NewBadRequestError("token is invalid or has expired")
+		}
+		return nil, apperrors.NewInternalServerError("failed to check token", err)
+	}
+	return userToken, nil
+}
+
+func (s *UserService) writeTokenLink(body *strings.Builder, label, path, token string) {
+	appURL := strings.TrimRight(s.config.AppURL, "/")
+	if appURL != "" {
+		fmt.Fprintf(body, "\n%s: %s%s?token=%s\n", label, appURL, path, url.QueryEscape(token))
+	} else {
+		fmt.Fprintf(body, "\nYour token: %s\n", token)
+	}
+}
@@ -0,0 +1,438 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/maxzhirnov/formease/config"
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/notifier"
+	"github.com/maxzhirnov/formease/internal/repository"
+	"github.com/maxzhirnov/formease/internal/utils"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tokenLinkPattern = regexp.MustCompile(`\?token=(\S+)`)
+
+func TestRegisterAndVerifyEmail(t *testing.T) {
+	env := newAccountTestEnv(t, true)
+	ctx := context.Background()
+
+	user := &models.User{Email: "ann@example.com", Password: "Secret123"}
+	if err := env.service.Register(ctx, user); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	token := env.lastToken(t, "ann@example.com", "Verify your email")
+	if err := env.service.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+	if env.users.byEmail("ann@example.com").EmailVerifiedAt == nil {
+		t.Fatal("email is not marked as verified")
+	}
+
+	assertStatus(t, env.service.VerifyEmail(ctx, token), http.StatusBadRequest)
+	assertStatus(t, env.service.SendVerificationEmail(ctx, user.ID.Hex()), http.StatusConflict)
+}
+
+func TestResendVerificationReplacesToken(t *testing.T) {
+	env := newAccountTestEnv(t, true)
+	ctx := context.Background()
+
+	user := &models.User{Email: "ann@example.com", Password: "Secret123"}
+	if err := env.service.Register(ctx, user); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	first := env.lastToken(t, "ann@example.com", "Verify your email")
+
+	if err := env.service.SendVerificationEmail(ctx, user.ID.Hex()); err != nil {
+		t.Fatalf("SendVerificationEmail() error = %v", err)
+	}
+	second := env.lastToken(t, "ann@example.com", "Verify your email")
+
+	assertStatus(t, env.service.VerifyEmail(ctx, first), http.StatusBadRequest)
+	if err := env.service.VerifyEmail(ctx, second); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+}
+
+func TestForgotAndResetPassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		expire     bool
+		wantStatus int
+	}{
+		{name: "resets password", password: "NewSecret456"},
+		{name: "short password", password: "short", wantStatus: http.StatusBadRequest},
+		{name: "expired token", password: "NewSecret456", expire: true, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newAccountTestEnv(t, true)
+			ctx := context.Background()
+
+			if err := env.service.Register(ctx, &models.User{Email: "ann@example.com", Password: "Secret123"}); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			if _, _, _, err := env.service.Login(ctx, "ann@example.com", "Secret123", SessionClient{}); err != nil {
+				t.Fatalf("Login() error = %v", err)
+			}
+
+			if err := env.service.ForgotPassword(ctx, " ann@example.com "); err != nil {
+				t.Fatalf("ForgotPassword() error = %v", err)
+			}
+			token := env.lastToken(t, "ann@example.com", "Reset your password")
+			if tt.expire {
+				env.tokens.expireAll()
+			}
+
+			err := env.service.ResetPassword(ctx, token, tt.password)
+			if tt.wantStatus != 0 {
+				assertStatus(t, err, tt.wantStatus)
+				if _, _, _, err := env.service.Login(ctx, "ann@example.com", "Secret123", SessionClient{}); err != nil {
+					t.Fatalf("old password stopped working: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResetPassword() error = %v", err)
+			}
+
+			if _, _, _, err := env.service.Login(ctx, "ann@example.com", "Secret123", SessionClient{}); err == nil {
+				t.Error("old password still works")
+			}
+			if _, _, _, err := env.service.Login(ctx, "ann@example.com", tt.password, SessionClient{}); err != nil {
+				t.Errorf("new password does not work: %v", err)
+			}
+			if env.users.byEmail("ann@example.com").EmailVerifiedAt == nil {
+				t.Error("reset did not verify the email")
+			}
+			if revoked := env.sessions.revokedCount(); revoked != 1 {
+				t.Errorf("revoked sessions = %d, want 1", revoked)
+			}
+			if len(env.denylist.ids) != 1 {
+				t.Errorf("revoked access tokens = %d, want 1", len(env.denylist.ids))
+			}
+
+			assertStatus(t, env.service.ResetPassword(ctx, token, "Another789"), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestForgotPasswordSendsInBackground(t *testing.T) {
+	env := newAccountTestEnv(t, true)
+	if err := env.service.Register(context.Background(), &models.User{Email: "ann@example.com", Password: "Secret123"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	env.mail.Reset()
+	release := make(chan struct{})
+	env.service.sender = blockingSender{Sender: env.mail, release: release}
+
+	// The request ends before the email is sent
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := env.service.ForgotPassword(ctx, "ann@example.com"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	cancel()
+	if got := len(env.mail.Messages()); got != 0 {
+		t.Fatalf("sent %d emails before ForgotPassword returned", got)
+	}
+
+	close(release)
+	if token := env.lastToken(t, "ann@example.com", "Reset your password"); token == "" {
+		t.Error("reset email has no token")
+	}
+}
+
+func TestForgotPasswordWithoutAccount(t *testing.T) {
+	tests := []struct {
+		name       string
+		email      string
+		withSender bool
+	}{
+		{name: "unknown email", email: "nobody@example.com", withSender: true},
+		{name: "no sender configured", email: "ann@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newAccountTestEnv(t, tt.withSender)
+			ctx := context.Background()
+
+			if err := env.service.Register(ctx, &models.User{Email: "ann@example.com", Password: "Secret123"}); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			env.mail.Reset()
+			env.tokens.reset()
+
+			if err := env.service.ForgotPassword(ctx, tt.email); err != nil {
+				t.Fatalf("ForgotPassword() error = %v", err)
+			}
+			env.service.WaitForEmails()
+			if got := len(env.mail.Messages()); got != 0 {
+				t.Errorf("sent %d emails, want 0", got)
+			}
+			if got := len(env.tokens.tokens); got != 0 {
+				t.Errorf("stored %d tokens, want 0", got)
+			}
+		})
+	}
+}
+
+// blockingSender holds emails back until release is closed.
+type blockingSender struct {
+	notifier.Sender
+	release chan struct{}
+}
+
+func (s blockingSender) Send(ctx context.Context, msg notifier.Message) error {
+	<-s.release
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Sender.Send(ctx, msg)
+}
+
+type accountTestEnv struct {
+	service  *UserService
+	mail     *notifier.MemorySender
+	users    *fakeUserRepository
+	sessions *fakeSessionStore
+	denylist *fakeRevokedTokenStore
+	tokens   *fakeUserTokenStore
+}
+
+func newAccountTestEnv(t *testing.T, withSender bool) *accountTestEnv {
+	t.Helper()
+	env := &accountTestEnv{
+		mail:     notifier.NewMemorySender(),
+		users:    &fakeUserRepository{},
+		sessions: &fakeSessionStore{sessions: map[primitive.ObjectID]*models.Session{}},
+		denylist: &fakeRevokedTokenStore{ids: map[string]bool{}},
+		tokens:   &fakeUserTokenStore{},
+	}
+	var sender notifier.Sender
+	if withSender {
+		sender = env.mail
+	}
+	cfg := &config.Config{AppURL: "https://app.example.com/", TokenExpirationHours: 1}
+	env.service = NewUserService(cfg, env.users, env.sessions, env.denylist, env.tokens, sender, utils.NewJWTUtil("test-secret"))
+	return env
+}
+
+// lastToken returns the token from the link in the latest email. It waits
+// for emails sent in the background first.
+func (e *accountTestEnv) lastToken(t *testing.T, to, subject string) string {
+	t.Helper()
+	e.service.WaitForEmails()
+	messages := e.mail.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no email was sent")
+	}
+	msg := messages[len(messages)-1]
+	if len(msg.To) != 1 || msg.To[0] != to || msg.Subject != subject {
+		t.Fatalf("last email = %v %q, want %s %q", msg.To, msg.Subject, to, subject)
+	}
+	match := tokenLinkPattern.FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("no token link in email body %q", msg.Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatalf("bad token in link: %v", err)
+	}
+	return token
+}
+
+func assertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("error = %v, want *AppError with status %d", err, status)
+	}
+	if appErr.StatusCode != status {
+		t.Fatalf("status = %d, want %d (%v)", appErr.StatusCode, status, err)
+	}
+}
+
+type fakeUserRepository struct {
+	users []*models.User
+}
+
+func (r *fakeUserRepository) Create(ctx context.Context, user *models.User) error {
+	if r.byEmail(user.Email) != nil {
+		return errors.New("user with this email already exists")
+	}
+	user.ID = primitive.NewObjectID()
+	stored := *user
+	r.users = append(r.users, &stored)
+	return nil
+}
+
+func (r *fakeUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	if user := r.byEmail(email); user != nil {
+		copied := *user
+		return &copied, nil
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepository) FindByID(ctx context.Context, id string) (*models.User, error) {
+	for _, user := range r.users {
+		if user.ID.Hex() == id {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepository) EnsureIndexes(ctx context.Context) error { return nil }
+
+func (r *fakeUserRepository) SetEmailVerified(ctx context.Context, userID primitive.ObjectID, verifiedAt time.Time) error {
+	for _, user := range r.users {
+		if user.ID == userID {
+			user.EmailVerifiedAt = &verifiedAt
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+func (r *fakeUserRepository) UpdatePassword(ctx context.Context, userID primitive.ObjectID, passwordHash string) error {
+	for _, user := range r.users {
+		if user.ID == userID {
+			user.Password = passwordHash
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+func (r *fakeUserRepository) byEmail(email string) *models.User {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user
+		}
+	}
+	return nil
+}
+
+type fakeSessionStore struct {
+	sessions map[primitive.ObjectID]*models.Session
+}
+
+func (s *fakeSessionStore) CreateSession(ctx context.Context, session *models.Session) error {
+	stored := *session
+	s.sessions[session.ID] = &stored
+	return nil
+}
+
+func (s *fakeSessionStore) FindSession(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, repository.ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (s *fakeSessionStore) ListActiveSessions(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]models.Session, error) {
+	var active []models.Session
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			active = append(active, *session)
+		}
+	}
+	return active, nil
+}
+
+func (s *fakeSessionStore) RotateToken(ctx context.Context, session *models.Session, newHash string) error {
+	stored, ok := s.sessions[session.ID]
+	if !ok || stored.TokenHash != session.TokenHash || stored.RevokedAt != nil {
+		return repository.ErrSessionNotFound
+	}
+	updated := *session
+	updated.UsedTokenHashes = append(stored.UsedTokenHashes, stored.TokenHash)
+	updated.TokenHash = newHash
+	s.sessions[session.ID] = &updated
+	return nil
+}
+
+func (s *fakeSessionStore) RevokeSession(ctx context.Context, id, userID primitive.ObjectID, now time.Time) error {
+	session, ok := s.sessions[id]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return repository.ErrSessionNotFound
+	}
+	session.RevokedAt = &now
+	return nil
+}
+
+func (s *fakeSessionStore) revokedCount() int {
+	count := 0
+	for _, session := range s.sessions {
+		if session.RevokedAt != nil {
+			count++
+		}
+	}
+	return count
+}
+
+type fakeRevokedTokenStore struct {
+	ids map[string]bool
+}
+
+func (s *fakeRevokedTokenStore) RevokeToken(ctx context.Context, token *models.RevokedToken) error {
+	s.ids[token.ID] = true
+	return nil
+}
+
+func (s *fakeRevokedTokenStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return s.ids[tokenID], nil
+}
+
+type fakeUserTokenStore struct {
+	tokens []*models.UserToken
+}
+
+func (s *fakeUserTokenStore) ReplaceToken(ctx context.Context, token *models.UserToken) error {
+	kept := s.tokens[:0]
+	for _, t := range s.tokens {
+		if t.UserID != token.UserID || t.Purpose != token.Purpose || t.UsedAt != nil {
+			kept = append(kept, t)
+		}
+	}
+	token.ID = primitive.NewObjectID()
+	stored := *token
+	s.tokens = append(kept, &stored)
+	return nil
+}
+
+func (s *fakeUserTokenStore) ConsumeToken(ctx context.Context, tokenHash, purpose string, now time.Time) (*models.UserToken, error) {
+	for _, t := range s.tokens {
+		if t.TokenHash == tokenHash && t.Purpose == purpose && t.UsedAt == nil && t.ExpiresAt.After(now) {
+			t.UsedAt = &now
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrUserTokenNotFound
+}
+
+func (s *fakeUserTokenStore) expireAll() {
+	for _, t := range s.tokens {
+		t.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
+
+func (s *fakeUserTokenStore) reset() {
+	s.tokens = nil
+}
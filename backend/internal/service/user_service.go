@@ -2,43 +2,120 @@ package service
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/maxzhirnov/formease/config"
 	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/notifier"
 	"github.com/maxzhirnov/formease/internal/repository"
 	"github.com/maxzhirnov/formease/internal/utils"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
 	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// maxUserAgentLength keeps stored session details short
+const maxUserAgentLength = 256
+
+var (
+	ErrSessionInvalid = errors.New("session is invalid or has expired")
+	// ErrRefreshTokenReused means a refresh token was used after it had been
+	// replaced, so it may be stolen. The session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token was already used")
+)
+
+// SessionStore persists sessions, *repository.SessionRepository implements it.
+type SessionStore interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	FindSession(ctx context.Context, id primitive.ObjectID) (*models.Session, error)
+	ListActiveSessions(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]models.Session, error)
+	RotateToken(ctx context.Context, session *models.Session, newHash string) error
+	RevokeSession(ctx context.Context, id, userID primitive.ObjectID, now time.Time) error
+}
+
+// RevokedTokenStore is the access token denylist,
+// *repository.RevokedTokenRepository implements it.
+type RevokedTokenStore interface {
+	RevokeToken(ctx context.Context, token *models.RevokedToken) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// UserTokenStore keeps the tokens sent by email,
+// *repository.UserTokenRepository implements it.
+type UserTokenStore interface {
+	ReplaceToken(ctx context.Context, token *models.UserToken) error
+	ConsumeToken(ctx context.Context, tokenHash, purpose string, now time.Time) (*models.UserToken, error)
+}
+
+// SessionClient describes the device a session is used from.
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
 type UserService struct {
-	config   *config.Config
-	userRepo repository.UserRepository
-	jwtUtil  *utils.JWTUtil
+	config      *config.Config
+	userRepo    repository.UserRepository
+	sessionRepo SessionStore
+	denylist    RevokedTokenStore
+	tokenRepo   UserTokenStore
+	sender      notifier.Sender
+	jwtUtil     *utils.JWTUtil
+	// Password reset emails sent in the background
+	emails sync.WaitGroup
 }
 
-func NewUserService(config *config.Config, userRepo repository.UserRepository, jwtUtil *utils.JWTUtil) *UserService {
+// NewUserService creates the service. Verification and password reset
+// emails are sent with sender if it is not nil.
+func NewUserService(
+	config *config.Config,
+	userRepo repository.UserRepository,
+	sessionRepo SessionStore,
+	denylist RevokedTokenStore,
+	tokenRepo UserTokenStore,
+	sender notifier.Sender,
+	jwtUtil *utils.JWTUtil,
+) *UserService {
 	return &UserService{
-		config:   config,
-		userRepo: userRepo,
-		jwtUtil:  jwtUtil,
+		config:      config,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		denylist:    denylist,
+		tokenRepo:   tokenRepo,
+		sender:      sender,
+		jwtUtil:     jwtUtil,
 	}
 }
 
+// Register creates an account and emails a verification link. A failed
+// email does not fail the registration, the link can be sent again.
 func (s *UserService) Register(ctx context.Context, user *models.User) error {
 	logger.Info("Registering user", zap.String("email", user.Email))
+	user.ID = primitive.NilObjectID
+	user.EmailVerifiedAt = nil
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		logger.Error("error hashing password", zap.Error(err))
 		return err
 	}
 	user.Password = string(hashedPassword)
-	logger.Info("Hashed password", zap.String("hash", user.Password))
-	return s.userRepo.Create(ctx, user)
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.sendVerification(ctx, user); err != nil {
+		logger.Error("Failed to send verification email", zap.String("userId", user.ID.Hex()), zap.Error(err))
+	}
+	return nil
 }
 
-func (s *UserService) Login(ctx context.Context, email, password string) (string, string, *models.User, error) {
+// Login checks the credentials and starts a new session for the device
+// described by client.
+func (s *UserService) Login(ctx context.Context, email, password string, client SessionClient) (string, string, *models.User, error) {
 	user, err := s.userRepo.FindByEmail(ctx, email)
 	logger.Info("Login attempt", zap.String("email", email))
 	if err != nil {
@@ -51,21 +128,24 @@ func (s *UserService) Login(ctx context.Context, email, password string) (string
 		return "", "", nil, err
 	}
 
-	accessToken, err := s.jwtUtil.GenerateToken(user.ID.Hex(), user.Email, s.config.TokenExpirationHours)
-	if err != nil {
-		logger.Error("error generating access token", zap.Error(err))
-		return "", "", nil, err
+	now := time.Now()
+	session := &models.Session{
+		ID:         primitive.NewObjectID(),
+		UserID:     user.ID,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(utils.RefreshTokenTTL),
 	}
+	client.apply(session)
 
-	refreshToken, err := s.jwtUtil.GenerateRefreshToken(user.ID.Hex())
+	accessToken, refreshToken, err := s.issueTokens(user, session)
 	if err != nil {
-		logger.Error("error generating refresh token", zap.Error(err))
 		return "", "", nil, err
 	}
+	session.TokenHash = hashToken(refreshToken)
 
-	// Store refresh token in the database
-	if err := s.userRepo.UpdateRefreshToken(ctx, user.ID, refreshToken); err != nil {
-		logger.Error("error updating refresh token", zap.Error(err))
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		logger.Error("error creating session", zap.Error(err))
 		return "", "", nil, err
 	}
 
@@ -76,34 +156,240 @@ func (s *UserService) ValidateToken(tokenString string) (string, string, error)
 	return s.jwtUtil.ValidateToken(tokenString)
 }
 
-func (s *UserService) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
-	user, err := s.userRepo.FindByRefreshToken(ctx, refreshToken)
+// IsTokenRevoked reports whether the access token with tokenID was revoked
+// by a logout.
+func (s *UserService) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return s.denylist.IsRevoked(ctx, tokenID)
+}
+
+// Logout ends the session of the given tokens. Either token may be empty or
+// invalid, whatever can be identified is revoked.
+func (s *UserService) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	now := time.Now()
+	sessionID := ""
+	if claims, err := s.jwtUtil.ValidateAccessToken(accessToken); err == nil {
+		if err := s.revokeAccessToken(ctx, claims.UserID, claims.ID, claims.ExpiresAt, now); err != nil {
+			return err
+		}
+		sessionID = claims.SessionID
+	}
+	if _, id, err := s.jwtUtil.ValidateRefreshToken(refreshToken); err == nil {
+		sessionID = id
+	}
+	if sessionID == "" {
+		return nil
+	}
+
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil
+	}
+	session, err := s.sessionRepo.FindSession(ctx, sessionObjectID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.revokeSession(ctx, session, now)
+}
+
+// LogoutAll ends every session of userID.
+func (s *UserService) LogoutAll(ctx context.Context, userID string) error {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return apperrors.NewBadRequestError("invalid user ID")
+	}
+
+	now := time.Now()
+	sessions, err := s.sessionRepo.ListActiveSessions(ctx, userObjectID, now)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to list sessions", err)
+	}
+	for i := range sessions {
+		if err := s.revokeSession(ctx, &sessions[i], now); err != nil {
+			return apperrors.NewInternalServerError("failed to revoke session", err)
+		}
+	}
+	return nil
+}
+
+// RefreshToken exchanges a refresh token for new tokens. Each refresh token
+// works once: using a replaced token again revokes its session, which logs
+// out both the thief and the legitimate device.
+func (s *UserService) RefreshToken(ctx context.Context, refreshToken string, client SessionClient) (string, string, error) {
+	_, sessionID, err := s.jwtUtil.ValidateRefreshToken(refreshToken)
 	if err != nil {
-		logger.Error("Error finding user by refresh token", zap.Error(err))
 		return "", "", err
 	}
-	logger.Info("Refresh token found", zap.String("refreshToken", refreshToken))
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return "", "", ErrSessionInvalid
+	}
 
-	accessToken, err := s.jwtUtil.GenerateToken(user.ID.Hex(), user.Email, s.config.TokenExpirationHours)
+	session, err := s.sessionRepo.FindSession(ctx, sessionObjectID)
 	if err != nil {
-		logger.Error("Error generating access token", zap.Error(err))
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return "", "", ErrSessionInvalid
+		}
 		return "", "", err
 	}
-	logger.Info("Access token generated", zap.String("accessToken", accessToken))
+	now := time.Now()
+	if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+		return "", "", ErrSessionInvalid
+	}
 
-	newRefreshToken, err := s.jwtUtil.GenerateRefreshToken(user.ID.Hex())
+	hash := hashToken(refreshToken)
+	if hash != session.TokenHash {
+		for _, used := range session.UsedTokenHashes {
+			if used == hash {
+				s.revokeReusedSession(ctx, session, now)
+				return "", "", ErrRefreshTokenReused
+			}
+		}
+		return "", "", ErrSessionInvalid
+	}
+
+	user, err := s.userRepo.FindByID(ctx, session.UserID.Hex())
+	if err != nil {
+		logger.Error("Error finding session user", zap.Error(err))
+		return "", "", ErrSessionInvalid
+	}
+
+	previousAccessID, previousAccessExpiresAt := session.AccessTokenID, session.AccessExpiresAt
+	accessToken, newRefreshToken, err := s.issueTokens(user, session)
 	if err != nil {
-		logger.Error("Error generating new refresh token", zap.Error(err))
 		return "", "", err
 	}
-	logger.Info("New refresh token generated", zap.String("refreshToken", newRefreshToken))
 
-	// Update the stored refresh token in the database
-	if err := s.userRepo.UpdateRefreshToken(ctx, user.ID, newRefreshToken); err != nil {
-		logger.Error("Error updating refresh token", zap.Error(err))
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(utils.RefreshTokenTTL)
+	client.apply(session)
+	if err := s.sessionRepo.RotateToken(ctx, session, hashToken(newRefreshToken)); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			// Another request exchanged the same token first
+			s.revokeReusedSession(ctx, session, now)
+			return "", "", ErrRefreshTokenReused
+		}
+		logger.Error("Error rotating refresh token", zap.Error(err))
 		return "", "", err
 	}
-	logger.Info("Refresh token updated", zap.String("refreshToken", newRefreshToken))
+
+	// A session has one valid access token at a time
+	if err := s.revokeAccessToken(ctx, session.UserID.Hex(), previousAccessID, previousAccessExpiresAt, now); err != nil {
+		logger.Error("Failed to revoke replaced access token", zap.Error(err))
+	}
 
 	return accessToken, newRefreshToken, nil
 }
+
+// ListSessions returns the active sessions of userID. The session that
+// refreshToken belongs to is marked as current.
+func (s *UserService) ListSessions(ctx context.Context, userID, refreshToken string) ([]models.Session, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid user ID")
+	}
+
+	sessions, err := s.sessionRepo.ListActiveSessions(ctx, userObjectID, time.Now())
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list sessions", err)
+	}
+	if refreshToken != "" {
+		hash := hashToken(refreshToken)
+		for i := range sessions {
+			sessions[i].Current = sessions[i].TokenHash == hash
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends a session of userID, its refresh token stops working.
+func (s *UserService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return apperrors.NewBadRequestError("invalid user ID")
+	}
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return apperrors.NewBadRequestError("invalid session ID")
+	}
+
+	session, err := s.sessionRepo.FindSession(ctx, sessionObjectID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return apperrors.NewNotFoundError("session not found")
+		}
+		return apperrors.NewInternalServerError("failed to load session", err)
+	}
+	if session.UserID != userObjectID || session.RevokedAt != nil {
+		return apperrors.NewNotFoundError("session not found")
+	}
+
+	if err := s.revokeSession(ctx, session, time.Now()); err != nil {
+		return apperrors.NewInternalServerError("failed to revoke session", err)
+	}
+	return nil
+}
+
+// issueTokens creates the tokens of a session and records the access token
+// in it, so it can be revoked together with the session.
+func (s *UserService) issueTokens(user *models.User, session *models.Session) (string, string, error) {
+	accessToken, claims, err := s.jwtUtil.GenerateToken(user.ID.Hex(), user.Email, session.ID.Hex(), s.config.TokenExpirationHours)
+	if err != nil {
+		logger.Error("error generating access token", zap.Error(err))
+		return "", "", err
+	}
+	session.AccessTokenID = claims.ID
+	session.AccessExpiresAt = claims.ExpiresAt
+
+	refreshToken, err := s.jwtUtil.GenerateRefreshToken(user.ID.Hex(), session.ID.Hex())
+	if err != nil {
+		logger.Error("error generating refresh token", zap.Error(err))
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+func (s *UserService) revokeReusedSession(ctx context.Context, session *models.Session, now time.Time) {
+	logger.Error("Refresh token reuse detected, revoking session",
+		zap.String("sessionId", session.ID.Hex()),
+		zap.String("userId", session.UserID.Hex()))
+	if err := s.revokeSession(ctx, session, now); err != nil {
+		logger.Error("Failed to revoke session", zap.String("sessionId", session.ID.Hex()), zap.Error(err))
+	}
+}
+
+// revokeSession stops the refresh token and the latest access token of a session.
+func (s *UserService) revokeSession(ctx context.Context, session *models.Session, now time.Time) error {
+	if err := s.sessionRepo.RevokeSession(ctx, session.ID, session.UserID, now); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		return err
+	}
+	return s.revokeAccessToken(ctx, session.UserID.Hex(), session.AccessTokenID, session.AccessExpiresAt, now)
+}
+
+// revokeAccessToken adds a token to the denylist. Expired tokens are
+// rejected anyway and are skipped.
+func (s *UserService) revokeAccessToken(ctx context.Context, userID, tokenID string, expiresAt, now time.Time) error {
+	if tokenID == "" || !expiresAt.After(now) {
+		return nil
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return s.denylist.RevokeToken(ctx, &models.RevokedToken{
+		ID:        tokenID,
+		UserID:    userObjectID,
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (c SessionClient) apply(session *models.Session) {
+	userAgent := c.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session.UserAgent = userAgent
+	session.IP = c.IP
+}
@@ -0,0 +1,502 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	WebhookEventSubmissionCreated = "submission.created"
+
+	WebhookSignatureHeader = "X-Formease-Signature"
+	WebhookTimestampHeader = "X-Formease-Timestamp"
+	WebhookEventHeader     = "X-Formease-Event"
+	WebhookDeliveryHeader  = "X-Formease-Delivery"
+
+	maxWebhooksPerForm    = 10
+	maxWebhookURLLength   = 2048
+	webhookDeliveryLimit  = 50
+	webhookMaxAttempts    = 8
+	webhookBaseBackoff    = 30 * time.Second
+	webhookMaxBackoff     = 6 * time.Hour
+	webhookRequestTimeout = 10 * time.Second
+	webhookPollInterval   = 5 * time.Second
+	// A claimed delivery is locked for longer than a request can take, so a
+	// crashed worker only delays it
+	webhookLease = time.Minute
+)
+
+var errPrivateAddress = errors.New("webhook target resolves to a private address")
+
+type WebhookService struct {
+	repo     *repository.WebhookRepository
+	formRepo *repository.FormRepository
+	client   *http.Client
+	wake     chan struct{}
+}
+
+// NewWebhookService creates the service. Unless allowPrivate is set, webhook
+// requests to loopback and private networks are refused.
+func NewWebhookService(repo *repository.WebhookRepository, formRepo *repository.FormRepository, allowPrivate bool) *WebhookService {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = publicAddressOnly
+	}
+
+	return &WebhookService{
+		repo:     repo,
+		formRepo: formRepo,
+		client: &http.Client{
+			Timeout:   webhookRequestTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// A redirect is reported as a failed delivery instead of being followed
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, formID, workspaceID, targetURL string) (*models.Webhook, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateWebhookURL(targetURL); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListWebhooks(ctx, form.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list webhooks", err)
+	}
+	if len(existing) >= maxWebhooksPerForm {
+		return nil, apperrors.NewBadRequestError(fmt.Sprintf("a form can have at most %d webhooks", maxWebhooksPerForm))
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate webhook secret", err)
+	}
+
+	webhook := &models.Webhook{
+		FormID:      form.ID,
+		WorkspaceID: form.WorkspaceID,
+		URL:         targetURL,
+		Secret:      secret,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create webhook", err)
+	}
+	return webhook, nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, formID, workspaceID string) ([]models.Webhook, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.repo.ListWebhooks(ctx, form.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list webhooks", err)
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, formID, workspaceID, webhookID string) error {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return err
+	}
+	id, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return apperrors.NewNotFoundError("webhook not found")
+	}
+
+	if err := s.repo.DeleteWebhook(ctx, id, form.ID); err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return apperrors.NewNotFoundError("webhook not found")
+		}
+		return apperrors.NewInternalServerError("failed to delete webhook", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries of a webhook.
+func (s *WebhookService) ListDeliveries(ctx context.Context, formID, workspaceID, webhookID string) ([]models.WebhookDelivery, error) {
+	webhook, err := s.loadOwnedWebhook(ctx, formID, workspaceID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, webhook.ID, webhookDeliveryLimit)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery queues a failed delivery again with a fresh attempt budget.
+func (s *WebhookService) ReplayDelivery(ctx context.Context, formID, workspaceID, webhookID, deliveryID string) (*models.WebhookDelivery, error) {
+	webhook, err := s.loadOwnedWebhook(ctx, formID, workspaceID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := primitive.ObjectIDFromHex(deliveryID)
+	if err != nil {
+		return nil, apperrors.NewNotFoundError("delivery not found")
+	}
+
+	delivery, err := s.repo.FindDelivery(ctx, id, webhook.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrDeliveryNotFound) {
+			return nil, apperrors.NewNotFoundError("delivery not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to load delivery", err)
+	}
+	if delivery.Status != models.DeliveryStatusFailed {
+		return nil, apperrors.NewConflictError("only failed deliveries can be replayed")
+	}
+
+	delivery, err = s.repo.ResetDelivery(ctx, delivery.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrDeliveryNotFound) {
+			// Replayed concurrently
+			return nil, apperrors.NewConflictError("only failed deliveries can be replayed")
+		}
+		return nil, apperrors.NewInternalServerError("failed to replay delivery", err)
+	}
+
+	s.notify()
+	return delivery, nil
+}
+
+func (s *WebhookService) loadOwnedWebhook(ctx context.Context, formID, workspaceID, webhookID string) (*models.Webhook, error) {
+	form, err := loadOwnedForm(s.formRepo, formID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	id, err := primitive.ObjectIDFromHex(webhookID)
+	if err != nil {
+		return nil, apperrors.NewNotFoundError("webhook not found")
+	}
+
+	webhook, err := s.repo.FindWebhook(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebhookNotFound) {
+			return nil, apperrors.NewNotFoundError("webhook not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to load webhook", err)
+	}
+	if webhook.FormID != form.ID {
+		return nil, apperrors.NewNotFoundError("webhook not found")
+	}
+	return webhook, nil
+}
+
+// SubmissionCreated queues a delivery for every webhook of the form. The
+// requests are sent by Run, so a slow endpoint never delays the respondent.
+func (s *WebhookService) SubmissionCreated(ctx context.Context, form *models.Form, sub *models.Submission) {
+	webhooks, err := s.repo.ListWebhooks(ctx, form.ID)
+	if err != nil {
+		logger.Error("Failed to load webhooks", zap.String("formId", form.ID.Hex()), zap.Error(err))
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(newSubmissionPayload(form, sub))
+	if err != nil {
+		logger.Error("Failed to encode webhook payload", zap.String("submissionId", sub.ID.Hex()), zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for _, webhook := range webhooks {
+		delivery := &models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			FormID:        form.ID,
+			SubmissionID:  sub.ID,
+			Event:         WebhookEventSubmissionCreated,
+			Payload:       string(payload),
+			Status:        models.DeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := s.repo.CreateDelivery(ctx, delivery); err != nil {
+			logger.Error("Failed to queue webhook delivery",
+				zap.String("webhookId", webhook.ID.Hex()),
+				zap.String("submissionId", sub.ID.Hex()),
+				zap.Error(err))
+		}
+	}
+
+	s.notify()
+}
+
+// notify wakes the worker without blocking.
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers queued webhooks until ctx is cancelled. The queue lives in
+// MongoDB, so pending deliveries survive restarts and several instances can
+// run workers side by side.
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.processDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *WebhookService) processDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivery, err := s.repo.ClaimDueDelivery(ctx, time.Now(), webhookLease)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error("Failed to claim webhook delivery", zap.Error(err))
+			}
+			return
+		}
+		if delivery == nil {
+			return
+		}
+		s.attempt(ctx, delivery)
+	}
+}
+
+func (s *WebhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	webhook, err := s.repo.FindWebhook(ctx, delivery.WebhookID)
+	switch {
+	case errors.Is(err, repository.ErrWebhookNotFound):
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.LastError = "webhook was deleted"
+	case err != nil:
+		// Leave the lock to expire, the delivery is retried after the lease
+		logger.Error("Failed to load webhook", zap.String("deliveryId", delivery.ID.Hex()), zap.Error(err))
+		return
+	default:
+		statusCode, err := s.send(ctx, webhook, delivery)
+		delivery.Attempts++
+		delivery.LastStatusCode = statusCode
+		now := time.Now()
+
+		switch {
+		case err == nil:
+			delivery.Status = models.DeliveryStatusSucceeded
+			delivery.LastError = ""
+			delivery.DeliveredAt = &now
+		case delivery.Attempts >= webhookMaxAttempts:
+			delivery.Status = models.DeliveryStatusFailed
+			delivery.LastError = err.Error()
+		default:
+			delivery.LastError = err.Error()
+			delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts))
+		}
+	}
+
+	// The outcome is stored even when the worker is being stopped
+	if err := s.repo.FinishAttempt(context.Background(), delivery); err != nil {
+		logger.Error("Failed to store webhook delivery result",
+			zap.String("deliveryId", delivery.ID.Hex()),
+			zap.Error(err))
+		return
+	}
+
+	logger.Info("Webhook delivery attempted",
+		zap.String("deliveryId", delivery.ID.Hex()),
+		zap.String("status", delivery.Status),
+		zap.Int("attempts", delivery.Attempts),
+		zap.Int("statusCode", delivery.LastStatusCode))
+}
+
+// send POSTs the payload signed with the webhook secret. Receivers verify
+// the signature as HMAC-SHA256 over "<timestamp>.<body>".
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Formease-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a bit of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the hex encoded signature of a webhook request.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff doubles the delay after every failed attempt.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return delay
+}
+
+func validateWebhookURL(raw string) error {
+	if raw == "" || len(raw) > maxWebhookURLLength {
+		return apperrors.NewBadRequestError("invalid webhook URL")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apperrors.NewBadRequestError("webhook URL must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return apperrors.NewBadRequestError("webhook URL must not contain credentials")
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// publicAddressOnly is a net.Dialer Control function. It runs after DNS
+// resolution, so host names pointing at internal services are refused too.
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+type submissionPayload struct {
+	Event      string            `json:"event"`
+	Form       payloadForm       `json:"form"`
+	Submission payloadSubmission `json:"submission"`
+}
+
+type payloadForm struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type payloadSubmission struct {
+	ID        string          `json:"id"`
+	SessionID string          `json:"sessionId,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Answers   []payloadAnswer `json:"answers"`
+}
+
+// payloadAnswer repeats the question and option texts, so receivers do not
+// have to look up the form to make sense of an answer.
+type payloadAnswer struct {
+	QuestionID int      `json:"questionId"`
+	Type       string   `json:"type"`
+	Question   string   `json:"question"`
+	Text       string   `json:"text,omitempty"`
+	OptionIDs  []int    `json:"optionIds,omitempty"`
+	Options    []string `json:"options,omitempty"`
+	Rating     *float64 `json:"rating,omitempty"`
+}
+
+func newSubmissionPayload(form *models.Form, sub *models.Submission) submissionPayload {
+	questions := make(map[int]models.Question, len(form.Questions))
+	for _, q := range form.Questions {
+		questions[q.ID] = q
+	}
+
+	answers := make([]payloadAnswer, 0, len(sub.Answers))
+	for _, a := range sub.Answers {
+		q := questions[a.QuestionID]
+		answer := payloadAnswer{
+			QuestionID: a.QuestionID,
+			Type:       q.Type,
+			Question:   q.Question,
+			Text:       a.Text,
+			OptionIDs:  a.OptionIDs,
+			Rating:     a.Rating,
+		}
+		for _, id := range a.OptionIDs {
+			answer.Options = append(answer.Options, optionText(&q, id))
+		}
+		answers = append(answers, answer)
+	}
+
+	return submissionPayload{
+		Event: WebhookEventSubmissionCreated,
+		Form:  payloadForm{ID: form.ID.Hex(), Name: form.Name},
+		Submission: payloadSubmission{
+			ID:        sub.ID.Hex(),
+			SessionID: sub.SessionID,
+			CreatedAt: sub.CreatedAt,
+			Answers:   answers,
+		},
+	}
+}
@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/notifier"
+	"github.com/maxzhirnov/formease/internal/repository"
+	apperrors "github.com/maxzhirnov/formease/pkg/errors"
+	"github.com/maxzhirnov/formease/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	invitationTTL          = 7 * 24 * time.Hour
+	maxWorkspaceNameLength = 100
+	personalWorkspaceName  = "Personal"
+)
+
+// WorkspaceStore persists workspaces, their members and invitations,
+// *repository.WorkspaceRepository implements it.
+type WorkspaceStore interface {
+	WorkspaceFinder
+	CreateWorkspace(ctx context.Context, workspace *models.Workspace) error
+	FindWorkspaces(ctx context.Context, ids []primitive.ObjectID) ([]models.Workspace, error)
+	AddMember(ctx context.Context, member *models.WorkspaceMember) error
+	FindMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error)
+	ListMembers(ctx context.Context, workspaceID primitive.ObjectID) ([]models.WorkspaceMember, error)
+	ListMemberships(ctx context.Context, userID primitive.ObjectID) ([]models.WorkspaceMember, error)
+	CountOwners(ctx context.Context, workspaceID primitive.ObjectID) (int64, error)
+	UpdateMemberRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role string) error
+	RemoveMember(ctx context.Context, workspaceID, userID primitive.ObjectID) error
+	CreateInvitation(ctx context.Context, invitation *models.WorkspaceInvitation) error
+	ListInvitations(ctx context.Context, workspaceID primitive.ObjectID) ([]models.WorkspaceInvitation, error)
+	FindInvitationByToken(ctx context.Context, tokenHash string) (*models.WorkspaceInvitation, error)
+	AcceptInvitation(ctx context.Context, id primitive.ObjectID, now time.Time) error
+	DeleteInvitation(ctx context.Context, id, workspaceID primitive.ObjectID) error
+}
+
+type WorkspaceService struct {
+	repo     WorkspaceStore
+	userRepo repository.UserRepository
+	sender   notifier.Sender
+	appURL   string
+}
+
+// NewWorkspaceService creates the service. Invitations are emailed with
+// sender if it is not nil, appURL is used for the link in the email.
+func NewWorkspaceService(repo WorkspaceStore, userRepo repository.UserRepository, sender notifier.Sender, appURL string) *WorkspaceService {
+	return &WorkspaceService{
+		repo:     repo,
+		userRepo: userRepo,
+		sender:   sender,
+		appURL:   strings.TrimRight(appURL, "/"),
+	}
+}
+
+// ResolveRole returns the role of userID in workspaceID. Every user owns
+// the personal workspace that has the ID of the user.
+func (s *WorkspaceService) ResolveRole(ctx context.Context, userID, workspaceID string) (string, error) {
+	if workspaceID == userID {
+		return models.RoleOwner, nil
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return "", apperrors.NewBadRequestError("invalid user ID")
+	}
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return "", apperrors.NewBadRequestError("invalid workspace ID")
+	}
+
+	member, err := s.repo.FindMember(ctx, workspaceObjectID, userObjectID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			return "", apperrors.NewForbiddenError("you are not a member of this workspace")
+		}
+		return "", apperrors.NewInternalServerError("failed to load workspace member", err)
+	}
+	return member.Role, nil
+}
+
+// ListWorkspaces returns the personal workspace of userID followed by the
+// workspaces the user is a member of.
+func (s *WorkspaceService) ListWorkspaces(ctx context.Context, userID string) ([]models.WorkspaceMembership, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid user ID")
+	}
+
+	memberships, err := s.repo.ListMemberships(ctx, userObjectID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list workspaces", err)
+	}
+	roles := make(map[primitive.ObjectID]string, len(memberships))
+	ids := make([]primitive.ObjectID, 0, len(memberships))
+	for _, m := range memberships {
+		roles[m.WorkspaceID] = m.Role
+		ids = append(ids, m.WorkspaceID)
+	}
+
+	result := []models.WorkspaceMembership{{
+		Workspace: models.Workspace{
+			ID:       userObjectID,
+			Name:     personalWorkspaceName,
+			OwnerID:  userObjectID,
+			Personal: true,
+		},
+		Role: models.RoleOwner,
+	}}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	workspaces, err := s.repo.FindWorkspaces(ctx, ids)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list workspaces", err)
+	}
+	for _, w := range workspaces {
+		result = append(result, models.WorkspaceMembership{Workspace: w, Role: roles[w.ID]})
+	}
+	return result, nil
+}
+
+// CreateWorkspace creates a team workspace with userID as its owner.
+func (s *WorkspaceService) CreateWorkspace(ctx context.Context, userID, name string) (*models.WorkspaceMembership, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid user ID")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, apperrors.NewBadRequestError("workspace name is required")
+	}
+	if len([]rune(name)) > maxWorkspaceNameLength {
+		return nil, apperrors.NewBadRequestError(fmt.Sprintf("workspace name must be at most %d characters", maxWorkspaceNameLength))
+	}
+
+	now := time.Now()
+	workspace := &models.Workspace{
+		Name:      name,
+		OwnerID:   userObjectID,
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateWorkspace(ctx, workspace); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create workspace", err)
+	}
+
+	member := &models.WorkspaceMember{
+		WorkspaceID: workspace.ID,
+		UserID:      userObjectID,
+		Role:        models.RoleOwner,
+		CreatedAt:   now,
+	}
+	if err := s.repo.AddMember(ctx, member); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to add workspace owner", err)
+	}
+	return &models.WorkspaceMembership{Workspace: *workspace, Role: models.RoleOwner}, nil
+}
+
+// ListMembers returns the members of a team workspace with their emails.
+func (s *WorkspaceService) ListMembers(ctx context.Context, workspaceID, userID string) ([]models.WorkspaceMember, error) {
+	workspace, err := s.loadTeamWorkspace(ctx, workspaceID, userID, models.PermissionViewForms)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.ListMembers(ctx, workspace.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list workspace members", err)
+	}
+	for i := range members {
+		if user, err := s.userRepo.FindByID(ctx, members[i].UserID.Hex()); err == nil {
+			members[i].Email = user.Email
+		}
+	}
+	return members, nil
+}
+
+// UpdateMemberRole changes the role of a member. The last owner of a
+// workspace can not be demoted.
+func (s *WorkspaceService) UpdateMemberRole(ctx context.Context, workspaceID, userID, memberID, role string) error {
+	if !models.IsValidRole(role) {
+		return apperrors.NewBadRequestError("role must be one of owner, editor, viewer, analyst")
+	}
+
+	workspace, err := s.loadTeamWorkspace(ctx, workspaceID, userID, models.PermissionManageWorkspace)
+	if err != nil {
+		return err
+	}
+	member, err := s.loadMember(ctx, workspace.ID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if member.Role == models.RoleOwner && role != models.RoleOwner {
+		if err := s.checkOtherOwners(ctx, workspace.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.UpdateMemberRole(ctx, workspace.ID, member.UserID, role); err != nil {
+		return apperrors.NewInternalServerError("failed to update workspace member", err)
+	}
+	return nil
+}
+
+// RemoveMember removes a member from a workspace. Members may always remove
+// themselves, removing others requires the owner role.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, workspaceID, userID, memberID string) error {
+	permission := models.PermissionManageWorkspace
+	if memberID == userID {
+		permission = models.PermissionViewForms
+	}
+
+	workspace, err := s.loadTeamWorkspace(ctx, workspaceID, userID, permission)
+	if err != nil {
+		return err
+	}
+	member, err := s.loadMember(ctx, workspace.ID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if member.Role == models.RoleOwner {
+		if err := s.checkOtherOwners(ctx, workspace.ID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.RemoveMember(ctx, workspace.ID, member.UserID); err != nil {
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			return apperrors.NewNotFoundError("workspace member not found")
+		}
+		return apperrors.NewInternalServerError("failed to remove workspace member", err)
+	}
+	return nil
+}
+
+// CreateInvitation invites email to a workspace with role. The token is
+// returned only here and in the email, the invitation keeps its hash.
+func (s *WorkspaceService) CreateInvitation(ctx context.Context, workspaceID, userID, email, role string) (*models.WorkspaceInvitation, string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, "", apperrors.NewBadRequestError("a valid email is required")
+	}
+	if !models.IsValidRole(role) {
+		return nil, "", apperrors.NewBadRequestError("role must be one of owner, editor, viewer, analyst")
+	}
+
+	workspace, err := s.loadTeamWorkspace(ctx, workspaceID, userID, models.PermissionManageWorkspace)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", apperrors.NewInternalServerError("failed to generate invitation token", err)
+	}
+
+	now := time.Now()
+	invitation := &models.WorkspaceInvitation{
+		WorkspaceID: workspace.ID,
+		Email:       email,
+		Role:        role,
+		TokenHash:   hashToken(token),
+		InvitedBy:   workspace.OwnerID,
+		ExpiresAt:   now.Add(invitationTTL),
+		CreatedAt:   now,
+	}
+	if inviter, err := primitive.ObjectIDFromHex(userID); err == nil {
+		invitation.InvitedBy = inviter
+	}
+	if err := s.repo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, "", apperrors.NewInternalServerError("failed to create invitation", err)
+	}
+
+	s.sendInvitation(ctx, workspace, invitation, token)
+	return invitation, token, nil
+}
+
+func (s *WorkspaceService) ListInvitations(ctx context.Context, workspaceID, userID string) ([]models.WorkspaceInvitation, error) {
+	workspace, err := s.loadTeamWorkspace(ctx, workspaceID, userID, models.PermissionManageWorkspace)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.repo.ListInvitations(ctx, workspace.ID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to list invitations", err)
+	}
+	return invitations, nil
+}
+
+func (s *WorkspaceService) RevokeInvitation(ctx context.Context, workspaceID, userID, invitationID string) error {
+	workspace, err := s.loadTeamWorkspace(ctx, workspaceID, userID, models.PermissionManageWorkspace)
+	if err != nil {
+		return err
+	}
+	invitationObjectID, err := primitive.ObjectIDFromHex(invitationID)
+	if err != nil {
+		return apperrors.NewBadRequestError("invalid invitation ID")
+	}
+
+	if err := s.repo.DeleteInvitation(ctx, invitationObjectID, workspace.ID); err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return apperrors.NewNotFoundError("invitation not found")
+		}
+		return apperrors.NewInternalServerError("failed to revoke invitation", err)
+	}
+	return nil
+}
+
+// AcceptInvitation adds userID to the workspace of the invitation. The
+// invitation must be addressed to the verified email of the user and can be
+// used once.
+func (s *WorkspaceService) AcceptInvitation(ctx context.Context, userID, token string) (*models.WorkspaceMembership, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.NewNotFoundError("user not found")
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, apperrors.NewForbiddenError("verify your email before accepting invitations")
+	}
+
+	invitation, err := s.repo.FindInvitationByToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return nil, apperrors.NewNotFoundError("invitation is invalid or has expired")
+		}
+		return nil, apperrors.NewInternalServerError("failed to load invitation", err)
+	}
+	if !strings.EqualFold(invitation.Email, strings.TrimSpace(user.Email)) {
+		return nil, apperrors.NewForbiddenError("invitation was sent to a different email")
+	}
+
+	workspace, err := s.repo.FindWorkspace(ctx, invitation.WorkspaceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWorkspaceNotFound) {
+			return nil, apperrors.NewNotFoundError("invitation is invalid or has expired")
+		}
+		return nil, apperrors.NewInternalServerError("failed to load workspace", err)
+	}
+
+	now := time.Now()
+	if err := s.repo.AcceptInvitation(ctx, invitation.ID, now); err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return nil, apperrors.NewNotFoundError("invitation is invalid or has expired")
+		}
+		return nil, apperrors.NewInternalServerError("failed to accept invitation", err)
+	}
+
+	member := &models.WorkspaceMember{
+		WorkspaceID: workspace.ID,
+		UserID:      user.ID,
+		Role:        invitation.Role,
+		CreatedAt:   now,
+	}
+	if err := s.repo.AddMember(ctx, member); err != nil {
+		if errors.Is(err, repository.ErrMemberExists) {
+			return nil, apperrors.NewConflictError("you are already a member of this workspace")
+		}
+		return nil, apperrors.NewInternalServerError("failed to add workspace member", err)
+	}
+	return &models.WorkspaceMembership{Workspace: *workspace, Role: member.Role}, nil
+}
+
+// loadTeamWorkspace loads a stored workspace and checks that userID has
+// permission in it. Personal workspaces have no members to manage.
+func (s *WorkspaceService) loadTeamWorkspace(ctx context.Context, workspaceID, userID, permission string) (*models.Workspace, error) {
+	if workspaceID == userID {
+		return nil, apperrors.NewBadRequestError("the personal workspace has no members")
+	}
+
+	role, err := s.ResolveRole(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if !models.RoleAllows(role, permission) {
+		return nil, apperrors.NewForbiddenError("your role does not allow this action")
+	}
+
+	workspaceObjectID, err := primitive.ObjectIDFromHex(workspaceID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid workspace ID")
+	}
+	workspace, err := s.repo.FindWorkspace(ctx, workspaceObjectID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWorkspaceNotFound) {
+			return nil, apperrors.NewNotFoundError("workspace not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to load workspace", err)
+	}
+	return workspace, nil
+}
+
+func (s *WorkspaceService) loadMember(ctx context.Context, workspaceID primitive.ObjectID, memberID string) (*models.WorkspaceMember, error) {
+	memberObjectID, err := primitive.ObjectIDFromHex(memberID)
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid member ID")
+	}
+
+	member, err := s.repo.FindMember(ctx, workspaceID, memberObjectID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			return nil, apperrors.NewNotFoundError("workspace member not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to load workspace member", err)
+	}
+	return member, nil
+}
+
+func (s *WorkspaceService) checkOtherOwners(ctx context.Context, workspaceID primitive.ObjectID) error {
+	owners, err := s.repo.CountOwners(ctx, workspaceID)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to count workspace owners", err)
+	}
+	if owners <= 1 {
+		return apperrors.NewConflictError("a workspace needs at least one owner")
+	}
+	return nil
+}
+
+// sendInvitation emails the invitation. A failed email does not fail the
+// invitation, the owner can share the token returned by CreateInvitation.
+func (s *WorkspaceService) sendInvitation(ctx context.Context, workspace *models.Workspace, invitation *models.WorkspaceInvitation, token string) {
+	if s.sender == nil {
+		logger.Info("Email sender is not configured, invitation email skipped", zap.String("invitationId", invitation.ID.Hex()))
+		return
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "You were invited to join the workspace %q as %s.\n", workspace.Name, invitation.Role)
+	if s.appURL != "" {
+		fmt.Fprintf(&body, "\nAccept the invitation: %s/invitations/accept?token=%s\n", s.appURL, url.QueryEscape(token))
+	} else {
+		fmt.Fprintf(&body, "\nYour invitation token: %s\n", token)
+	}
+	fmt.Fprintf(&body, "\nThe invitation expires on %s.\n", invitation.ExpiresAt.UTC().Format("2006-01-02 15:04 UTC"))
+
+	msg := notifier.Message{
+		To:      []string{invitation.Email},
+		Subject: fmt.Sprintf("Invitation to %q", workspace.Name),
+		Body:    body.String(),
+	}
+	if err := s.sender.Send(ctx, msg); err != nil {
+		logger.Error("Failed to send invitation email",
+			zap.String("invitationId", invitation.ID.Hex()),
+			zap.Error(err))
+	}
+}
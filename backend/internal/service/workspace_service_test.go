@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/maxzhirnov/formease/internal/models"
+	"github.com/maxzhirnov/formease/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolveRole(t *testing.T) {
+	ann := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+	team := primitive.NewObjectID()
+	members := &fakeWorkspaceStore{members: map[[2]primitive.ObjectID]string{
+		{team, ann}: models.RoleViewer,
+	}}
+	s := NewWorkspaceService(members, nil, nil, "")
+
+	tests := []struct {
+		name        string
+		userID      string
+		workspaceID string
+		want        string
+		wantStatus  int
+	}{
+		{name: "own personal workspace", userID: ann.Hex(), workspaceID: ann.Hex(), want: models.RoleOwner},
+		{name: "member of a team workspace", userID: ann.Hex(), workspaceID: team.Hex(), want: models.RoleViewer},
+		{name: "personal workspace of another user", userID: ann.Hex(), workspaceID: bob.Hex(), wantStatus: http.StatusForbidden},
+		{name: "not a member", userID: bob.Hex(), workspaceID: team.Hex(), wantStatus: http.StatusForbidden},
+		{name: "invalid workspace ID", userID: ann.Hex(), workspaceID: "team", wantStatus: http.StatusBadRequest},
+		{name: "invalid user ID", userID: "ann", workspaceID: team.Hex(), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, err := s.ResolveRole(context.Background(), tt.userID, tt.workspaceID)
+			if tt.wantStatus != 0 {
+				assertStatus(t, err, tt.wantStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveRole() error = %v", err)
+			}
+			if role != tt.want {
+				t.Errorf("ResolveRole() = %q, want %q", role, tt.want)
+			}
+		})
+	}
+
+	members.err = errors.New("connection refused")
+	_, err := s.ResolveRole(context.Background(), ann.Hex(), team.Hex())
+	assertStatus(t, err, http.StatusInternalServerError)
+}
+
+// fakeWorkspaceStore only knows the roles of members, keyed by workspace
+// and user ID.
+type fakeWorkspaceStore struct {
+	WorkspaceStore
+	members map[[2]primitive.ObjectID]string
+	err     error
+}
+
+func (f *fakeWorkspaceStore) FindMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	role, ok := f.members[[2]primitive.ObjectID{workspaceID, userID}]
+	if !ok {
+		return nil, repository.ErrMemberNotFound
+	}
+	return &models.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID, Role: role}, nil
+}
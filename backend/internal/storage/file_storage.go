@@ -64,6 +64,19 @@ func (s *LocalFileStorage) Store(filename string, file multipart.File) (string,
 	return s.GetPublicURL(filename), nil
 }
 
+func (s *LocalFileStorage) Open(filename string) (io.ReadCloser, error) {
+	if !isValidFilename(filename) {
+		return nil, fmt.Errorf("invalid filename: %s", filename)
+	}
+
+	file, err := os.Open(s.GetFullPath(filename))
+	if err != nil {
+		logger.Error("Failed to open file", zap.Error(err))
+		return nil, fmt.Errorf("failed to open file %s: %w", filename, err)
+	}
+	return file, nil
+}
+
 func (s *LocalFileStorage) Delete(filename string) error {
 
 	fullPath := s.GetFullPath(filename)
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"time"
 
@@ -80,6 +81,23 @@ func (s *YandexS3Storage) Store(filename string, file multipart.File) (string, e
 	return s.GetPublicURL(filename), nil
 }
 
+// Open streams an object. The download is not limited by a timeout,
+// the caller closes the body when done.
+func (s *YandexS3Storage) Open(filename string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(filename),
+	})
+	if err != nil {
+		logger.Error("Failed to download from Yandex S3",
+			zap.String("filename", filename),
+			zap.Error(err))
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+
+	return out.Body, nil
+}
+
 func (s *YandexS3Storage) Delete(filename string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
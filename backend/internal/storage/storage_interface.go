@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"io"
 	"mime/multipart"
 )
 
@@ -18,6 +19,7 @@ type FileStorageConfig struct {
 
 type FileStorage interface {
 	Store(filename string, file multipart.File) (fileURL string, err error)
+	Open(filename string) (io.ReadCloser, error)
 	Delete(filename string) error
 	Exists(filename string) bool
 	GetFullPath(filename string) string
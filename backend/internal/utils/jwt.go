@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -21,39 +24,185 @@ func NewJWTUtil(secretKey string) *JWTUtil {
 	}
 }
 
-func (j *JWTUtil) GenerateToken(userId string, email string, expirationHours int) (string, error) {
+// AccessClaims identify the user of an access token and let it be revoked
+// before it expires.
+type AccessClaims struct {
+	UserID    string
+	Email     string
+	ID        string
+	SessionID string
+	ExpiresAt time.Time
+}
+
+// GenerateToken issues an access token for a session. The returned claims
+// hold the random token ID ("jti") used to revoke it.
+func (j *JWTUtil) GenerateToken(userId, email, sessionId string, expirationHours int) (string, *AccessClaims, error) {
+	tokenId, err := newTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	claims := &AccessClaims{
+		UserID:    userId,
+		Email:     email,
+		ID:        tokenId,
+		SessionID: sessionId,
+		ExpiresAt: now.Add(time.Duration(expirationHours) * time.Hour),
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userId,
-		"email":   email,
-		"exp":     time.Now().Add(time.Duration(expirationHours) * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
+		"user_id":    userId,
+		"email":      email,
+		"session_id": sessionId,
+		"jti":        tokenId,
+		"exp":        claims.ExpiresAt.Unix(),
+		"iat":        now.Unix(),
 	})
-	return token.SignedString(j.SecretKey)
+	signed, err := token.SignedString(j.SecretKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
 }
 
 func (j *JWTUtil) ValidateToken(tokenString string) (string, string, error) {
+	claims, err := j.ValidateAccessToken(tokenString)
+	if err != nil {
+		return "", "", err
+	}
+	return claims.UserID, claims.Email, nil
+}
+
+// ValidateAccessToken checks an access token and returns its claims. Tokens
+// without an ID can not be revoked and are not accepted.
+func (j *JWTUtil) ValidateAccessToken(tokenString string) (*AccessClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		return j.SecretKey, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return "", "", jwt.ErrSignatureInvalid
+		return nil, jwt.ErrSignatureInvalid
+	}
+	// Tokens issued for other purposes, e.g. form previews, are not sessions
+	if _, ok := claims["purpose"]; ok {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	userId, ok := claims["user_id"].(string)
+	if !ok {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	email, ok := claims["email"].(string)
+	if !ok {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	tokenId, ok := claims["jti"].(string)
+	if !ok || tokenId == "" {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	sessionId, _ := claims["session_id"].(string)
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return nil, jwt.ErrTokenInvalidClaims
 	}
-	userId := claims["user_id"]
-	email := claims["email"].(string)
-	return userId.(string), email, nil
+
+	return &AccessClaims{
+		UserID:    userId,
+		Email:     email,
+		ID:        tokenId,
+		SessionID: sessionId,
+		ExpiresAt: expiresAt.Time,
+	}, nil
 }
 
-func (j *JWTUtil) GenerateRefreshToken(userId string) (string, error) {
+// RefreshTokenTTL is how long a refresh token and an unused session stay valid.
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+const refreshTokenPurpose = "refresh"
+
+var ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+
+// GenerateRefreshToken issues a refresh token for a session. Every token
+// gets a random ID, so tokens issued in the same second differ.
+func (j *JWTUtil) GenerateRefreshToken(userId, sessionId string) (string, error) {
+	tokenId, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{
-		"user_id": userId,
-		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(), // Refresh token expires in 7 days
-		"iat":     time.Now().Unix(),
+		"purpose":    refreshTokenPurpose,
+		"user_id":    userId,
+		"session_id": sessionId,
+		"jti":        tokenId,
+		"exp":        time.Now().Add(RefreshTokenTTL).Unix(),
+		"iat":        time.Now().Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(j.SecretKey)
 }
+
+// ValidateRefreshToken checks a refresh token and returns the user and
+// session it was issued for.
+func (j *JWTUtil) ValidateRefreshToken(tokenString string) (string, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return j.SecretKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return "", "", ErrRefreshTokenInvalid
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != refreshTokenPurpose {
+		return "", "", ErrRefreshTokenInvalid
+	}
+	userId, _ := claims["user_id"].(string)
+	sessionId, _ := claims["session_id"].(string)
+	if userId == "" || sessionId == "" {
+		return "", "", ErrRefreshTokenInvalid
+	}
+	return userId, sessionId, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+const previewTokenPurpose = "form_preview"
+
+var ErrPreviewTokenInvalid = errors.New("invalid preview token")
+
+// GeneratePreviewToken issues a token that lets anyone holding it view the
+// given form while it is still a draft.
+func (j *JWTUtil) GeneratePreviewToken(formId string, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose": previewTokenPurpose,
+		"form_id": formId,
+		"exp":     expiresAt.Unix(),
+		"iat":     time.Now().Unix(),
+	})
+	signed, err := token.SignedString(j.SecretKey)
+	return signed, expiresAt, err
+}
+
+// ValidatePreviewToken checks that tokenString is an unexpired preview token for formId.
+func (j *JWTUtil) ValidatePreviewToken(tokenString string, formId string) error {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return j.SecretKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return ErrPreviewTokenInvalid
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != previewTokenPurpose || claims["form_id"] != formId {
+		return ErrPreviewTokenInvalid
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func signClaims(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func TestPreviewToken(t *testing.T) {
+	j := NewJWTUtil(testSecret)
+	token, expiresAt, err := j.GeneratePreviewToken("form1", time.Hour)
+	if err != nil {
+		t.Fatalf("GeneratePreviewToken() error = %v", err)
+	}
+	if d := time.Until(expiresAt); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expiresAt is %s from now, want about 1h", d)
+	}
+	access, _, err := j.GenerateToken("user1", "ann@example.com", "session1", 1)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		formID  string
+		wantErr bool
+	}{
+		{"valid", token, "form1", false},
+		{"other form", token, "form2", true},
+		{"other secret", signClaims(t, "other", jwt.MapClaims{"purpose": previewTokenPurpose, "form_id": "form1", "exp": time.Now().Add(time.Hour).Unix()}), "form1", true},
+		{"expired", signClaims(t, testSecret, jwt.MapClaims{"purpose": previewTokenPurpose, "form_id": "form1", "exp": time.Now().Add(-time.Minute).Unix()}), "form1", true},
+		{"access token", access, "form1", true},
+		{"garbage", "not-a-token", "form1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := j.ValidatePreviewToken(tt.token, tt.formID); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePreviewToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccessTokenRejectsOtherPurposes(t *testing.T) {
+	j := NewJWTUtil(testSecret)
+	preview, _, err := j.GeneratePreviewToken("form1", time.Hour)
+	if err != nil {
+		t.Fatalf("GeneratePreviewToken() error = %v", err)
+	}
+
+	if _, _, err := j.ValidateToken(preview); err == nil {
+		t.Error("ValidateToken() accepted a preview token")
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	j := NewJWTUtil(testSecret)
+	token, err := j.GenerateRefreshToken("user1", "session1")
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken() error = %v", err)
+	}
+	again, err := j.GenerateRefreshToken("user1", "session1")
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken() error = %v", err)
+	}
+	if token == again {
+		t.Error("refresh tokens issued at once are equal")
+	}
+	access, _, err := j.GenerateToken("user1", "ann@example.com", "session1", 1)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", token, false},
+		{"access token", access, true},
+		{"no session", signClaims(t, testSecret, jwt.MapClaims{"purpose": refreshTokenPurpose, "user_id": "user1", "exp": exp}), true},
+		{"no user", signClaims(t, testSecret, jwt.MapClaims{"purpose": refreshTokenPurpose, "session_id": "session1", "exp": exp}), true},
+		{"expired", signClaims(t, testSecret, jwt.MapClaims{"purpose": refreshTokenPurpose, "user_id": "user1", "session_id": "session1", "exp": time.Now().Add(-time.Minute).Unix()}), true},
+		{"other secret", signClaims(t, "other", jwt.MapClaims{"purpose": refreshTokenPurpose, "user_id": "user1", "session_id": "session1", "exp": exp}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, sessionID, err := j.ValidateRefreshToken(tt.token)
+			if tt.wantErr {
+				if err != ErrRefreshTokenInvalid {
+					t.Errorf("ValidateRefreshToken() error = %v, want ErrRefreshTokenInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateRefreshToken() error = %v", err)
+			}
+			if userID != "user1" || sessionID != "session1" {
+				t.Errorf("ValidateRefreshToken() = %q, %q, want user1, session1", userID, sessionID)
+			}
+		})
+	}
+
+	if _, err := j.ValidateAccessToken(token); err == nil {
+		t.Error("ValidateAccessToken() accepted a refresh token")
+	}
+}
+
+func TestAccessToken(t *testing.T) {
+	j := NewJWTUtil(testSecret)
+	token, claims, err := j.GenerateToken("user1", "ann@example.com", "session1", 2)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	if claims.ID == "" {
+		t.Fatal("GenerateToken() returned claims without a token ID")
+	}
+	_, other, err := j.GenerateToken("user1", "ann@example.com", "session1", 2)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	if other.ID == claims.ID {
+		t.Error("access tokens issued at once share a token ID")
+	}
+
+	got, err := j.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+	if got.UserID != "user1" || got.Email != "ann@example.com" || got.SessionID != "session1" || got.ID != claims.ID {
+		t.Errorf("ValidateAccessToken() = %+v, want %+v", *got, *claims)
+	}
+	if got.ExpiresAt.Unix() != claims.ExpiresAt.Unix() {
+		t.Errorf("ExpiresAt = %s, want %s", got.ExpiresAt, claims.ExpiresAt)
+	}
+}
+
+func TestAccessTokenInvalid(t *testing.T) {
+	j := NewJWTUtil(testSecret)
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{"user_id": "user1", "email": "ann@example.com", "jti": "abc", "exp": exp}
+	}
+	without := func(key string) jwt.MapClaims {
+		claims := valid()
+		delete(claims, key)
+		return claims
+	}
+	with := func(key string, value interface{}) jwt.MapClaims {
+		claims := valid()
+		claims[key] = value
+		return claims
+	}
+
+	if _, err := j.ValidateAccessToken(signClaims(t, testSecret, valid())); err != nil {
+		t.Fatalf("ValidateAccessToken() rejected the base claims: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"without jti", signClaims(t, testSecret, without("jti"))},
+		{"empty jti", signClaims(t, testSecret, with("jti", ""))},
+		{"without user", signClaims(t, testSecret, without("user_id"))},
+		{"without email", signClaims(t, testSecret, without("email"))},
+		{"without exp", signClaims(t, testSecret, without("exp"))},
+		{"expired", signClaims(t, testSecret, with("exp", time.Now().Add(-time.Minute).Unix()))},
+		{"with purpose", signClaims(t, testSecret, with("purpose", "anything"))},
+		{"other secret", signClaims(t, "other", valid())},
+		{"unsigned", func() string {
+			s, _ := jwt.NewWithClaims(jwt.SigningMethodNone, valid()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return s
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := j.ValidateAccessToken(tt.token); err == nil {
+				t.Error("ValidateAccessToken() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -33,6 +33,13 @@ func NewNotFoundError(message string) *AppError {
 	}
 }
 
+func NewForbiddenError(message string) *AppError {
+	return &AppError{
+		Message:    message,
+		StatusCode: http.StatusForbidden,
+	}
+}
+
 func NewInternalServerError(message string, err error) *AppError {
 	return &AppError{
 		Message:    message,
@@ -40,3 +47,24 @@ func NewInternalServerError(message string, err error) *AppError {
 		Err:        err,
 	}
 }
+
+func NewConflictError(message string) *AppError {
+	return &AppError{
+		Message:    message,
+		StatusCode: http.StatusConflict,
+	}
+}
+
+func NewPreconditionFailedError(message string) *AppError {
+	return &AppError{
+		Message:    message,
+		StatusCode: http.StatusPreconditionFailed,
+	}
+}
+
+func NewPreconditionRequiredError(message string) *AppError {
+	return &AppError{
+		Message:    message,
+		StatusCode: http.StatusPreconditionRequired,
+	}
+}
@@ -0,0 +1,167 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, tt := range tests {
+		if got := columnName(tt.index); got != tt.want {
+			t.Errorf("columnName(%d) = %q, want %q", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Responses: [2024/01]")
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	rows := [][]string{
+		{"Name", "Comment"},
+		{"Ann", `<b>"quoted" & bold</b>`},
+		{"  spaced  ", "line\nbreak"},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+	if err := w.WriteRow([]string{"late"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("WriteRow() after Close error = %v, want ErrClosed", err)
+	}
+
+	files := readZip(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels", "xl/workbook.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("archive is missing %s", name)
+		}
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(files["xl/workbook.xml"], &workbook); err != nil {
+		t.Fatalf("workbook.xml is not valid XML: %v", err)
+	}
+	if len(workbook.Sheets) != 1 || workbook.Sheets[0].Name != "Responses 202401" {
+		t.Errorf("sheets = %+v, want one named %q", workbook.Sheets, "Responses 202401")
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Ref   string `xml:"r,attr"`
+			Cells []struct {
+				Ref  string `xml:"r,attr"`
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatalf("sheet1.xml is not valid XML: %v", err)
+	}
+
+	var got [][]string
+	for i, row := range sheet.Rows {
+		var cells []string
+		for j, cell := range row.Cells {
+			if want := columnName(j) + row.Ref; cell.Ref != want {
+				t.Errorf("row %d cell %d ref = %q, want %q", i, j, cell.Ref, want)
+			}
+			cells = append(cells, cell.Text)
+		}
+		got = append(got, cells)
+	}
+	if !reflect.DeepEqual(got, rows) {
+		t.Errorf("rows = %q, want %q", got, rows)
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", "Sheet1"},
+		{"[]:*?/\\", "Sheet1"},
+		{"A very long form name that does not fit", "A very long form name that does"},
+		{"Опрос клиентов", "Опрос клиентов"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, tt.name)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			var workbook struct {
+				Sheet struct {
+					Name string `xml:"name,attr"`
+				} `xml:"sheets>sheet"`
+			}
+			if err := xml.Unmarshal(readZip(t, buf.Bytes())["xl/workbook.xml"], &workbook); err != nil {
+				t.Fatalf("workbook.xml is not valid XML: %v", err)
+			}
+			if workbook.Sheet.Name != tt.want {
+				t.Errorf("sheet name = %q, want %q", workbook.Sheet.Name, tt.want)
+			}
+		})
+	}
+}
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("output is not a zip archive: %v", err)
+	}
+
+	files := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		files[f.Name] = content
+	}
+	return files
+}
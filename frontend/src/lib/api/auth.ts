@@ -36,6 +36,34 @@ export async function login(credentials: LoginRequest): Promise<LoginResponse> {
     return response.json();
 }
 
+async function postAuth(path: string, body: Record<string, string>, fallbackError: string) {
+    const response = await fetch(`${PUBLIC_API_URL}/auth/${path}`, {
+        method: 'POST',
+        credentials: 'include',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(body)
+    });
+
+    if (!response.ok) {
+        const error = await response.json();
+        throw new Error(error.error || fallbackError);
+    }
+
+    return response.json();
+}
+
+export async function verifyEmail(token: string) {
+    return postAuth('verify-email', { token }, 'Email verification failed');
+}
+
+export async function forgotPassword(email: string) {
+    return postAuth('forgot-password', { email }, 'Failed to request password reset');
+}
+
+export async function resetPassword(token: string, password: string) {
+    return postAuth('reset-password', { token, password }, 'Password reset failed');
+}
+
 export async function refresh(): Promise<void> {
     const response = await fetch(`${PUBLIC_API_URL}/auth/refresh`, {
         method: 'POST',
@@ -48,6 +76,12 @@ export async function refresh(): Promise<void> {
 }
 
 export async function logout(): Promise<void> {
+    // Revoke the session on the server, signing out locally must work even if it fails
+    await fetch(`${PUBLIC_API_URL}/auth/logout`, {
+        method: 'POST',
+        credentials: 'include'
+    }).catch(() => undefined);
+
     // Clear cookies by setting expiry to past
     const response = await fetch(`/logout`, {
         method: 'POST',
@@ -1,12 +1,20 @@
 import type { FormData } from '$lib/types/form';
 import { PUBLIC_API_URL } from '$env/static/public';
 
+// Workspace sent with form requests, the personal workspace when null
+let workspaceId: string | null = null;
+
+export function setWorkspace(id: string | null) {
+    workspaceId = id;
+}
+
 const fetchWithCreds = (url: string, options: RequestInit = {}, customFetch: typeof fetch = fetch) => {
     return customFetch(url, {
         ...options,
         credentials: 'include',
         headers: {
             'Content-Type': 'application/json',
+            ...(workspaceId ? { 'X-Workspace-ID': workspaceId } : {}),
             ...options.headers,
         },
     });
@@ -26,16 +34,50 @@ export async function createForm(formData: FormData): Promise<FormData> {
     return await response.json();
 }
 
-export async function listForms() {
-    try {
-        const response = await fetchWithCreds(`${PUBLIC_API_URL}/my-forms`);
+export interface FormListParams {
+    q?: string;
+    status?: 'draft' | 'published';
+    theme?: string;
+    // Folder ID, or 'root' for forms outside of folders
+    folder?: string;
+    tag?: string;
+    sort?: 'created' | 'updated' | 'responses';
+    order?: 'asc' | 'desc';
+    cursor?: string;
+    limit?: number;
+}
 
-        if (!response.ok) {
-            const error = await response.json();
-            throw new Error(error.error || 'Failed to fetch forms');
+export interface FormPage {
+    forms: FormData[];
+    nextCursor?: string;
+}
+
+export async function listFormsPage(params: FormListParams = {}): Promise<FormPage> {
+    const query = new URLSearchParams();
+    for (const [key, value] of Object.entries(params)) {
+        if (value !== undefined && value !== '') {
+            query.set(key, String(value));
         }
+    }
 
-        const forms = await response.json();
+    const response = await fetchWithCreds(`${PUBLIC_API_URL}/my-forms?${query}`);
+    if (!response.ok) {
+        const error = await response.json();
+        throw new Error(error.error || 'Failed to fetch forms');
+    }
+    return response.json();
+}
+
+// listForms loads every page of the forms list
+export async function listForms(params: FormListParams = {}) {
+    try {
+        const forms: FormData[] = [];
+        let cursor: string | undefined;
+        do {
+            const page = await listFormsPage({ limit: 100, ...params, cursor });
+            forms.push(...page.forms);
+            cursor = page.nextCursor;
+        } while (cursor);
         return forms;
     } catch (error) {
         if (error instanceof Error) {
@@ -61,11 +103,28 @@ export async function getForm(id: string, {}, customFetch: typeof fetch = fetch)
     return await response.json();
 }
 
-export async function getFormPublic(id: string, customFetch: typeof fetch = fetch): Promise<FormData> {
-    const response = await customFetch(`${PUBLIC_API_URL}/forms/${id}`);
+export interface FormClosed {
+    reason: 'not_open_yet' | 'closed' | 'response_limit_reached';
+    message?: string;
+    opensAt?: string;
+    closesAt?: string;
+}
+
+export class FormClosedError extends Error {
+    constructor(public closed: FormClosed) {
+        super('Form is closed');
+    }
+}
+
+export async function getFormPublic(id: string, customFetch: typeof fetch = fetch, previewToken?: string | null): Promise<FormData> {
+    const query = previewToken ? `?previewToken=${encodeURIComponent(previewToken)}` : '';
+    const response = await customFetch(`${PUBLIC_API_URL}/forms/${id}${query}`);
     
     if (!response.ok) {
         const error = await response.json();
+        if (error.closed) {
+            throw new FormClosedError(error.closed);
+        }
         throw new Error(error.error || 'Failed to fetch form');
     }
 
@@ -75,6 +134,9 @@ export async function getFormPublic(id: string, customFetch: typeof fetch = fetc
 export async function updateForm(id: string, formData: FormData) {
     const response = await fetchWithCreds(`${PUBLIC_API_URL}/my-forms/${id}`, {
         method: 'PUT',
+        headers: {
+            'If-Match': `"${formData.version ?? 0}"`
+        },
         body: JSON.stringify(formData)
     });
 
@@ -125,7 +187,7 @@ export async function generateFormWithAI(aiRequest: AIFormRequest): Promise<Form
 
 export async function toggleDraft(id: string, formData: FormData) {
     const response = await fetchWithCreds(`${PUBLIC_API_URL}/my-forms/${id}/toggle-draft`, {
-        method: 'GET',
+        method: 'POST',
     });
 
     if (!response.ok) {
@@ -13,7 +13,7 @@ export class FormApiService {
       isDraft: currentFormData.isDraft ?? true,
       name: currentFormData.name || 'Untitled Form',
       theme: currentFormData.theme || 'light',
-      userId: currentFormData.userId || '', // Ensure this is set
+      workspaceId: currentFormData.workspaceId || '', // Ensure this is set
       floatingShapesTheme: currentFormData.floatingShapesTheme || 'default',
       questions: currentFormData.questions || [],
       thankYouMessage: currentFormData.thankYouMessage || {
@@ -55,11 +55,14 @@ export class FormApiService {
 
   async update(id: string): Promise<FormData> {
     const currentFormData = this.stateService.getCurrentForm();
-    return updateForm(id, currentFormData);
+    const updated = await updateForm(id, currentFormData);
+    this.stateService.setVersion(updated.version);
+    return updated;
   }
 
   async toggleDraft(id: string): Promise<void> {
-    await toggleDraft(id, this.stateService.getCurrentForm());
+    const result = await toggleDraft(id, this.stateService.getCurrentForm());
+    this.stateService.setVersion(result.version);
   }
 
   async fetch(id: string, customFetch: typeof fetch = fetch): Promise<FormData> {
@@ -70,8 +73,8 @@ export class FormApiService {
 
 
 
-  async fetchPublic(id: string, customFetch: typeof fetch = fetch): Promise<FormData> {
-    this.stateService.setForm(await getFormPublic(id, customFetch));
+  async fetchPublic(id: string, customFetch: typeof fetch = fetch, previewToken?: string | null): Promise<FormData> {
+    this.stateService.setForm(await getFormPublic(id, customFetch, previewToken));
     return this.stateService.getCurrentForm();
   }
 
@@ -18,6 +18,10 @@ export class FormStateService {
     this.store.update(newForm);
   }
 
+  setVersion(version: number) {
+    this.store.update({ version });
+  }
+
   updateFormName(name: string) {
     this.store.update({ name });
   }
@@ -45,11 +49,11 @@ export class FormStateService {
   }
 
 
-  setUserId(userId: string): void {
-    if (!userId.trim()) {
-      throw new Error('User ID cannot be empty');
+  setWorkspaceId(workspaceId: string): void {
+    if (!workspaceId.trim()) {
+      throw new Error('Workspace ID cannot be empty');
     }
-    this.store.update({ userId });
+    this.store.update({ workspaceId });
   }
 
   updateTheme(themeName: ThemeName): void {
@@ -567,16 +571,24 @@ updateQuestionOption(questionId: number, optionId: number, updates: Partial<Ques
     return {
       ...nextQuestion,
       default: this.updateRoutingId(nextQuestion.default, removedId),
-      conditions: nextQuestion.conditions?.map(condition => ({
-        ...condition,
-        nextId: this.updateRoutingId(condition.nextId, removedId) ?? condition.nextId // Fallback to original nextId
-      }))
+      // Conditions leading to the removed question are dropped with it
+      conditions: nextQuestion.conditions
+        ?.filter(condition => condition.nextId !== removedId)
+        .map(condition => ({
+          ...condition,
+          nextId: this.updateRoutingId(condition.nextId, removedId) ?? condition.nextId
+        }))
     };
   }
 
-  private updateRoutingId(routingId: number | undefined, removedId: number): number {
-    if (typeof routingId === 'undefined') {
-      return 1; // Or some default value that makes sense in your context
+  /**
+   * Shifts a routing target after a question was removed. An unset target
+   * stays unset and a target pointing at the removed question is cleared,
+   * so neither turns into a jump back to the first question.
+   */
+  private updateRoutingId(routingId: number | undefined, removedId: number): number | undefined {
+    if (typeof routingId === 'undefined' || routingId === removedId) {
+      return undefined;
     }
     return routingId > removedId ? routingId - 1 : routingId;
   }
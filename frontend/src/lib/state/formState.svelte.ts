@@ -10,7 +10,7 @@ const initialFormData: FormData = {
   id: '',
   isDraft: true,
   name: '',
-  userId: '',
+  workspaceId: '',
   theme: themeOptionsList[1].value,
   floatingShapesTheme: floatingShapesOptionsList[0].value,
   questions: [],
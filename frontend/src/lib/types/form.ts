@@ -7,8 +7,23 @@ export interface FormData {
   isDraft: boolean;
   name: string;
   theme: ThemeName;
-  userId: string;
+  workspaceId: string;
   floatingShapesTheme: string;
   questions: Question[];
   thankYouMessage: ThankYouMessage;
+  // Server side version, sent back as If-Match when saving
+  version?: number;
+  // Scheduling
+  opensAt?: string;
+  closesAt?: string;
+  maxResponses?: number;
+  closedMessage?: string;
+  // Offered in the template catalog of the owner
+  isTemplate?: boolean;
+  // Organization, the folder is changed through the move endpoint
+  folderId?: string;
+  tags?: string[];
+  responseCount?: number;
+  createdAt?: string;
+  updatedAt?: string;
 }
@@ -18,6 +18,7 @@ export interface BaseQuestion {
   question: string;
   subtext: string;
   image: string;
+  optional?: boolean;
   nextQuestion?: {
     conditions: {
       answer: string;
@@ -69,7 +69,7 @@ export const validators: Record<ValidatorName, RegExp> = {
   
   // Russian documents
   passport: /^(\d{4})\s*(\d{6})$/,
-  inn: /^\d{10}|\d{12}$/,
+  inn: /^(\d{10}|\d{12})$/,
   snils: /^\d{3}-\d{3}-\d{3}\s\d{2}$/,
   
   // Payment
@@ -83,7 +83,8 @@ export function validate(value: string, type: ValidatorName): boolean {
   if (!validator) {
     throw new Error(`Unknown validator type: ${type}`);
   }
-  return validator.test(value);
+  // Surrounding whitespace is ignored, the server validates trimmed answers too
+  return validator.test(value.trim());
 }
 
 // Optional: Add custom error messages
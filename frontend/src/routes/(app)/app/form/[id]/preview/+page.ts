@@ -5,7 +5,8 @@ import type { FloatingShapesTheme } from '$lib/types/shapes';
 
 export const load: PageLoad = async ({ params, fetch: customFetch }) => {
     try {
-        const form = await formService.api.fetchPublic(params.id, customFetch);
+        // Owner endpoint, so drafts can be previewed as well
+        const form = await formService.api.fetch(params.id, customFetch);
         return {
             form: {
                 id: form.id,
@@ -2,21 +2,29 @@ import type { PageLoad } from './$types';
 import { formService } from '$lib/services/formService';
 import type { ThemeName } from '$lib/types/theme';
 import type { FloatingShapesTheme } from '$lib/types/shapes';
+import { FormClosedError } from '$lib/api/forms';
 
-export const load: PageLoad = async ({ params, fetch: customFetch }) => {
+export const load: PageLoad = async ({ params, url, fetch: customFetch }) => {
     try {
-        const form = await formService.api.fetchPublic(params.id, customFetch);
+        // Drafts are only returned together with a preview token
+        const previewToken = url.searchParams.get('previewToken');
+        const form = await formService.api.fetchPublic(params.id, customFetch, previewToken);
         return {
             form: {
                 id: form.id,
                 questions: form.questions,
                 thankYouMessage: form.thankYouMessage,
                 theme: form.theme as ThemeName,
-                floatingShapesTheme: form.floatingShapesTheme as FloatingShapesTheme,
-                isDraft: form.isDraft
+                floatingShapesTheme: form.floatingShapesTheme as FloatingShapesTheme
             }
         };
     } catch (error) {
+        if (error instanceof FormClosedError) {
+            return {
+                form: null,
+                closed: error.closed
+            };
+        }
         console.error('Error loading form:', error);
         return {
             form: null,